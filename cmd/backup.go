@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	docker "github.com/SpecterOps/BloodHound_CLI/cmd/internal"
+	"github.com/spf13/cobra"
+)
+
+var backupDir string
+
+// backupCmd represents the backup command
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Back up the BloodHound databases and configuration to an archive",
+	Long: `Back up the BloodHound databases and configuration to a timestamped tar.gz archive.
+
+The command performs the following steps:
+
+* Stops the BloodHound and Neo4j services
+* Dumps the Postgres database with "pg_dump"
+* Copies the Neo4j data volume
+* Saves the configuration, including any secrets held by the keyring or encrypted file secret
+  store, and copies the Docker YAML file
+* Writes a manifest with the image versions and file checksums
+* Starts the stopped services again
+
+By default, archives are written to the "backups" directory inside the config directory. The archive contains
+your configuration and credentials, so store it somewhere safe.`,
+	RunE: backupBloodHound,
+}
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+
+	backupCmd.Flags().StringVarP(&backupDir, "dir", "d", "", "Directory where the backup archive will be written")
+}
+
+// backupBloodHound creates a backup archive of the BloodHound deployment described by the configured YAML file.
+func backupBloodHound(cmd *cobra.Command, args []string) error {
+	rt, err := newRuntime()
+	if err != nil {
+		return err
+	}
+	outputDir := backupDir
+	if outputDir == "" {
+		outputDir = filepath.Join(docker.GetBloodHoundDir(), "backups")
+	}
+	fmt.Println("[+] Starting BloodHound backup")
+	yaml, err := docker.GetYamlFilePath(fileOverride)
+	if err != nil {
+		return err
+	}
+	archive, err := docker.RunBackup(rt, yaml, outputDir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[+] Backup complete: %s\n", archive)
+	return nil
+}
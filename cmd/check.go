@@ -2,30 +2,107 @@ package cmd
 
 import (
 	"fmt"
+	"github.com/SpecterOps/BloodHound_CLI/cmd/config"
 	docker "github.com/SpecterOps/BloodHound_CLI/cmd/internal"
 	"github.com/spf13/cobra"
 )
 
-// installCmd represents the install command
+var (
+	// Flag for removing extra access from the config directory and files
+	fixPermissions bool
+	// Flag for downloading the YAML files instead of writing the copies built into the binary
+	refreshYaml bool
+)
+
+// checkCmd represents the check command
 var checkCmd = &cobra.Command{
 	Use:   "check",
-	Short: "Evaluates the Docker environment and downloads the necessary YAML files, as needed.",
-	Long: `Evaluates the Docker environment and downloads the necessary YAML files, as needed.
+	Short: "Evaluates the Docker environment and writes the necessary YAML files, as needed.",
+	Long: `Evaluates the Docker environment and writes the necessary YAML files, as needed.
 
 You can run this command before or after running the "install" command. The intent is to ensure that
-the necessary commands are available in the $PATH and the YAML files are downloaded. If you accidentally delete the
-YAML files or move the binary without them, this command will prompt you to re-download them.`,
-	Run: evaluateBloodHound,
+the necessary commands are available in the $PATH and the YAML files are in the config directory. If you
+accidentally delete the YAML files, this command writes them again.
+
+The YAML files for this release are built into the binary, so the command works without network access.
+Add "--refresh" to download them from the release on GitHub that matches the CLI's version instead.
+Either way, the files are checked against the SHA-256 checksums built into the binary. The command
+reports YAML files that differ from the ones this version expects, such as files edited by hand or
+left over from an older release, and asks before overwriting them.
+
+The command also audits the permissions of the config directory and the JSON config file, which
+holds the admin password. It warns if they allow more access than the "permissions_mode" config
+value: "private" (the default) allows only your user (0700 and 0600), and "shared" also allows your
+group (0770 and 0660) for multi-user systems. Use "--fix" to remove the extra access.`,
+	RunE: evaluateBloodHound,
 }
 
 // init registers the checkCmd command with the root command, enabling the "check" CLI subcommand.
 func init() {
 	rootCmd.AddCommand(checkCmd)
+
+	checkCmd.Flags().BoolVar(&fixPermissions, "fix", false, "Remove extra access from the config directory and files")
+	checkCmd.Flags().BoolVar(&refreshYaml, "refresh", false, "Download the YAML files from GitHub instead of using the copies built into the binary")
 }
 
 // evaluateBloodHound checks the Docker Compose status and evaluates the environment, printing a confirmation message upon successful completion.
-func evaluateBloodHound(cmd *cobra.Command, args []string) {
-	docker.EvaluateDockerComposeStatus()
-	docker.EvaluateEnvironment()
+func evaluateBloodHound(cmd *cobra.Command, args []string) error {
+	if err := checkPermissions(); err != nil {
+		return err
+	}
+	if _, err := newRuntime(); err != nil {
+		return err
+	}
+	if err := checkComposeFiles(); err != nil {
+		return err
+	}
+	if err := docker.EvaluateEnvironment(refreshYaml); err != nil {
+		return err
+	}
 	fmt.Println("[+] Environment checks are complete!")
+	return nil
+}
+
+// checkComposeFiles reports YAML files in the config directory that differ from the ones this version of the CLI
+// expects.
+func checkComposeFiles() error {
+	fmt.Println("[+] Comparing the Docker YAML files with the ones for this version...")
+	files, err := docker.CheckComposeFiles()
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		switch file.Status {
+		case docker.ComposeMissing:
+			fmt.Printf("[!] %s is missing\n", file.Path)
+		case docker.ComposeModified:
+			fmt.Printf("[!] %s differs from the file for BloodHound CLI %s (SHA-256 %s, expected %s)\n", file.Path, config.Version, file.Actual, file.Expected)
+		}
+	}
+	return nil
+}
+
+// checkPermissions warns about config paths that allow more access than the permissions mode and fixes them if
+// requested.
+func checkPermissions() error {
+	fmt.Println("[+] Checking the permissions of the config directory...")
+	issues, err := docker.AuditConfigPermissions()
+	if err != nil {
+		return err
+	}
+	for _, issue := range issues {
+		fmt.Printf("[!] %s\n", issue)
+	}
+	if len(issues) == 0 {
+		return nil
+	}
+	if !fixPermissions {
+		fmt.Println("[!] Run `bloodhound-cli check --fix` to remove the extra access")
+		return nil
+	}
+	if err := docker.FixConfigPermissions(issues); err != nil {
+		return err
+	}
+	fmt.Println("[+] Removed the extra access from the config directory and files")
+	return nil
 }
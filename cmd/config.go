@@ -4,6 +4,9 @@ import (
 	"fmt"
 	env "github.com/SpecterOps/BloodHound_CLI/cmd/internal"
 	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"text/tabwriter"
 )
 
 // configCmd represents the config command
@@ -11,16 +14,78 @@ var configCmd = &cobra.Command{
 	Use:   "config",
 	Short: "Display or adjust the configuration",
 	Long: `Run this command to display the configuration. Use subcommands to
-adjust the configuration or retrieve individual values.`,
-	Run: configDisplay,
+adjust the configuration or retrieve individual values.
+
+Secret values like passwords are redacted unless you add "--show-secrets". Use "config get" to
+retrieve a single secret value.
+
+Add "--show-origin" to list the effective value of every key and where it comes from: a default,
+the JSON config file ("file"), the secret store, an environment variable ("env"), or a flag. The
+command warns when an environment variable or flag hides a value saved in the config, such as a
+ROOT_URL variable left in a shell or CI runner.`,
+	RunE: configDisplay,
 }
 
+var (
+	showSecrets bool
+	showOrigin  bool
+)
+
 func init() {
 	rootCmd.AddCommand(configCmd)
+
+	configCmd.Flags().BoolVar(&showSecrets, "show-secrets", false, "Show secret values like passwords instead of redacting them")
+	configCmd.Flags().BoolVar(&showOrigin, "show-origin", false, "Show where each value comes from and warn about values hidden by environment variables or flags")
+}
+
+func configDisplay(cmd *cobra.Command, args []string) error {
+	if showOrigin {
+		return configDisplayOrigins()
+	}
+	fmt.Fprintln(os.Stderr, "[+] Current configuration and available variables:")
+	configJSON, err := env.GetConfigAll(showSecrets)
+	if err != nil {
+		return err
+	}
+	return renderOutput(env.GetConfigSettings(showSecrets), func(out io.Writer) {
+		fmt.Fprintln(out, string(configJSON))
+	})
+}
+
+func configDisplayOrigins() error {
+	origins := env.GetConfigOrigins(hostOverride, contextOverride, showSecrets)
+	for _, origin := range origins {
+		if origin.Shadowed != nil {
+			fmt.Fprintf(os.Stderr, "[!] The %s %s overrides the saved `%s` value (%v is used instead of %v)\n", origin.Source, describeOriginSource(origin), origin.Key, origin.Value, origin.Shadowed)
+		}
+	}
+	return renderOutput(origins, func(out io.Writer) {
+		printOriginTable(out, origins)
+	})
 }
 
-func configDisplay(cmd *cobra.Command, args []string) {
-	fmt.Println("[+] Current configuration and available variables:")
-	configuration := env.GetConfigAll()
-	fmt.Println(string(configuration))
+// describeOriginSource returns "flag" or "environment variable" for the source of an overriding value.
+func describeOriginSource(origin env.ConfigOrigin) string {
+	if origin.Origin == env.OriginFlag {
+		return "flag"
+	}
+	return "environment variable"
+}
+
+// printOriginTable writes each key's effective value and origin as a table.
+func printOriginTable(out io.Writer, origins []env.ConfigOrigin) {
+	writer := new(tabwriter.Writer)
+	writer.Init(out, 8, 8, 1, '\t', 0)
+	defer writer.Flush()
+
+	fmt.Fprintf(writer, "\n %s\t%s\t%s", "Key", "Value", "Origin")
+	fmt.Fprintf(writer, "\n %s\t%s\t%s", "–––", "–––––", "––––––")
+	for _, origin := range origins {
+		source := origin.Origin
+		if origin.Source != "" {
+			source = fmt.Sprintf("%s (%s)", origin.Origin, origin.Source)
+		}
+		fmt.Fprintf(writer, "\n %s\t%v\t%s", origin.Key, origin.Value, source)
+	}
+	fmt.Fprintln(writer, "")
 }
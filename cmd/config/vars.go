@@ -2,6 +2,8 @@ package config
 
 // Constants and variables used by the BloodHound CLI
 
+import "io/fs"
+
 var (
 	// BloodHound CLI version
 	// This gets populated at build time with the following flags:
@@ -14,4 +16,11 @@ var (
 	Name        string = "BloodHound CLI"
 	DisplayName string = "BloodHound CLI"
 	Description string = "A command line interface for BloodHound Community Edition"
+	// Base64-encoded Ed25519 public key for verifying the signatures of release assets
+	// Builds without a key can still verify the SHA-256 checksums, and can be given one with:
+	//   -X 'github.com/SpecterOps/BloodHound_CLI/cmd/config.ReleaseSigningKey=<key>'
+	ReleaseSigningKey string
+	// Docker YAML files built into the binary
+	// The main package sets this because only it can embed the files at the root of the repository
+	ComposeFiles fs.FS
 )
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	env "github.com/SpecterOps/BloodHound_CLI/cmd/internal"
+	"github.com/spf13/cobra"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// configDescribeCmd represents the configDescribe command
+var configDescribeCmd = &cobra.Command{
+	Use:   "describe [<configuration> ...]",
+	Short: "Describe the configuration keys",
+	Long: `Describe the configuration keys, including each key's type, allowed values,
+default, and purpose. Without arguments, the command lists every key.
+
+For example: bloodhound-cli config describe log_level`,
+	RunE: configDescribe,
+}
+
+// keyDescription is the documentation for a config key.
+type keyDescription struct {
+	env.ConfigKey
+	Default string `json:"default"`
+	EnvVar  string `json:"env_var,omitempty"`
+}
+
+func init() {
+	configCmd.AddCommand(configDescribeCmd)
+}
+
+func configDescribe(cmd *cobra.Command, args []string) error {
+	var keys []env.ConfigKey
+	if len(args) == 0 {
+		keys = env.ConfigSchema()
+	}
+	for _, arg := range args {
+		key, ok := env.LookupConfigKey(arg)
+		if !ok {
+			return fmt.Errorf("%w: `%s` is not a known config key", env.ErrConfigKeyNotFound, arg)
+		}
+		keys = append(keys, key)
+	}
+
+	var descriptions []keyDescription
+	for _, key := range keys {
+		descriptions = append(descriptions, keyDescription{ConfigKey: key, Default: key.DefaultString(), EnvVar: key.EnvVar()})
+	}
+	return renderOutput(descriptions, func(out io.Writer) {
+		if len(args) == 0 {
+			printKeyTable(out, descriptions)
+			return
+		}
+		for _, description := range descriptions {
+			printKeyDescription(out, description)
+		}
+	})
+}
+
+// printKeyTable writes a table with the key, type, and description of every key.
+func printKeyTable(out io.Writer, descriptions []keyDescription) {
+	writer := new(tabwriter.Writer)
+	writer.Init(out, 8, 8, 1, '\t', 0)
+	defer writer.Flush()
+
+	fmt.Fprintf(writer, "\n %s\t%s\t%s", "Key", "Type", "Description")
+	fmt.Fprintf(writer, "\n %s\t%s\t%s", "–––", "––––", "–––––––––––")
+	for _, description := range descriptions {
+		fmt.Fprintf(writer, "\n %s\t%s\t%s", description.Key, description.Type, description.Description)
+	}
+	fmt.Fprintln(writer, "")
+}
+
+// printKeyDescription writes the full documentation for a key.
+func printKeyDescription(out io.Writer, description keyDescription) {
+	fmt.Fprintf(out, "\n%s\n", description.Key)
+	fmt.Fprintf(out, "  %s\n", description.Description)
+	fmt.Fprintf(out, "  Type: %s\n", description.Type)
+	if len(description.Allowed) > 0 {
+		fmt.Fprintf(out, "  Allowed values: %s\n", strings.Join(description.Allowed, ", "))
+	}
+	fmt.Fprintf(out, "  Default: %s\n", description.Default)
+	if description.EnvVar != "" {
+		fmt.Fprintf(out, "  Compose variable: %s\n", description.EnvVar)
+	}
+	if description.Secret {
+		fmt.Fprintln(out, "  Secret: yes (redacted by `config` and kept in the secret store, if one is configured)")
+	}
+	if description.ReadOnly {
+		fmt.Fprintln(out, "  Read-only: yes (managed by BloodHound CLI)")
+	}
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	env "github.com/SpecterOps/BloodHound_CLI/cmd/internal"
+	"github.com/spf13/cobra"
+)
+
+// configEditCmd represents the configEdit command
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edit the JSON config file in your editor",
+	Long: `Open a copy of the JSON config file in the editor from the VISUAL or EDITOR environment
+variable (vi by default, or Notepad on Windows).
+
+When you close the editor, the file is checked against the config schema. Valid changes replace the
+configuration, and the previous file is saved as "bloodhound.config.json.bak" in the config
+directory. If the file is not valid, you can edit it again or discard the changes. Unknown keys are
+rejected unless you add "--force".
+
+For example: EDITOR=nano bloodhound-cli config edit`,
+	Args: cobra.NoArgs,
+	RunE: configEdit,
+}
+
+var forceConfigEdit bool
+
+func init() {
+	configCmd.AddCommand(configEditCmd)
+
+	configEditCmd.Flags().BoolVar(&forceConfigEdit, "force", false, "Keep keys that are not in the config schema")
+}
+
+func configEdit(cmd *cobra.Command, args []string) error {
+	return env.EditConfig(forceConfigEdit)
+}
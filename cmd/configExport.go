@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	env "github.com/SpecterOps/BloodHound_CLI/cmd/internal"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// configExportCmd represents the configExport command
+var configExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the configuration to share it with another system",
+	Long: `Write the configuration to standard output as JSON (the default), YAML, or a Compose ".env"
+file. The "config_directory" value is left out because it belongs to this system.
+
+The "env" format has the variables the Docker YAML files read, so the output can be saved as the
+".env" file next to them. Add "--redact" to blank the secrets before sharing the file; importing a
+file with blank secrets keeps the secrets already on the importing system.
+
+For example: bloodhound-cli config export --redact --format yaml > bloodhound.yaml`,
+	Args: cobra.NoArgs,
+	RunE: configExport,
+}
+
+var (
+	exportFormat   string
+	redactedExport bool
+)
+
+func init() {
+	configCmd.AddCommand(configExportCmd)
+
+	configExportCmd.Flags().StringVar(&exportFormat, "format", env.OutputJson, "Export format: json, yaml, or env")
+	configExportCmd.Flags().BoolVar(&redactedExport, "redact", false, "Blank the secret values")
+}
+
+func configExport(cmd *cobra.Command, args []string) error {
+	return env.ExportConfig(os.Stdout, exportFormat, redactedExport)
+}
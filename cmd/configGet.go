@@ -4,6 +4,7 @@ import (
 	"fmt"
 	env "github.com/SpecterOps/BloodHound_CLI/cmd/internal"
 	"github.com/spf13/cobra"
+	"io"
 	"os"
 	"strings"
 	"text/tabwriter"
@@ -17,26 +18,36 @@ var configGetCmd = &cobra.Command{
 a list of values separated by spaces.
 
 For example: bloodhound-cli config get ADMIN_PASSWORD POSTGRES_PASSWORD`,
-	Run: configGet,
+	RunE: configGet,
 }
 
 func init() {
 	configCmd.AddCommand(configGetCmd)
 }
 
-func configGet(cmd *cobra.Command, args []string) {
+func configGet(cmd *cobra.Command, args []string) error {
+	fmt.Fprintln(os.Stderr, "[+] Getting configuration values:")
+	results, err := env.GetConfig(args)
+	if err != nil {
+		return err
+	}
+	return renderOutput(results, func(out io.Writer) {
+		printConfigTable(out, results)
+	})
+}
+
+// printConfigTable writes the configuration values as a table of settings and values.
+func printConfigTable(out io.Writer, results env.Configurations) {
 	// initialize tabwriter
 	writer := new(tabwriter.Writer)
 	// Set minwidth, tabwidth, padding, padchar, and flags
-	writer.Init(os.Stdout, 8, 8, 1, '\t', 0)
+	writer.Init(out, 8, 8, 1, '\t', 0)
 
 	defer writer.Flush()
 
-	fmt.Println("[+] Getting configuration values:")
 	fmt.Fprintf(writer, "\n %s\t%s", "Setting", "Value")
 	fmt.Fprintf(writer, "\n %s\t%s", "–––––––", "–––––––")
 
-	results := env.GetConfig(args)
 	for _, config := range results {
 		if config.Val == "" {
 			config.Val = "–"
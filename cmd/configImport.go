@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	env "github.com/SpecterOps/BloodHound_CLI/cmd/internal"
+	"github.com/spf13/cobra"
+)
+
+// configImportCmd represents the configImport command
+var configImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a configuration written by \"config export\"",
+	Long: `Import a JSON, YAML, or Compose ".env" file written by "config export". The format is chosen by
+the file's extension (".json", ".yaml", ".yml", or ".env").
+
+The imported values replace the configuration, except for the config directory and the secrets,
+which are kept unless the file has them. Add "--merge" to keep every current value the file does not
+set. Blank secrets, such as those from "config export --redact", keep their current values.
+
+The database credentials (the Postgres and Neo4j users, passwords, and database name) are always
+kept because they must match the existing databases. Add "--include-credentials" to import them, for
+example when the databases were restored from the system that exported the file.
+
+The command validates the file against the config schema and shows the changes before asking
+for confirmation. The previous file is saved as "bloodhound.config.json.bak" in the config
+directory. Unknown keys are rejected unless you add "--force".
+
+For example: bloodhound-cli config import --merge bloodhound.yaml`,
+	Args: cobra.ExactArgs(1),
+	RunE: configImport,
+}
+
+var (
+	mergeConfigImport       bool
+	credentialsConfigImport bool
+	forceConfigImport       bool
+)
+
+func init() {
+	configCmd.AddCommand(configImportCmd)
+
+	configImportCmd.Flags().BoolVar(&mergeConfigImport, "merge", false, "Keep current values that the file does not set")
+	configImportCmd.Flags().BoolVar(&credentialsConfigImport, "include-credentials", false, "Import the database credentials instead of keeping the current ones")
+	configImportCmd.Flags().BoolVar(&forceConfigImport, "force", false, "Keep keys that are not in the config schema")
+}
+
+func configImport(cmd *cobra.Command, args []string) error {
+	return env.ImportConfig(args[0], mergeConfigImport, credentialsConfigImport, forceConfigImport)
+}
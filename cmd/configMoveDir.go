@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	env "github.com/SpecterOps/BloodHound_CLI/cmd/internal"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// configMoveDirCmd represents the configMoveDir command
+var configMoveDirCmd = &cobra.Command{
+	Use:   "move-dir <path>",
+	Short: "Move the config directory to a new location",
+	Long: `Move the config directory to a new location, such as a dedicated data disk.
+
+The command performs the following steps:
+
+* Creates the new directory with the permissions for the "permissions_mode" config value
+* Copies the JSON config file, the encrypted secrets file, the Docker YAML files, and any TLS
+  files stored in the config directory
+* Verifies the SHA-256 checksum of every copy
+* Records the current Compose project name as "compose_project", because Compose would
+  otherwise name the project after the new directory and stop using the existing volumes
+* Updates "config_directory" and replaces the JSON config file in the default config directory
+  with a pointer to the new directory
+* Deletes the moved files from the old directory if you add "--remove-old"
+
+The new directory must not exist or be empty, and the BloodHound containers must be down. The
+default config directory keeps the pointer, so do not delete it. Backup archives and other files
+you added to the old directory are not moved.
+
+For example: bloodhound-cli config move-dir /data/bloodhound`,
+	Args: cobra.ExactArgs(1),
+	RunE: configMoveDir,
+}
+
+var removeOldConfigDir bool
+
+func init() {
+	configCmd.AddCommand(configMoveDirCmd)
+
+	configMoveDirCmd.Flags().BoolVar(&removeOldConfigDir, "remove-old", false, "Delete the moved files from the old config directory")
+}
+
+func configMoveDir(cmd *cobra.Command, args []string) error {
+	// The containers mount the JSON config file from the config directory
+	rt, err := newRuntime()
+	if err == nil {
+		running, err := env.GetRunning(rt)
+		if err != nil {
+			return err
+		}
+		if len(running) > 0 {
+			return fmt.Errorf("%w: bring the containers down with `down` before moving the config directory", env.ErrInvalidConfig)
+		}
+	} else if errors.Is(err, env.ErrInvalidConfig) {
+		return err
+	} else {
+		fmt.Fprintf(os.Stderr, "[!] Could not check for running containers, so make sure they are down: %s\n", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "[+] Moving the config directory to %s\n", args[0])
+	move, err := env.MoveConfigDir(args[0], removeOldConfigDir)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "[+] Copied and verified %d file(s) from %s\n", len(move.Files), move.From)
+	switch {
+	case move.Removed:
+		fmt.Fprintf(os.Stderr, "[+] Deleted the old config directory, %s\n", move.From)
+	case removeOldConfigDir:
+		fmt.Fprintf(os.Stderr, "[+] Deleted the moved files from %s; anything else in it was left in place\n", move.From)
+	default:
+		fmt.Fprintf(os.Stderr, "[+] The old files are still in %s; delete them once you have checked the new directory\n", move.From)
+	}
+	fmt.Fprintf(os.Stderr, "[+] Compose keeps using the %s project for the existing containers and volumes\n", move.Project)
+	fmt.Println("[+] The config directory is now " + move.To)
+	return nil
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"fmt"
+	env "github.com/SpecterOps/BloodHound_CLI/cmd/internal"
+	"github.com/spf13/cobra"
+)
+
+// configResetCmd represents the configReset command
+var configResetCmd = &cobra.Command{
+	Use:   "reset [<configuration> ...]",
+	Short: "Revert configuration values to their defaults",
+	Long: `Revert the specified configuration values to their defaults. You can provide one key or
+a list of keys separated by spaces.
+
+Without any keys, every value is reverted and unknown keys are removed after you confirm. The
+config directory, the pinned "bloodhound.tag" image tag, the database credentials, and the
+secrets are kept because they must match the existing deployment. Run "config describe" to see each key's default.
+
+For example: bloodhound-cli config reset log_level root_url`,
+	RunE: configReset,
+}
+
+func init() {
+	configCmd.AddCommand(configResetCmd)
+}
+
+func configReset(cmd *cobra.Command, args []string) error {
+	if err := env.ResetConfig(args); err != nil {
+		return err
+	}
+	fmt.Println("[+] Bring containers down and up for any changes to take effect.")
+	return nil
+}
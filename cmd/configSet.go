@@ -1,9 +1,11 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	env "github.com/SpecterOps/BloodHound_CLI/cmd/internal"
 	"github.com/spf13/cobra"
+	"os"
 )
 
 // configSetCmd represents the configSet command
@@ -13,16 +15,43 @@ var configSetCmd = &cobra.Command{
 	Long: `Set the specified configuration value. Use quotations around the value
 if it contains spaces.
 
-For example: bloodhound-cli config set NEO4J_USER "bloodhound"`,
+The key and value are checked against the config schema, so unknown keys and values of the wrong
+type are rejected. Run "config describe" to list the keys and their types. Use "--force" to set a
+key that is not in the schema.
+
+The database credentials (e.g., "neo4j.secret") cannot contain spaces or any of @ / : ? # and
+cannot be changed while BloodHound containers exist, because they must match the credentials the
+databases were created with. Use "rotate-secrets" to change the passwords of a deployment.
+
+For example: bloodhound-cli config set log_level "DEBUG"`,
 	Args: cobra.ExactArgs(2),
-	Run:  configSet,
+	RunE: configSet,
 }
 
+var forceConfig bool
+
 func init() {
 	configCmd.AddCommand(configSetCmd)
+
+	configSetCmd.Flags().BoolVar(&forceConfig, "force", false, "Set a key that is not in the config schema")
 }
 
-func configSet(cmd *cobra.Command, args []string) {
-	env.SetConfig(args[0], args[1])
+func configSet(cmd *cobra.Command, args []string) error {
+	if env.IsDatabaseCredential(args[0]) {
+		rt, err := newRuntime()
+		if err == nil {
+			if err := env.CheckCredentialChange(rt, args[0]); err != nil {
+				return err
+			}
+		} else if errors.Is(err, env.ErrInvalidConfig) {
+			return err
+		} else {
+			fmt.Fprintf(os.Stderr, "[!] Could not check for existing containers, so make sure this credential matches the databases: %s\n", err)
+		}
+	}
+	if err := env.SetConfig(args[0], args[1], forceConfig); err != nil {
+		return err
+	}
 	fmt.Println("[+] Configuration successfully updated. Bring containers down and up for changes to take effect.")
+	return nil
 }
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"testing"
+
+	env "github.com/SpecterOps/BloodHound_CLI/cmd/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigSetCredentials(t *testing.T) {
+	rt := newFakeStack()
+	useFakeRuntime(t, rt)
+
+	_, err := runCommand(t, "config", "set", "neo4j.secret", "NewSecret1")
+	assert.ErrorIs(t, err, env.ErrInvalidConfig, "A database credential should not be changed while BloodHound containers exist")
+	assert.Equal(t, exitConfigError, exitCode(err))
+
+	rt.Containers = nil
+	_, err = runCommand(t, "config", "set", "neo4j.secret", "bad@secret")
+	assert.ErrorIs(t, err, env.ErrInvalidConfig, "A credential that would break the connection URLs should be refused")
+	_, err = runCommand(t, "config", "set", "neo4j.secret", "NewSecret1")
+	assert.NoError(t, err, "A database credential should be changeable before the first install")
+	out, err := runCommand(t, "config", "get", "neo4j.secret", "--output", "json")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "NewSecret1")
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"fmt"
+	env "github.com/SpecterOps/BloodHound_CLI/cmd/internal"
+	"github.com/spf13/cobra"
+)
+
+// configUnsetCmd represents the configUnset command
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <configuration>",
+	Short: "Remove the specified configuration value",
+	Long: `Remove the specified configuration value from the JSON config file. Keys with a
+default go back to the default, and other keys (e.g., a key set by mistake with "config set --force")
+are removed.
+
+Secrets and the values managed by BloodHound CLI cannot be removed. Use "resetpwd" or
+"rotate-secrets" to change the secrets.
+
+For example: bloodhound-cli config unset bloodhound.port`,
+	Args: cobra.ExactArgs(1),
+	RunE: configUnset,
+}
+
+func init() {
+	configCmd.AddCommand(configUnsetCmd)
+}
+
+func configUnset(cmd *cobra.Command, args []string) error {
+	if err := env.UnsetConfig(args[0]); err != nil {
+		return err
+	}
+	fmt.Println("[+] Configuration successfully updated. Bring containers down and up for changes to take effect.")
+	return nil
+}
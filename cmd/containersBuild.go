@@ -14,7 +14,7 @@ var containersBuildCmd = &cobra.Command{
 
 Note: Build will stop a container if it is already running. You will need to run
 the "up" command to start the containers after the build.`,
-	Run: buildContainers,
+	RunE: buildContainers,
 }
 
 func init() {
@@ -23,8 +23,15 @@ func init() {
 
 // buildContainers builds and upgrades BloodHound containers using Docker Compose.
 // It checks the current Docker Compose status before initiating the build process.
-func buildContainers(cmd *cobra.Command, args []string) {
-	docker.EvaluateDockerComposeStatus()
+func buildContainers(cmd *cobra.Command, args []string) error {
+	rt, err := newRuntime()
+	if err != nil {
+		return err
+	}
 	fmt.Println("[+] Starting build")
-	docker.RunDockerComposeUpgrade(docker.GetYamlFilePath(fileOverride))
+	yaml, err := docker.GetYamlFilePath(fileOverride)
+	if err != nil {
+		return err
+	}
+	return docker.RunDockerComposeUpgrade(rt, yaml)
 }
@@ -14,7 +14,7 @@ var containersDownCmd = &cobra.Command{
 	Short: "Bring down all BloodHound services and remove the containers",
 	Long: `Bring down all BloodHound services and remove the containers. This
 performs the equivalent of running the "docker compose down" command.`,
-	Run: containersDown,
+	RunE: containersDown,
 }
 
 func init() {
@@ -24,8 +24,15 @@ func init() {
 }
 
 // containersDown brings down all BloodHound Docker services and optionally removes their data volumes.
-func containersDown(cmd *cobra.Command, args []string) {
-	docker.EvaluateDockerComposeStatus()
+func containersDown(cmd *cobra.Command, args []string) error {
+	rt, err := newRuntime()
+	if err != nil {
+		return err
+	}
 	fmt.Println("[+] Bringing down the BloodHound environment")
-	docker.RunDockerComposeDown(docker.GetYamlFilePath(fileOverride), volumes)
+	yaml, err := docker.GetYamlFilePath(fileOverride)
+	if err != nil {
+		return err
+	}
+	return docker.RunDockerComposeDown(rt, yaml, volumes)
 }
@@ -12,7 +12,7 @@ var containersRestartCmd = &cobra.Command{
 	Short: "Restart all stopped and running BloodHound services",
 	Long: `Restart all stopped and running BloodHound services. This performs
 the equivalent of running the "docker compose restart" command.`,
-	Run: containersRestart,
+	RunE: containersRestart,
 }
 
 func init() {
@@ -20,8 +20,15 @@ func init() {
 }
 
 // containersRestart restarts all BloodHound services using the Docker Compose file located in the BloodHound directory.
-func containersRestart(cmd *cobra.Command, args []string) {
-	docker.EvaluateDockerComposeStatus()
+func containersRestart(cmd *cobra.Command, args []string) error {
+	rt, err := newRuntime()
+	if err != nil {
+		return err
+	}
 	fmt.Println("[+] Restarting the BloodHound environment")
-	docker.RunDockerComposeRestart(docker.GetYamlFilePath(fileOverride))
+	yaml, err := docker.GetYamlFilePath(fileOverride)
+	if err != nil {
+		return err
+	}
+	return docker.RunDockerComposeRestart(rt, yaml)
 }
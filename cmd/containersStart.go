@@ -12,7 +12,7 @@ var containersStartCmd = &cobra.Command{
 	Short: "Start all stopped BloodHound services",
 	Long: `Start all stopped BloodHound services. This performs the equivalent
 of running the "docker compose start" command.`,
-	Run: containersStart,
+	RunE: containersStart,
 }
 
 func init() {
@@ -20,8 +20,15 @@ func init() {
 }
 
 // containersStart starts all stopped BloodHound Docker Compose services by invoking the appropriate Docker Compose command with the configuration file located in the BloodHound directory.
-func containersStart(cmd *cobra.Command, args []string) {
-	docker.EvaluateDockerComposeStatus()
+func containersStart(cmd *cobra.Command, args []string) error {
+	rt, err := newRuntime()
+	if err != nil {
+		return err
+	}
 	fmt.Println("[+] Starting the BloodHound environment")
-	docker.RunDockerComposeStart(docker.GetYamlFilePath(fileOverride))
+	yaml, err := docker.GetYamlFilePath(fileOverride)
+	if err != nil {
+		return err
+	}
+	return docker.RunDockerComposeStart(rt, yaml)
 }
@@ -12,7 +12,7 @@ var containersStopCmd = &cobra.Command{
 	Short: "Stop all BloodHound services without removing the containers",
 	Long: `Stop all BloodHound services without removing the containers. This
 performs the equivalent of running the "docker compose stop" command.`,
-	Run: containersStop,
+	RunE: containersStop,
 }
 
 func init() {
@@ -20,8 +20,15 @@ func init() {
 }
 
 // containersStop stops all BloodHound Docker Compose services without removing their containers.
-func containersStop(cmd *cobra.Command, args []string) {
-	docker.EvaluateDockerComposeStatus()
+func containersStop(cmd *cobra.Command, args []string) error {
+	rt, err := newRuntime()
+	if err != nil {
+		return err
+	}
 	fmt.Println("[+] Stopping the BloodHound environment")
-	docker.RunDockerComposeStop(docker.GetYamlFilePath(fileOverride))
+	yaml, err := docker.GetYamlFilePath(fileOverride)
+	if err != nil {
+		return err
+	}
+	return docker.RunDockerComposeStop(rt, yaml)
 }
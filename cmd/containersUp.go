@@ -12,16 +12,29 @@ var containersUpCmd = &cobra.Command{
 	Short: "Build, (re)create, and start all BloodHound containers",
 	Long: `Build, (re)create, and start all BloodHound containers. This
 performs the equivalent of running the "docker compose up" command.`,
-	Run: containersUp,
+	RunE: containersUp,
 }
 
 func init() {
 	containersCmd.AddCommand(containersUpCmd)
+
+	containersUpCmd.Flags().DurationVar(&waitTimeout, "timeout", defaultWaitTimeout, waitTimeoutUsage)
 }
 
 // containersUp brings up the BloodHound container environment by evaluating Docker Compose status and running `docker compose up` with the BloodHound configuration.
-func containersUp(cmd *cobra.Command, args []string) {
-	docker.EvaluateDockerComposeStatus()
+// It then waits for the services to become healthy unless the timeout is zero.
+func containersUp(cmd *cobra.Command, args []string) error {
+	rt, err := newRuntime()
+	if err != nil {
+		return err
+	}
 	fmt.Println("[+] Bringing up the BloodHound environment")
-	docker.RunDockerComposeUp(docker.GetYamlFilePath(fileOverride))
+	yaml, err := docker.GetYamlFilePath(fileOverride)
+	if err != nil {
+		return err
+	}
+	if err := docker.RunDockerComposeUp(rt, yaml); err != nil {
+		return err
+	}
+	return docker.WaitForStack(rt, waitTimeout)
 }
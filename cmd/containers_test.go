@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	env "github.com/SpecterOps/BloodHound_CLI/cmd/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainersUpAndDown(t *testing.T) {
+	rt := newFakeStack()
+	useFakeRuntime(t, rt)
+	yaml := writeTestYaml(t)
+
+	_, err := runCommand(t, "up", "--file", yaml, "--timeout", "0")
+	assert.NoError(t, err, "`up` should bring up the containers")
+	_, err = runCommand(t, "containers", "down", "--file", yaml, "--volumes")
+	assert.NoError(t, err, "`containers down` should bring down the containers")
+	_, err = runCommand(t, "down", "--file", yaml)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"up -d", "down --volumes", "down"}, rt.Commands, "Each command should run its Compose command, and flags should not carry over")
+
+	rt.Errors["Up"] = errors.New("compose failed")
+	_, err = runCommand(t, "up", "--file", yaml, "--timeout", "0")
+	assert.ErrorContains(t, err, "compose failed", "`up` should return the Compose error")
+	assert.Equal(t, exitError, exitCode(err))
+}
+
+func TestContainersUpMissingYaml(t *testing.T) {
+	rt := newFakeStack()
+	useFakeRuntime(t, rt)
+
+	_, err := runCommand(t, "up", "--file", "missing.yml", "--timeout", "0")
+	assert.ErrorIs(t, err, env.ErrYamlMissing)
+	assert.Equal(t, exitYamlMissing, exitCode(err))
+	assert.Empty(t, rt.Commands, "No Compose command should run without the YAML file")
+}
+
+func TestRunning(t *testing.T) {
+	rt := newFakeStack()
+	rt.Containers[2].State = "exited"
+	useFakeRuntime(t, rt)
+
+	out, err := runCommand(t, "running", "--output", "json")
+	assert.NoError(t, err)
+	var containers []map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(out), &containers), "`--output json` should write only JSON to stdout")
+	assert.Len(t, containers, 2, "Only the running containers should be listed")
+}
@@ -8,8 +8,8 @@ import (
 var downCmd = &cobra.Command{
 	Use:   "down",
 	Short: "Shortcut for `containers down`",
-	Run: func(cmd *cobra.Command, args []string) {
-		containersDownCmd.Run(cmd, args)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return containersDownCmd.RunE(cmd, args)
 	},
 }
 
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	docker "github.com/SpecterOps/BloodHound_CLI/cmd/internal"
+	"github.com/spf13/cobra"
+)
+
+// healthCmd represents the health command
+var healthCmd = &cobra.Command{
+	Use:   "health",
+	Short: "Check the health of the BloodHound services",
+	Long: `Check the health of the BloodHound services.
+
+The command inspects each BloodHound container for its healthcheck state, restart count, out-of-memory kills, and
+exit codes. It also checks that the BloodHound web server responds at the configured "root_url", that the Neo4j bolt
+port accepts connections, and that Postgres is ready for connections. The bolt port is checked from inside the Neo4j
+container when it is not published on the host (e.g., after "install --secure").
+
+The command exits with a non-zero status if any errors are found, so it can be used for monitoring. Warnings do not
+change the exit status.`,
+	RunE: checkHealth,
+}
+
+func init() {
+	rootCmd.AddCommand(healthCmd)
+}
+
+// checkHealth prints a table of any health issues found in the BloodHound deployment and returns ErrUnhealthy if any
+// of them are errors.
+func checkHealth(cmd *cobra.Command, args []string) error {
+	rt, err := newRuntime()
+	if err != nil {
+		return err
+	}
+	fmt.Println("[+] Checking the health of the BloodHound services...")
+
+	yaml, err := docker.GetYamlFilePath(fileOverride)
+	if err != nil {
+		return err
+	}
+	issues, err := docker.CheckHealth(rt, yaml)
+	if err != nil {
+		return err
+	}
+	if len(issues) == 0 {
+		fmt.Println("[+] All BloodHound services are healthy")
+		return nil
+	}
+
+	// initialize tabwriter
+	writer := new(tabwriter.Writer)
+	// Set minwidth, tabwidth, padding, padchar, and flags
+	writer.Init(os.Stdout, 8, 8, 1, ' ', 0)
+
+	fmt.Printf("[!] Found %d health issues\n", len(issues))
+	fmt.Fprintf(writer, "\n %s\t%s\t%s", "Type", "Service", "Message")
+	fmt.Fprintf(writer, "\n %s\t%s\t%s", "––––––––––––", "––––––––––––", "––––––––––––")
+	for _, issue := range issues {
+		fmt.Fprintf(writer, "\n %s\t%s\t%s", issue.Type, issue.Service, issue.Message)
+	}
+	fmt.Fprintln(writer, "")
+	writer.Flush()
+
+	if issues.HasErrors() {
+		return docker.ErrUnhealthy
+	}
+	return nil
+}
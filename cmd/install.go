@@ -4,9 +4,11 @@ import (
 	"fmt"
 	docker "github.com/SpecterOps/BloodHound_CLI/cmd/internal"
 	"github.com/spf13/cobra"
-	"log"
 )
 
+// Flag for replacing default database passwords during the install
+var installSecure bool
+
 // installCmd represents the install command
 var installCmd = &cobra.Command{
 	Use:   "install",
@@ -16,26 +18,48 @@ var installCmd = &cobra.Command{
 The command performs the following steps:
 
 * Sets up the default server configuration
+* Generates random Postgres and Neo4j passwords for a new deployment
 * Builds the Docker containers
 * Creates a default admin user with a randomly generated password
+* Waits for the services to become healthy and the UI to respond (see "--timeout")
 
 This command only needs to be run once. If you run it again, you will see some errors because
-certain actions (e.g., creating the default user) can and should only be done once.`,
-	Run: installBloodHound,
+certain actions (e.g., creating the default user) can and should only be done once.
+
+Use "--secure" to also replace database passwords that are set to the well-known default, to
+restrict the JSON config file to the current user, and to stop publishing the Neo4j bolt and web
+ports on 127.0.0.1 (see the "neo4j.publish_ports" config value). With "--secure", the command
+stops if it finds an existing deployment that still needs new passwords; use "rotate-secrets" for
+those deployments.
+
+Unpublishing the Neo4j ports requires Docker Compose 2.24.0 or later, including when Podman runs
+Docker Compose. The standalone podman-compose script and older Compose releases do not support it,
+so keep "neo4j.publish_ports" set to true with them.`,
+	RunE: installBloodHound,
 }
 
 // init registers the install command with the root command, making it available in the CLI.
 func init() {
 	rootCmd.AddCommand(installCmd)
+
+	installCmd.Flags().DurationVar(&waitTimeout, "timeout", defaultWaitTimeout, waitTimeoutUsage)
+	installCmd.Flags().BoolVar(&installSecure, "secure", false, "Replace default database passwords, restrict the JSON config file to the current user, and stop publishing the Neo4j ports")
 }
 
 // installBloodHound sets up the BloodHound environment by verifying Docker Compose status, creating the required home directory, and launching the Docker containers using the installation configuration.
-func installBloodHound(cmd *cobra.Command, args []string) {
-	docker.EvaluateDockerComposeStatus()
+func installBloodHound(cmd *cobra.Command, args []string) error {
+	rt, err := newRuntime()
+	if err != nil {
+		return err
+	}
 	configErr := docker.MakeConfigDir()
 	if configErr != nil {
-		log.Fatalf("Error creating config directory: %v", configErr)
+		return fmt.Errorf("error creating config directory: %w", configErr)
 	}
 	fmt.Println("[+] Starting BloodHound environment installation")
-	docker.RunDockerComposeInstall(docker.GetYamlFilePath(fileOverride))
+	yaml, err := docker.GetYamlFilePath(fileOverride)
+	if err != nil {
+		return err
+	}
+	return docker.RunDockerComposeInstall(rt, yaml, waitTimeout, installSecure)
 }
@@ -0,0 +1,574 @@
+package internal
+
+// Functions for creating and restoring backup archives of a BloodHound deployment's data volumes and configuration
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/SpecterOps/BloodHound_CLI/cmd/config"
+	"github.com/moby/moby/api/types/container"
+)
+
+// Vars for the files stored inside a backup archive
+var (
+	backupManifestFile = "manifest.json"
+	backupPostgresFile = "postgres.dump"
+	backupNeo4jFile    = "neo4j-data.tar"
+	backupConfigFile   = "bloodhound.config.json"
+	backupYamlFile     = "docker-compose.yml"
+	// Commands run inside the containers; the values come from the container's own environment
+	pgDumpCmd    = `pg_dump --format=custom --username="$POSTGRES_USER" --dbname="$POSTGRES_DB"`
+	pgRestoreCmd = `pg_restore --clean --if-exists --no-owner --username="$POSTGRES_USER" --dbname="$POSTGRES_DB"`
+	pgReadyCmd   = `pg_isready --username="$POSTGRES_USER" --dbname="$POSTGRES_DB"`
+	// Path of the Neo4j data volume inside the ``graph-db`` container
+	neo4jDataPath = "/data"
+)
+
+// BackupImage records the image reference and image ID of a BloodHound container at the time of a backup.
+type BackupImage struct {
+	Image   string `json:"image"`
+	ImageID string `json:"image_id"`
+}
+
+// BackupManifest describes the contents of a backup archive.
+type BackupManifest struct {
+	CreatedAt  string                 `json:"created_at"`
+	CliVersion string                 `json:"cli_version"`
+	Images     map[string]BackupImage `json:"images"`
+	Checksums  map[string]string      `json:"checksums"`
+}
+
+// RunBackup creates a timestamped tar.gz archive in the "outputDir" directory containing a Postgres dump, a copy of the
+// Neo4j data volume, the configuration with any secrets held by a secret store, and the specified Docker Compose YAML
+// file. The BloodHound and Neo4j services are stopped while the data is copied and started again afterward. Returns the
+// path to the new archive.
+func RunBackup(rt Runtime, yaml string, outputDir string) (string, error) {
+	if err := CheckYamlExists(yaml); err != nil {
+		return "", err
+	}
+
+	stagingDir, err := os.MkdirTemp("", "bloodhound-backup-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create a temporary directory for the backup: %w", err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	images, err := GetBloodHoundImages(rt)
+	if err != nil {
+		return "", err
+	}
+	if len(images) == 0 {
+		return "", errors.New("no BloodHound containers were found, so there is nothing to back up; run `bloodhound-cli up` and try again")
+	}
+	manifest := BackupManifest{
+		CreatedAt:  time.Now().UTC().Format(time.RFC3339),
+		CliVersion: config.Version,
+		Images:     images,
+		Checksums:  map[string]string{},
+	}
+
+	// Stop the application first so nothing writes to the databases while they are copied
+	fmt.Println("[+] Stopping the BloodHound and Neo4j services for the backup...")
+	stopErr := rt.Stop(yaml, "bloodhound", "graph-db")
+	if stopErr != nil {
+		return "", fmt.Errorf("error trying to stop the BloodHound services with %s: %w", yaml, stopErr)
+	}
+	defer func() {
+		fmt.Println("[+] Starting the BloodHound and Neo4j services again...")
+		startErr := rt.Start(yaml, "graph-db", "bloodhound")
+		if startErr != nil {
+			fmt.Printf("[-] Error trying to start the BloodHound services with %s: %v\n", yaml, startErr)
+		}
+	}()
+
+	fmt.Println("[+] Dumping the Postgres database...")
+	dumpErr := dumpPostgres(rt, yaml, filepath.Join(stagingDir, backupPostgresFile))
+	if dumpErr != nil {
+		return "", fmt.Errorf("error trying to dump the Postgres database: %w", dumpErr)
+	}
+
+	fmt.Println("[+] Copying the Neo4j data volume...")
+	copyErr := copyNeo4jData(rt, filepath.Join(stagingDir, backupNeo4jFile))
+	if copyErr != nil {
+		return "", fmt.Errorf("error trying to copy the Neo4j data volume: %w", copyErr)
+	}
+
+	configErr := writeBackupConfig(filepath.Join(stagingDir, backupConfigFile))
+	if configErr != nil {
+		return "", fmt.Errorf("error trying to write the JSON config file: %w", configErr)
+	}
+	yamlErr := CopyFile(yaml, filepath.Join(stagingDir, backupYamlFile))
+	if yamlErr != nil {
+		return "", fmt.Errorf("error trying to copy the YAML file: %w", yamlErr)
+	}
+
+	for _, name := range []string{backupPostgresFile, backupNeo4jFile, backupConfigFile, backupYamlFile} {
+		sum, sumErr := FileChecksum(filepath.Join(stagingDir, name))
+		if sumErr != nil {
+			return "", fmt.Errorf("error trying to calculate the checksum for %s: %w", name, sumErr)
+		}
+		manifest.Checksums[name] = sum
+	}
+	manifestErr := writeBackupManifest(manifest, filepath.Join(stagingDir, backupManifestFile))
+	if manifestErr != nil {
+		return "", fmt.Errorf("error trying to write the backup manifest: %w", manifestErr)
+	}
+
+	mkErr := os.MkdirAll(outputDir, 0700)
+	if mkErr != nil {
+		return "", fmt.Errorf("error trying to create the backup directory %s: %w", outputDir, mkErr)
+	}
+	archive := filepath.Join(outputDir, fmt.Sprintf("bloodhound-backup-%s.tar.gz", time.Now().UTC().Format("20060102-150405")))
+	archiveErr := WriteTarGz(archive, stagingDir, []string{
+		backupManifestFile, backupPostgresFile, backupNeo4jFile, backupConfigFile, backupYamlFile,
+	})
+	if archiveErr != nil {
+		return "", fmt.Errorf("error trying to write the backup archive: %w", archiveErr)
+	}
+
+	return archive, nil
+}
+
+// GetBloodHoundImages returns the image reference and image ID of every BloodHound container, running or stopped,
+// keyed by the container's "name" label.
+func GetBloodHoundImages(rt Runtime) (map[string]BackupImage, error) {
+	images := map[string]BackupImage{}
+	containers, err := rt.ListContainers(context.Background(), true)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range containers {
+		name := c.Labels["name"]
+		if Contains(devImages, name) || Contains(prodImages, name) {
+			images[name] = BackupImage{Image: c.Image, ImageID: c.ImageID}
+		}
+	}
+	return images, nil
+}
+
+// localBackupImages returns the images of the existing BloodHound containers for comparing with a backup. If there are
+// no containers, the images in the YAML file are pulled and the images recorded in the backup are looked up instead, so
+// the comparison does not create or replace any containers.
+func localBackupImages(rt Runtime, yaml string, backup map[string]BackupImage) (map[string]BackupImage, error) {
+	images, err := GetBloodHoundImages(rt)
+	if err != nil || len(images) > 0 {
+		return images, err
+	}
+	fmt.Println("[+] No BloodHound containers were found, so pulling the images to compare them with the backup...")
+	pullErr := rt.Pull(yaml)
+	if pullErr != nil {
+		return nil, fmt.Errorf("error trying to pull the images with %s: %w", yaml, pullErr)
+	}
+	for name, want := range backup {
+		// An image that is not available locally is reported as a mismatch by CompareBackupImages
+		inspect, inspectErr := rt.InspectImage(context.Background(), want.Image)
+		if inspectErr != nil {
+			continue
+		}
+		images[name] = BackupImage{Image: want.Image, ImageID: inspect.ID}
+	}
+	return images, nil
+}
+
+// findContainerByName returns the BloodHound container, running or stopped, with the specified "name" label.
+func findContainerByName(rt Runtime, name string) (container.Summary, error) {
+	containers, err := rt.ListContainers(context.Background(), true)
+	if err != nil {
+		return container.Summary{}, err
+	}
+	for _, c := range containers {
+		if c.Labels["name"] == name {
+			return c, nil
+		}
+	}
+	return container.Summary{}, fmt.Errorf("no container found with the `%s` label", name)
+}
+
+// dumpPostgres runs `pg_dump` inside the `app-db` service and writes the dump to the specified path.
+func dumpPostgres(rt Runtime, yaml string, path string) error {
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return rt.Exec(yaml, "app-db", []string{"sh", "-c", pgDumpCmd}, ExecStreams{Stdout: out, Stderr: os.Stderr})
+}
+
+// copyNeo4jData copies the contents of the Neo4j data volume out of the `graph-db` container as a tar archive.
+func copyNeo4jData(rt Runtime, path string) error {
+	neo4j, err := findContainerByName(rt, "bhce_neo4j")
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return rt.CopyFromContainer(context.Background(), neo4j.ID, neo4jDataPath, out)
+}
+
+// writeBackupConfig writes the current configuration as indented JSON to the specified path. Unlike the JSON config
+// file, it includes the secrets held by a secret store, so the backup can be restored without the store.
+func writeBackupConfig(path string) error {
+	data, err := json.MarshalIndent(currentSettings(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// writeBackupManifest writes the manifest as indented JSON to the specified path.
+func writeBackupManifest(manifest BackupManifest, path string) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// FileChecksum returns the hex-encoded SHA-256 checksum of the file at the specified path.
+func FileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// CopyFile copies the file at "src" to "dst" and gives the copy private permissions.
+func CopyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// WriteTarGz writes a gzip-compressed tar archive to "path" containing the named files from the "dir" directory.
+// Files are stored at the root of the archive in sorted order.
+func WriteTarGz(path string, dir string, names []string) error {
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	sorted := append([]string{}, names...)
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		if err := addFileToTar(tw, filepath.Join(dir, name), name); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// addFileToTar writes the file at "path" into the tar archive under the specified name.
+func addFileToTar(tw *tar.Writer, path string, name string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, file)
+	return err
+}
+
+// RunRestore rebuilds the BloodHound deployment described by the specified Docker Compose YAML file from a backup
+// archive created by RunBackup. The archive's checksums are verified first, and the restore refuses to continue if the
+// image versions recorded in the manifest do not match the local images unless "force" is true. The versions are
+// compared without changing the deployment, and the user must confirm before anything is changed. ErrCancelled is
+// returned if they decline.
+func RunRestore(rt Runtime, yaml string, archive string, force bool) error {
+	return restoreBackup(rt, yaml, archive, force, true)
+}
+
+// restoreBackup restores the backup archive like RunRestore, but only asks the user for confirmation if "confirm" is
+// true.
+func restoreBackup(rt Runtime, yaml string, archive string, force bool, confirm bool) error {
+	stagingDir, err := os.MkdirTemp("", "bloodhound-restore-")
+	if err != nil {
+		return fmt.Errorf("failed to create a temporary directory for the restore: %w", err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	fmt.Printf("[+] Extracting and verifying %s...\n", archive)
+	extractErr := ExtractTarGz(archive, stagingDir)
+	if extractErr != nil {
+		return fmt.Errorf("error trying to extract the backup archive: %w", extractErr)
+	}
+	manifest, manifestErr := ReadBackupManifest(stagingDir)
+	if manifestErr != nil {
+		return fmt.Errorf("error trying to read the backup manifest: %w", manifestErr)
+	}
+	verifyErr := VerifyBackupChecksums(stagingDir, manifest)
+	if verifyErr != nil {
+		return fmt.Errorf("the backup archive failed verification: %w", verifyErr)
+	}
+	fmt.Printf("[+] Backup created at %s with BloodHound CLI %s\n", manifest.CreatedAt, manifest.CliVersion)
+
+	// Compare the versions with the archived YAML file if there is no YAML file at the expected location yet
+	composeYaml := yaml
+	if !FileExists(yaml) {
+		composeYaml = filepath.Join(stagingDir, backupYamlFile)
+	}
+	localImages, err := localBackupImages(rt, composeYaml, manifest.Images)
+	if err != nil {
+		return err
+	}
+	mismatches := CompareBackupImages(manifest.Images, localImages)
+	if len(mismatches) > 0 {
+		for _, mismatch := range mismatches {
+			fmt.Printf("[!] %s\n", mismatch)
+		}
+		if !force {
+			return errors.New("the image versions in the backup do not match the local images; use `--force` to restore anyway")
+		}
+		fmt.Println("[!] Continuing with mismatched image versions because `--force` was provided")
+	}
+
+	if confirm {
+		c := AskForConfirmation("[!] This command deletes the current BloodHound volume data and replaces it with the backup. Are you sure you want to continue?")
+		if !c {
+			return ErrCancelled
+		}
+	}
+
+	if composeYaml != yaml {
+		fmt.Printf("[+] No YAML file found at %s, so restoring the YAML file from the backup...\n", yaml)
+		yamlErr := CopyFile(composeYaml, yaml)
+		if yamlErr != nil {
+			return fmt.Errorf("error trying to restore the YAML file: %w", yamlErr)
+		}
+	}
+
+	fmt.Println("[+] Restoring the JSON config file...")
+	configErr := RestoreConfig(filepath.Join(stagingDir, backupConfigFile))
+	if configErr != nil {
+		return fmt.Errorf("error trying to restore the JSON config file: %w", configErr)
+	}
+
+	fmt.Println("[+] Recreating the BloodHound containers and volumes...")
+	if err := RunDockerComposeDown(rt, yaml, true); err != nil {
+		return err
+	}
+	createErr := rt.Create(yaml)
+	if createErr != nil {
+		return fmt.Errorf("error trying to create the containers with %s: %w", yaml, createErr)
+	}
+
+	fmt.Println("[+] Restoring the Neo4j data volume...")
+	neo4jErr := restoreNeo4jData(rt, filepath.Join(stagingDir, backupNeo4jFile))
+	if neo4jErr != nil {
+		return fmt.Errorf("error trying to restore the Neo4j data volume: %w", neo4jErr)
+	}
+
+	fmt.Println("[+] Restoring the Postgres database...")
+	startErr := rt.Up(yaml, "app-db")
+	if startErr != nil {
+		return fmt.Errorf("error trying to start the Postgres service with %s: %w", yaml, startErr)
+	}
+	readyErr := waitForPostgres(rt, yaml, 2*time.Minute)
+	if readyErr != nil {
+		return fmt.Errorf("error waiting for the Postgres service: %w", readyErr)
+	}
+	pgErr := restorePostgres(rt, yaml, filepath.Join(stagingDir, backupPostgresFile))
+	if pgErr != nil {
+		return fmt.Errorf("error trying to restore the Postgres database: %w", pgErr)
+	}
+
+	return RunDockerComposeUp(rt, yaml)
+}
+
+// CompareBackupImages compares the images recorded in a backup manifest against the local images and returns a
+// description of every difference. Image IDs are only compared when both sides have one.
+func CompareBackupImages(backup map[string]BackupImage, local map[string]BackupImage) []string {
+	var mismatches []string
+	names := make([]string, 0, len(backup))
+	for name := range backup {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		want := backup[name]
+		have, ok := local[name]
+		if !ok {
+			mismatches = append(mismatches, fmt.Sprintf("`%s` is in the backup but no local container or image was found", name))
+			continue
+		}
+		if want.Image != have.Image {
+			mismatches = append(mismatches, fmt.Sprintf("`%s` uses %s in the backup but %s locally", name, want.Image, have.Image))
+		} else if want.ImageID != "" && have.ImageID != "" && want.ImageID != have.ImageID {
+			mismatches = append(mismatches, fmt.Sprintf("`%s` uses image %s in the backup but %s locally", name, want.ImageID, have.ImageID))
+		}
+	}
+	return mismatches
+}
+
+// ReadBackupManifest reads the manifest from an extracted backup archive in the "dir" directory.
+func ReadBackupManifest(dir string) (BackupManifest, error) {
+	var manifest BackupManifest
+	data, err := os.ReadFile(filepath.Join(dir, backupManifestFile))
+	if err != nil {
+		return manifest, err
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("failed to parse the manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// VerifyBackupChecksums confirms that every file required for a restore is listed in the manifest and matches the
+// recorded SHA-256 checksum.
+func VerifyBackupChecksums(dir string, manifest BackupManifest) error {
+	for _, name := range []string{backupPostgresFile, backupNeo4jFile, backupConfigFile, backupYamlFile} {
+		want, ok := manifest.Checksums[name]
+		if !ok {
+			return fmt.Errorf("the manifest has no checksum for %s", name)
+		}
+		have, err := FileChecksum(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		if want != have {
+			return fmt.Errorf("the checksum for %s does not match the manifest", name)
+		}
+	}
+	return nil
+}
+
+// ExtractTarGz extracts the files stored at the root of a gzip-compressed tar archive into the "dir" directory.
+// Entries that are not regular files or that would be written outside the directory cause an error.
+func ExtractTarGz(path string, dir string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("failed to read the archive: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read the archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg || filepath.Base(header.Name) != header.Name {
+			return fmt.Errorf("unexpected entry in the archive: %s", header.Name)
+		}
+		out, err := os.OpenFile(filepath.Join(dir, header.Name), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, tr); err != nil {
+			out.Close()
+			return err
+		}
+		if err := out.Close(); err != nil {
+			return err
+		}
+	}
+}
+
+// restoreNeo4jData copies an archived Neo4j data volume into the `graph-db` container.
+func restoreNeo4jData(rt Runtime, path string) error {
+	neo4j, err := findContainerByName(rt, "bhce_neo4j")
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	// The archive's entries are rooted at the `data` directory, so copy them into the container's root
+	return rt.CopyToContainer(context.Background(), neo4j.ID, filepath.Dir(neo4jDataPath), in)
+}
+
+// restorePostgres runs `pg_restore` inside the `app-db` service with the dump at the specified path.
+func restorePostgres(rt Runtime, yaml string, path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return rt.Exec(yaml, "app-db", []string{"sh", "-c", pgRestoreCmd}, ExecStreams{Stdin: in, Stderr: os.Stderr})
+}
+
+// waitForPostgres polls `pg_isready` inside the `app-db` service until Postgres accepts connections or the timeout
+// expires.
+func waitForPostgres(rt Runtime, yaml string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := rt.Exec(yaml, "app-db", []string{"sh", "-c", pgReadyCmd}, ExecStreams{})
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("postgres was not ready after %s", timeout)
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
@@ -0,0 +1,227 @@
+package internal
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SpecterOps/BloodHound_CLI/cmd/internal/runtimetest"
+	"github.com/moby/moby/api/types/image"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileChecksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checksum.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("bloodhound"), 0600))
+
+	sum, err := FileChecksum(path)
+	assert.NoError(t, err, "Expected `FileChecksum()` to return no error")
+	assert.Equal(t, "2b8b3644ac7e9a7db891504e3d4bdb9bf1664317add2647d8ab5315960828302", sum, "Expected `FileChecksum()` to return the SHA-256 checksum")
+
+	_, err = FileChecksum(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err, "Expected `FileChecksum()` to return an error for a missing file")
+}
+
+func TestCopyFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.json")
+	dst := filepath.Join(dir, "dst.json")
+	assert.NoError(t, os.WriteFile(src, []byte(`{"version": 1}`), 0644))
+
+	assert.NoError(t, CopyFile(src, dst), "Expected `CopyFile()` to return no error")
+	content, err := os.ReadFile(dst)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"version": 1}`, string(content), "Expected the copy to match the source file")
+
+	info, err := os.Stat(dst)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "Expected the copy to have private permissions")
+}
+
+func TestWriteTarGz(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "b.txt"), []byte("second"), 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("first"), 0600))
+
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+	assert.NoError(t, WriteTarGz(archive, dir, []string{"b.txt", "a.txt"}), "Expected `WriteTarGz()` to return no error")
+
+	file, err := os.Open(archive)
+	assert.NoError(t, err)
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	assert.NoError(t, err)
+	tr := tar.NewReader(gz)
+
+	contents := map[string]string{}
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		assert.NoError(t, err)
+		names = append(names, header.Name)
+		contents[header.Name] = string(data)
+	}
+	assert.Equal(t, []string{"a.txt", "b.txt"}, names, "Expected the archive entries to be sorted")
+	assert.Equal(t, "first", contents["a.txt"])
+	assert.Equal(t, "second", contents["b.txt"])
+}
+
+func TestExtractTarGz(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(`{}`), 0600))
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+	assert.NoError(t, WriteTarGz(archive, dir, []string{"manifest.json"}))
+
+	out := t.TempDir()
+	assert.NoError(t, ExtractTarGz(archive, out), "Expected `ExtractTarGz()` to return no error")
+	assert.True(t, FileExists(filepath.Join(out, "manifest.json")), "Expected the extracted file to exist")
+
+	// Archives with nested or relative paths are rejected
+	evil := filepath.Join(t.TempDir(), "evil.tar.gz")
+	file, err := os.Create(evil)
+	assert.NoError(t, err)
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Name: "../evil.txt", Mode: 0600, Size: 4, Typeflag: tar.TypeReg}))
+	_, err = tw.Write([]byte("evil"))
+	assert.NoError(t, err)
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, gz.Close())
+	assert.NoError(t, file.Close())
+	assert.Error(t, ExtractTarGz(evil, t.TempDir()), "Expected `ExtractTarGz()` to reject paths outside the directory")
+}
+
+func TestVerifyBackupChecksums(t *testing.T) {
+	dir := t.TempDir()
+	manifest := BackupManifest{Checksums: map[string]string{}}
+	for _, name := range []string{backupPostgresFile, backupNeo4jFile, backupConfigFile, backupYamlFile} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(name), 0600))
+		sum, err := FileChecksum(filepath.Join(dir, name))
+		assert.NoError(t, err)
+		manifest.Checksums[name] = sum
+	}
+	assert.NoError(t, writeBackupManifest(manifest, filepath.Join(dir, backupManifestFile)))
+
+	read, err := ReadBackupManifest(dir)
+	assert.NoError(t, err, "Expected `ReadBackupManifest()` to return no error")
+	assert.NoError(t, VerifyBackupChecksums(dir, read), "Expected `VerifyBackupChecksums()` to pass for untouched files")
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, backupConfigFile), []byte("tampered"), 0600))
+	assert.Error(t, VerifyBackupChecksums(dir, read), "Expected `VerifyBackupChecksums()` to fail for a modified file")
+}
+
+func TestCompareBackupImages(t *testing.T) {
+	backup := map[string]BackupImage{
+		"bhce_bloodhound": {Image: "specterops/bloodhound:v8.0.0", ImageID: "sha256:aaa"},
+		"bhce_neo4j":      {Image: "neo4j:4.4", ImageID: "sha256:bbb"},
+		"bhce_postgres":   {Image: "postgres:16", ImageID: "sha256:ccc"},
+	}
+	assert.Empty(t, CompareBackupImages(backup, backup), "Expected identical images to match")
+
+	local := map[string]BackupImage{
+		"bhce_bloodhound": {Image: "specterops/bloodhound:v8.1.0", ImageID: "sha256:ddd"},
+		"bhce_neo4j":      {Image: "neo4j:4.4", ImageID: "sha256:eee"},
+	}
+	mismatches := CompareBackupImages(backup, local)
+	assert.Len(t, mismatches, 3, "Expected a tag change, an ID change, and a missing container")
+}
+
+// writeTestBackup creates a backup archive of the fake stack with the YAML file in the config directory and returns the
+// archive's path.
+func writeTestBackup(t *testing.T, rt *runtimetest.FakeRuntime) string {
+	rt.ExecOutput["app-db"] = "postgres dump"
+	rt.Archives["bhce_neo4j-id"] = []byte("neo4j data")
+	yaml, err := GetYamlFilePath("")
+	assert.NoError(t, err)
+	archive, err := RunBackup(rt, yaml, t.TempDir())
+	assert.NoError(t, err, "`RunBackup()` should back up the fake stack")
+	assert.Equal(t, []string{
+		"stop bloodhound graph-db",
+		"exec -T app-db sh -c " + pgDumpCmd,
+		"start graph-db bloodhound",
+	}, rt.Commands)
+	rt.Commands = nil
+	return archive
+}
+
+func TestRunRestore(t *testing.T) {
+	setTestConfigDirs(t)
+	keepBloodHoundEnv(t)
+	rt := newFakeStack()
+	archive := writeTestBackup(t, rt)
+	yaml, _ := GetYamlFilePath("")
+	rt.Archives["bhce_neo4j-id"] = []byte("newer data")
+
+	answerPrompts(t, "y\n")
+	assert.NoError(t, RunRestore(rt, yaml, archive, false), "`RunRestore()` should restore a backup of the same images")
+	assert.Equal(t, []string{
+		"down --volumes",
+		"create",
+		"up -d app-db",
+		"exec -T app-db sh -c " + pgReadyCmd,
+		"exec -T app-db sh -c " + pgRestoreCmd,
+		"up -d",
+	}, rt.Commands)
+	assert.Equal(t, "neo4j data", string(rt.Archives["bhce_neo4j-id"]), "The Neo4j data should be copied back into the container")
+
+	rt.Commands = nil
+	rt.Containers[0].Image = "bhce_bloodhound:v9.9.9"
+	assert.Error(t, RunRestore(rt, yaml, archive, false), "Mismatched images should stop the restore")
+	assert.Empty(t, rt.Commands)
+
+	// An upgrade rollback restores its snapshot while the containers still run the failed images, without a prompt
+	assert.NoError(t, restoreSnapshot(rt, yaml, archive))
+	assert.Contains(t, rt.Commands, "down --volumes")
+}
+
+func TestRunRestoreDeclined(t *testing.T) {
+	setTestConfigDirs(t)
+	rt := newFakeStack()
+	archive := writeTestBackup(t, rt)
+	yaml, _ := GetYamlFilePath("")
+
+	answerPrompts(t, "n\n")
+	assert.ErrorIs(t, RunRestore(rt, yaml, archive, false), ErrCancelled)
+	assert.Empty(t, rt.Commands, "Nothing should run before the restore is confirmed")
+
+	// Without containers, the images are pulled to compare the versions, but the YAML file is not restored yet
+	rt.Containers = nil
+	for _, name := range prodImages {
+		rt.Images[name+":latest"] = image.InspectResponse{ID: "sha256:" + name}
+	}
+	assert.NoError(t, os.Remove(yaml))
+	answerPrompts(t, "n\n")
+	assert.ErrorIs(t, RunRestore(rt, yaml, archive, false), ErrCancelled)
+	assert.Equal(t, []string{"pull"}, rt.Commands)
+	assert.False(t, FileExists(yaml), "The archived YAML file should only be restored after the restore is confirmed")
+}
+
+func TestRunBackupIncludesStoredSecrets(t *testing.T) {
+	setTestConfigDirs(t)
+	store := useTestFileStore(t)
+	keepBloodHoundEnv(t)
+	_, inFile := getNestedValue(readStoredSettings(), "default_admin.password")
+	assert.False(t, inFile, "The JSON config file should not have the stored secret")
+
+	archive := writeTestBackup(t, newFakeStack())
+	dir := t.TempDir()
+	assert.NoError(t, ExtractTarGz(archive, dir))
+	content, err := os.ReadFile(filepath.Join(dir, backupConfigFile))
+	assert.NoError(t, err)
+	settings := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(content, &settings))
+	password, _ := getNestedValue(settings, "default_admin.password")
+	assert.Equal(t, "hunter2", password, "The archived configuration should have the secret from the store")
+	stored, err := store.Get("default_admin.password")
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", stored)
+}
@@ -0,0 +1,220 @@
+package internal
+
+// Functions for writing and checking the Docker Compose YAML files
+// The files are written from the copies built into the binary, or downloaded from the release tag that matches the
+// CLI's version with `check --refresh`, and must match the SHA-256 checksums in the manifest embedded in the binary, so
+// a binary always installs the YAML files it was released with
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/SpecterOps/BloodHound_CLI/cmd/config"
+)
+
+// composeManifest holds the SHA-256 checksums of the YAML files in the `sha256sum` format
+// Update it with `sha256sum docker-compose.yml docker-compose.dev.yml > cmd/internal/compose.sha256` whenever the
+// YAML files change
+//
+//go:embed compose.sha256
+var composeManifest []byte
+
+// Vars for downloading the Docker Compose YAML files
+var (
+	// Base URL of the BloodHound CLI repository's files at a tag, which tests point at a local server
+	composeBaseUrl = "https://raw.githubusercontent.com/SpecterOps/BloodHound_CLI/refs/tags"
+	// Statuses of a YAML file compared with the manifest
+	ComposeCurrent  = "current"
+	ComposeModified = "modified"
+	ComposeMissing  = "missing"
+	// Sources of the YAML files, recorded in the `compose_source` config value
+	composeSourceEmbedded   = "embedded"
+	composeSourceDownloaded = "downloaded"
+	// Override file merged with the YAML file to stop publishing the Neo4j ports when `neo4j.publish_ports` is false
+	neo4jPortsOverrideYaml = "docker-compose.neo4j-ports.yml"
+	neo4jPortsOverride     = `# Written by BloodHound CLI because the neo4j.publish_ports config value is false
+services:
+  graph-db:
+    ports: !reset []
+`
+	// Oldest Docker Compose release that understands the "!reset" tag in the override files
+	minOverrideComposeVersion = "v2.24.0"
+	// Version in the output of "docker compose version", which "podman compose" also prints when it runs Docker Compose
+	composeVersionPattern = regexp.MustCompile(`(?i)docker compose version v?(\d+\.\d+\.\d+)`)
+)
+
+// ComposeFile describes a Docker Compose YAML file in the config directory compared with the manifest.
+type ComposeFile struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	// Checksum in the manifest for this version of the CLI
+	Expected string `json:"expected"`
+	// Checksum of the file in the config directory, if it exists
+	Actual string `json:"actual,omitempty"`
+}
+
+// composeFileUrl returns the URL of the YAML file at the release tag that matches the CLI's version.
+func composeFileUrl(name string) string {
+	return fmt.Sprintf("%s/%s/%s", composeBaseUrl, config.Version, name)
+}
+
+// expectedComposeChecksum returns the checksum of the YAML file in the manifest.
+func expectedComposeChecksum(name string) (string, error) {
+	sum, ok := parseChecksum(composeManifest, name, false)
+	if !ok {
+		return "", fmt.Errorf("the checksum manifest has no entry for %s", name)
+	}
+	return sum, nil
+}
+
+// CheckComposeFiles compares the production and development YAML files in the config directory with the checksums
+// in the manifest for this version of the CLI.
+func CheckComposeFiles() ([]ComposeFile, error) {
+	var files []ComposeFile
+	for _, name := range []string{prodYaml, devYaml} {
+		expected, err := expectedComposeChecksum(name)
+		if err != nil {
+			return nil, err
+		}
+		file := ComposeFile{Name: name, Path: filepath.Join(GetBloodHoundDir(), name), Status: ComposeCurrent, Expected: expected}
+		content, err := os.ReadFile(file.Path)
+		switch {
+		case os.IsNotExist(err):
+			file.Status = ComposeMissing
+		case err != nil:
+			return nil, fmt.Errorf("failed to read %s: %w", file.Path, err)
+		default:
+			file.Actual = checksumString(content)
+			if file.Actual != expected {
+				file.Status = ComposeModified
+			}
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// WriteDockerComposeFiles writes the production and development Docker Compose YAML files for this version of the CLI
+// into the BloodHound directory from the copies built into the binary, or downloads them from the release if "refresh"
+// is true. Files that already match the version are skipped, and the user is asked before a modified file is
+// overwritten. The source of the files is saved as the `compose_source` config value. Returns an error if a file
+// cannot be written or does not match the checksum manifest.
+func WriteDockerComposeFiles(refresh bool) error {
+	files, err := CheckComposeFiles()
+	if err != nil {
+		return err
+	}
+	source := ""
+	for _, file := range files {
+		switch file.Status {
+		case ComposeCurrent:
+			continue
+		case ComposeModified:
+			c := AskForConfirmation(fmt.Sprintf("[*] The %s file differs from the one for BloodHound CLI %s. Do you want to overwrite it?", file.Name, config.Version))
+			if !c {
+				continue
+			}
+		}
+		if refresh {
+			if err := downloadComposeFile(file.Name, file.Path); err != nil {
+				return fmt.Errorf("error trying to download the %s file: %w", file.Name, err)
+			}
+			source = composeSourceDownloaded
+			continue
+		}
+		if err := writeEmbeddedComposeFile(file.Name, file.Path); err != nil {
+			return fmt.Errorf("error trying to write the %s file: %w", file.Name, err)
+		}
+		source = composeSourceEmbedded
+	}
+	if source == "" {
+		return nil
+	}
+	bhEnv.Set("compose_source", source)
+	return WriteBloodHoundEnvironmentVariables()
+}
+
+// writeEmbeddedComposeFile writes the copy of the YAML file built into the binary to the path after checking it
+// against the manifest.
+func writeEmbeddedComposeFile(name string, path string) error {
+	expected, err := expectedComposeChecksum(name)
+	if err != nil {
+		return err
+	}
+	if config.ComposeFiles == nil {
+		return fmt.Errorf("this build of BloodHound CLI has no built-in YAML files; run `bloodhound-cli check --refresh` to download them")
+	}
+	content, err := fs.ReadFile(config.ComposeFiles, name)
+	if err != nil {
+		return fmt.Errorf("failed to read the built-in copy of %s: %w", name, err)
+	}
+	if actual := checksumString(content); actual != expected {
+		return fmt.Errorf("%w: the SHA-256 checksum of the built-in %s is %s, but the manifest lists %s", ErrVerificationFailed, name, actual, expected)
+	}
+	fmt.Printf("[+] Writing %s from the copy built into BloodHound CLI %s...\n", name, config.Version)
+	return writeFileAtomic(path, content, 0644)
+}
+
+// downloadComposeFile downloads the YAML file from the release tag that matches the CLI's version, checks it against
+// the manifest, and writes it to the path. The file at the path is left alone unless the download is verified.
+// Returns an error wrapping ErrVerificationFailed if the download does not match the manifest.
+func downloadComposeFile(name string, path string) error {
+	expected, err := expectedComposeChecksum(name)
+	if err != nil {
+		return err
+	}
+	url := composeFileUrl(name)
+	fmt.Printf("[+] Downloading %s from %s...\n", name, url)
+	content, err := downloadBytes(url)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	if actual := checksumString(content); actual != expected {
+		return fmt.Errorf("%w: the SHA-256 checksum of the downloaded %s is %s, but this version of the CLI expects %s", ErrVerificationFailed, name, actual, expected)
+	}
+	return writeFileAtomic(path, content, 0644)
+}
+
+// writeComposeOverrides writes the override files Compose must merge with the YAML file for the current configuration
+// to the config directory and returns their paths. Override files that no longer apply are removed.
+func writeComposeOverrides() ([]string, error) {
+	path := filepath.Join(GetBloodHoundDir(), neo4jPortsOverrideYaml)
+	if bhEnv.GetBool("neo4j.publish_ports") {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		return nil, nil
+	}
+	if err := writeFileAtomic(path, []byte(neo4jPortsOverride), configFileMode()); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return []string{path}, nil
+}
+
+// checkOverrideSupport returns an error wrapping ErrInvalidConfig unless the output of the Compose provider's "version"
+// command shows Docker Compose 2.24.0 or later, which the override files need. Older releases and podman-compose
+// reject the "!reset" tag.
+func checkOverrideSupport(versionOutput string) error {
+	match := composeVersionPattern.FindStringSubmatch(versionOutput)
+	if match != nil && compareReleases("v"+match[1], minOverrideComposeVersion) >= 0 {
+		return nil
+	}
+	found := "a Compose provider other than Docker Compose"
+	if match != nil {
+		found = "Docker Compose v" + match[1]
+	}
+	return fmt.Errorf("%w: setting `neo4j.publish_ports` to false requires Docker Compose %s or later, but found %s; upgrade Docker Compose or run `bloodhound-cli config set neo4j.publish_ports true`", ErrInvalidConfig, minOverrideComposeVersion, found)
+}
+
+// checksumString returns the hex-encoded SHA-256 checksum of the content.
+func checksumString(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,170 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SpecterOps/BloodHound_CLI/cmd/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubComposeFiles points the YAML file downloads at a local server that serves the files for the CLI's version until
+// the test finishes.
+func stubComposeFiles(t *testing.T, files map[string]string) {
+	handlers := map[string]string{}
+	for name, content := range files {
+		handlers["/raw/"+config.Version+"/"+name] = content
+	}
+	stubReleaseApis(t, handlers)
+	base := composeBaseUrl
+	composeBaseUrl = githubApiUrl + "/raw"
+	t.Cleanup(func() { composeBaseUrl = base })
+}
+
+// useRepoComposeFiles uses the YAML files at the root of the repository as the files built into the binary until the
+// test finishes.
+func useRepoComposeFiles(t *testing.T) {
+	original := config.ComposeFiles
+	config.ComposeFiles = os.DirFS(filepath.Join("..", ".."))
+	t.Cleanup(func() { config.ComposeFiles = original })
+}
+
+// readRepoYaml returns the YAML file at the root of the repository.
+func readRepoYaml(t *testing.T, name string) string {
+	content, err := os.ReadFile(filepath.Join("..", "..", name))
+	assert.NoError(t, err)
+	return string(content)
+}
+
+func TestComposeManifestMatchesYamlFiles(t *testing.T) {
+	for _, name := range []string{prodYaml, devYaml} {
+		expected, err := expectedComposeChecksum(name)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, checksumString([]byte(readRepoYaml(t, name))), "Update compose.sha256 after changing %s", name)
+	}
+}
+
+func TestCheckComposeFiles(t *testing.T) {
+	dir := setTestConfigDirs(t)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, devYaml), []byte(readRepoYaml(t, devYaml)), 0644))
+
+	files, err := CheckComposeFiles()
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+	assert.Equal(t, ComposeModified, files[0].Status, "A YAML file edited by hand should be reported")
+	assert.Equal(t, ComposeCurrent, files[1].Status)
+
+	assert.NoError(t, os.Remove(filepath.Join(dir, devYaml)))
+	files, err = CheckComposeFiles()
+	assert.NoError(t, err)
+	assert.Equal(t, ComposeMissing, files[1].Status)
+	assert.Empty(t, files[1].Actual)
+}
+
+func TestDownloadComposeFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, prodYaml)
+	stubComposeFiles(t, map[string]string{prodYaml: readRepoYaml(t, prodYaml), devYaml: "services: {}\n"})
+
+	assert.NoError(t, downloadComposeFile(prodYaml, path), "A download matching the manifest should be written")
+	content, _ := os.ReadFile(path)
+	assert.Equal(t, readRepoYaml(t, prodYaml), string(content))
+
+	path = filepath.Join(dir, devYaml)
+	assert.NoError(t, os.WriteFile(path, []byte("existing"), 0644))
+	err := downloadComposeFile(devYaml, path)
+	assert.ErrorIs(t, err, ErrVerificationFailed, "A download that does not match the manifest should be rejected")
+	content, _ = os.ReadFile(path)
+	assert.Equal(t, "existing", string(content), "The existing file should be left alone")
+
+	entries, _ := os.ReadDir(dir)
+	assert.Len(t, entries, 2, "No temporary files should be left behind")
+}
+
+func TestDownloadFileKeepsFileOnFailure(t *testing.T) {
+	stubReleaseApis(t, map[string]string{"/file.yml": "new"})
+	path := filepath.Join(t.TempDir(), "file.yml")
+	assert.NoError(t, os.WriteFile(path, []byte("existing"), 0644))
+
+	assert.Error(t, DownloadFile(githubApiUrl+"/missing.yml", path))
+	content, _ := os.ReadFile(path)
+	assert.Equal(t, "existing", string(content), "A failed download should not truncate the file")
+
+	assert.NoError(t, DownloadFile(githubApiUrl+"/file.yml", path))
+	content, _ = os.ReadFile(path)
+	assert.Equal(t, "new", string(content))
+}
+
+func TestWriteDockerComposeFiles(t *testing.T) {
+	dir := setTestConfigDirs(t)
+	setTestConfig(t, map[string]string{"compose_source": ""})
+	useRepoComposeFiles(t)
+	assert.NoError(t, os.Remove(filepath.Join(dir, prodYaml)))
+	// Downloads would fail, so the files must come from the built-in copies
+	stubComposeFiles(t, map[string]string{})
+
+	assert.NoError(t, WriteDockerComposeFiles(false), "Missing YAML files should be written without network access")
+	files, err := CheckComposeFiles()
+	assert.NoError(t, err)
+	for _, file := range files {
+		assert.Equal(t, ComposeCurrent, file.Status)
+	}
+	assert.Equal(t, composeSourceEmbedded, bhEnv.GetString("compose_source"), "The source should be recorded")
+
+	assert.NoError(t, os.Remove(filepath.Join(dir, devYaml)))
+	assert.Error(t, WriteDockerComposeFiles(true), "`--refresh` should only use the network")
+
+	stubComposeFiles(t, map[string]string{devYaml: readRepoYaml(t, devYaml)})
+	assert.NoError(t, WriteDockerComposeFiles(true))
+	assert.Equal(t, composeSourceDownloaded, bhEnv.GetString("compose_source"))
+	assert.Equal(t, composeSourceDownloaded, readStoredSettings()["compose_source"], "The source should be saved to the JSON config file")
+}
+
+func TestWriteComposeOverrides(t *testing.T) {
+	dir := setTestConfigDirs(t)
+	setTestConfig(t, map[string]string{"neo4j.publish_ports": "false"})
+
+	overrides, err := writeComposeOverrides()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, neo4jPortsOverrideYaml)}, overrides, "Unpublished Neo4j ports need an override file")
+	content, _ := os.ReadFile(overrides[0])
+	assert.Contains(t, string(content), "ports: !reset []")
+
+	bhEnv.Set("neo4j.publish_ports", true)
+	overrides, err = writeComposeOverrides()
+	assert.NoError(t, err)
+	assert.Empty(t, overrides)
+	assert.False(t, FileExists(filepath.Join(dir, neo4jPortsOverrideYaml)), "The override file should be removed once it no longer applies")
+}
+
+func TestCheckOverrideSupport(t *testing.T) {
+	supported := []string{
+		"Docker Compose version v2.24.0",
+		"Docker Compose version 2.29.7",
+		">>>> Executing external compose provider \"/usr/libexec/docker/cli-plugins/docker-compose\" <<<<\nDocker Compose version v2.31.0-desktop.2",
+	}
+	for _, output := range supported {
+		assert.NoError(t, checkOverrideSupport(output), "`%s` should support the override files", output)
+	}
+
+	unsupported := []string{
+		"Docker Compose version v2.23.3",
+		"podman-compose version: 1.0.6\npodman version 4.9.3",
+		"",
+	}
+	for _, output := range unsupported {
+		err := checkOverrideSupport(output)
+		assert.ErrorIs(t, err, ErrInvalidConfig, "`%s` should not support the override files", output)
+		assert.ErrorContains(t, err, "neo4j.publish_ports true")
+	}
+}
+
+func TestWriteEmbeddedComposeFileWithoutFiles(t *testing.T) {
+	original := config.ComposeFiles
+	config.ComposeFiles = nil
+	t.Cleanup(func() { config.ComposeFiles = original })
+
+	err := writeEmbeddedComposeFile(prodYaml, filepath.Join(t.TempDir(), prodYaml))
+	assert.ErrorContains(t, err, "--refresh", "A build without the YAML files should point to `check --refresh`")
+}
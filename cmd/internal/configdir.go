@@ -0,0 +1,310 @@
+package internal
+
+// Functions for moving the config directory to a new location
+// The JSON config file in the default directory is replaced with a pointer to the new directory, which
+// ParseBloodHoundEnvironmentVariables follows on every run
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Vars for moving the config directory
+var (
+	// Files in the config directory that are moved, if they exist
+	movedConfigFiles = []string{"bloodhound.config.json", "bloodhound.config.json.bak", secretFileName, prodYaml, devYaml, upgradeHistoryFile}
+	// Backups made before migrating the JSON config file
+	configBackupPattern = "bloodhound.config.json.v*.bak"
+	// Config keys with paths to TLS files, which are moved if they are inside the config directory
+	tlsConfigKeys = []string{"tls.cert_file", "tls.key_file"}
+	// defaultConfigDir returns the directory that holds the JSON config file or the pointer to the moved directory
+	defaultConfigDir = GetDefaultConfigDir
+	// Characters Compose removes from a directory name to make a project name
+	projectNameChars = regexp.MustCompile(`[^a-z0-9_-]+`)
+)
+
+// ConfigDirMove describes a completed move of the config directory.
+type ConfigDirMove struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Compose project name that keeps the existing containers and volumes
+	Project string `json:"project"`
+	// Files copied to the new directory, relative to the directories
+	Files []string `json:"files"`
+	// Whether the old directory was deleted; it is kept if it has files that were not moved
+	Removed bool `json:"removed"`
+}
+
+// MoveConfigDir copies the JSON config file, the secrets file, the Docker YAML files, and any TLS files inside the
+// config directory to the target directory, verifies their checksums, and points the default config directory at the
+// target. Compose names the project after the YAML file's directory, so the current project name is recorded as the
+// `compose_project` config value to keep using the existing volumes. The target must not exist or be empty. If
+// "removeOld" is true, the moved files are deleted from the old directory, along with the directory itself if nothing
+// else is left in it. Returns an error wrapping ErrInvalidConfig if the target cannot be used.
+func MoveConfigDir(target string, removeOld bool) (ConfigDirMove, error) {
+	source := filepath.Clean(GetBloodHoundDir())
+	target, err := filepath.Abs(target)
+	if err != nil {
+		return ConfigDirMove{}, fmt.Errorf("failed to resolve the path %s: %w", target, err)
+	}
+	move := ConfigDirMove{From: source, To: target}
+	if isSubPath(source, target) || isSubPath(target, source) {
+		return move, fmt.Errorf("%w: the new config directory, %s, cannot be the current directory or contain it", ErrInvalidConfig, target)
+	}
+	if err := prepareConfigDir(target); err != nil {
+		return move, err
+	}
+
+	files, tlsPaths, err := configDirFiles(source)
+	if err != nil {
+		return move, err
+	}
+	for _, name := range files {
+		if err := copyConfigFile(filepath.Join(source, name), filepath.Join(target, name)); err != nil {
+			os.RemoveAll(target)
+			return move, fmt.Errorf("failed to copy %s to the new config directory: %w", name, err)
+		}
+	}
+	if err := verifyConfigFiles(source, target, files); err != nil {
+		os.RemoveAll(target)
+		return move, err
+	}
+	move.Files = files
+
+	// Point the configuration at the new directory and save it there
+	bhEnv.Set("config_directory", target)
+	move.Project = bhEnv.GetString("compose_project")
+	if move.Project == "" {
+		move.Project = os.Getenv(composeProjectVar)
+	}
+	if move.Project == "" {
+		move.Project = composeProjectName(source)
+	}
+	bhEnv.Set("compose_project", move.Project)
+	for key, name := range tlsPaths {
+		bhEnv.Set(key, filepath.Join(target, name))
+	}
+	if err := WriteBloodHoundEnvironmentVariables(); err != nil {
+		return move, err
+	}
+	if err := writeConfigPointer(target); err != nil {
+		return move, err
+	}
+
+	if removeOld {
+		removed, err := removeMovedFiles(source, files)
+		if err != nil {
+			return move, err
+		}
+		move.Removed = removed
+	}
+	return move, nil
+}
+
+// composeProjectName returns the project name Compose derives from a directory: its base name in lowercase without the
+// characters a project name cannot have.
+func composeProjectName(dir string) string {
+	name := projectNameChars.ReplaceAllString(strings.ToLower(filepath.Base(dir)), "")
+	return strings.TrimLeft(name, "_-")
+}
+
+// isSubPath reports whether "path" is "parent" or inside it.
+func isSubPath(parent string, path string) bool {
+	rel, err := filepath.Rel(parent, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// prepareConfigDir creates the target directory with the mode for the `permissions_mode` config value. Returns an
+// error wrapping ErrInvalidConfig if the target is a file or a directory that is not empty.
+func prepareConfigDir(target string) error {
+	info, err := os.Stat(target)
+	switch {
+	case os.IsNotExist(err):
+		if err := os.MkdirAll(target, configDirMode()); err != nil {
+			return fmt.Errorf("failed to create the new config directory: %w", err)
+		}
+	case err != nil:
+		return fmt.Errorf("failed to check the new config directory: %w", err)
+	case !info.IsDir():
+		return fmt.Errorf("%w: %s is a file, not a directory", ErrInvalidConfig, target)
+	default:
+		entries, err := os.ReadDir(target)
+		if err != nil {
+			return fmt.Errorf("failed to check the new config directory: %w", err)
+		}
+		if len(entries) > 0 {
+			return fmt.Errorf("%w: the new config directory, %s, must be empty", ErrInvalidConfig, target)
+		}
+	}
+	// MkdirAll applies the umask, so set the mode explicitly
+	if err := os.Chmod(target, configDirMode()); err != nil {
+		return fmt.Errorf("failed to set the permissions on the new config directory: %w", err)
+	}
+	return nil
+}
+
+// configDirFiles returns the files to move from the config directory, relative to it, and the TLS config keys with
+// absolute paths inside it mapped to their relative paths, so the keys can be pointed at the new directory.
+func configDirFiles(source string) ([]string, map[string]string, error) {
+	var files []string
+	for _, name := range movedConfigFiles {
+		if FileExists(filepath.Join(source, name)) {
+			files = append(files, name)
+		}
+	}
+	backups, err := filepath.Glob(filepath.Join(source, configBackupPattern))
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, backup := range backups {
+		files = append(files, filepath.Base(backup))
+	}
+
+	// TLS paths are usually inside the BloodHound container, so only files found inside the config directory are moved
+	tlsPaths := map[string]string{}
+	for _, key := range tlsConfigKeys {
+		value := bhEnv.GetString(key)
+		if value == "" {
+			continue
+		}
+		rel, err := filepath.Rel(source, value)
+		if err != nil || !filepath.IsAbs(value) || !isSubPath(source, value) || !FileExists(value) || Contains(files, rel) {
+			continue
+		}
+		files = append(files, rel)
+		tlsPaths[key] = rel
+	}
+	return files, tlsPaths, nil
+}
+
+// copyConfigFile copies a file into the new config directory with the mode for the `permissions_mode` config value.
+func copyConfigFile(src string, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), configDirMode()); err != nil {
+		return err
+	}
+	if err := CopyFile(src, dst); err != nil {
+		return err
+	}
+	return os.Chmod(dst, configFileMode())
+}
+
+// verifyConfigFiles compares the SHA-256 checksums of the copied files with the originals.
+func verifyConfigFiles(source string, target string, files []string) error {
+	for _, name := range files {
+		want, err := FileChecksum(filepath.Join(source, name))
+		if err != nil {
+			return fmt.Errorf("failed to checksum %s: %w", name, err)
+		}
+		have, err := FileChecksum(filepath.Join(target, name))
+		if err != nil {
+			return fmt.Errorf("failed to checksum the copy of %s: %w", name, err)
+		}
+		if have != want {
+			return fmt.Errorf("the copy of %s in the new config directory does not match the original", name)
+		}
+	}
+	return nil
+}
+
+// writeConfigPointer replaces the JSON config file in the default config directory with one that only points to the
+// config directory.
+func writeConfigPointer(configDir string) error {
+	dir := defaultConfigDir()
+	if err := os.MkdirAll(dir, configDirMode()); err != nil {
+		return fmt.Errorf("failed to create the default config directory: %w", err)
+	}
+	pointer, err := json.MarshalIndent(map[string]interface{}{
+		"config_directory": configDir,
+		"version":          currentConfigVersion,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal configuration to JSON: %w", err)
+	}
+	path := filepath.Join(dir, "bloodhound.config.json")
+	tmp := filepath.Join(dir, ".bloodhound.config.json.tmp")
+	if err := os.WriteFile(tmp, pointer, configFileMode()); err != nil {
+		return fmt.Errorf("failed to write the pointer to the new config directory: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write the pointer to the new config directory: %w", err)
+	}
+	return nil
+}
+
+// isConfigPointer reports whether the JSON config file at the path only points to another config directory.
+func isConfigPointer(path string) bool {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	var settings map[string]interface{}
+	if err := json.Unmarshal(content, &settings); err != nil {
+		return false
+	}
+	for key := range settings {
+		if key != "config_directory" && key != "version" {
+			return false
+		}
+	}
+	_, ok := settings["config_directory"]
+	return ok
+}
+
+// removeMovedFiles deletes the moved files from the old config directory and reports whether the directory itself was
+// deleted. The default config directory keeps the pointer to the new directory, and any other directory is only
+// deleted if it is empty.
+func removeMovedFiles(source string, files []string) (bool, error) {
+	isDefault := filepath.Clean(defaultConfigDir()) == source
+	for _, name := range files {
+		if isDefault && name == "bloodhound.config.json" {
+			continue
+		}
+		if err := os.Remove(filepath.Join(source, name)); err != nil && !os.IsNotExist(err) {
+			return false, fmt.Errorf("failed to remove %s from the old config directory: %w", name, err)
+		}
+		// Remove the TLS file's directories if they are now empty
+		for dir := filepath.Dir(name); dir != "."; dir = filepath.Dir(dir) {
+			if os.Remove(filepath.Join(source, dir)) != nil {
+				break
+			}
+		}
+	}
+	if isDefault {
+		return false, nil
+	}
+	// Remove fails for a directory that still has files, which are left for the user
+	return os.Remove(source) == nil, nil
+}
+
+// followConfigPointer reads the JSON config file from the configured config directory if it differs from the
+// directory the file was read from ("readDir"). Returns an error wrapping ErrInvalidConfig if the file read from
+// "readDir" is a pointer to a directory without a JSON config file (e.g., a data disk that is not mounted).
+func followConfigPointer(readDir string) error {
+	configDir := filepath.Clean(GetBloodHoundDir())
+	if configDir == filepath.Clean(readDir) {
+		return nil
+	}
+	configFile := filepath.Join(configDir, "bloodhound.config.json")
+	if !FileExists(configFile) {
+		if isConfigPointer(filepath.Join(readDir, "bloodhound.config.json")) {
+			return fmt.Errorf("%w: the config directory, %s, has no JSON config file; make sure its disk is mounted or run `config move-dir` again", ErrInvalidConfig, configDir)
+		}
+		// Older versions allowed `config_directory` to be edited by hand, so the file in "readDir" is still used
+		return nil
+	}
+	if err := migrateConfigFileWithNotice(configFile); err != nil {
+		return err
+	}
+	bhEnv.SetConfigFile(configFile)
+	if err := bhEnv.ReadInConfig(); err != nil {
+		return fmt.Errorf("%w: error while parsing the JSON config file in %s: %w", ErrInvalidConfig, configDir, err)
+	}
+	// The file's own `config_directory` could be stale if the directory was moved by hand
+	bhEnv.Set("config_directory", configDir)
+	return nil
+}
@@ -0,0 +1,115 @@
+package internal
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setTestConfigDirs creates a config directory with a JSON config file, a YAML file, and a TLS certificate, and uses it
+// as both the config directory and the default config directory until the test finishes.
+func setTestConfigDirs(t *testing.T) string {
+	// Load the configuration first, so setTestConfig restores the loaded values instead of empty ones
+	assert.NoError(t, ParseBloodHoundEnvironmentVariables())
+	source := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(source, "bloodhound.config.json"), []byte("{\"version\": 2}\n"), 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(source, prodYaml), []byte("services: {}\n"), 0600))
+	assert.NoError(t, os.MkdirAll(filepath.Join(source, "tls"), 0700))
+	assert.NoError(t, os.WriteFile(filepath.Join(source, "tls", "cert.pem"), []byte("certificate"), 0600))
+
+	setTestConfig(t, map[string]string{
+		"config_directory": source,
+		"tls.cert_file":    filepath.Join(source, "tls", "cert.pem"),
+		"tls.key_file":     "/etc/bloodhound/key.pem",
+		"compose_project":  "",
+	})
+	original := defaultConfigDir
+	defaultConfigDir = func() string { return source }
+	t.Cleanup(func() { defaultConfigDir = original })
+	return source
+}
+
+func TestMoveConfigDir(t *testing.T) {
+	source := setTestConfigDirs(t)
+	target := filepath.Join(t.TempDir(), "data", "bloodhound")
+
+	move, err := MoveConfigDir(target, true)
+	assert.NoError(t, err, "`MoveConfigDir()` should move the config directory")
+	assert.Equal(t, target, move.To)
+	assert.ElementsMatch(t, []string{"bloodhound.config.json", prodYaml, filepath.Join("tls", "cert.pem")}, move.Files)
+	assert.False(t, move.Removed, "The default config directory should be kept for the pointer")
+
+	assert.Equal(t, target, GetBloodHoundDir(), "`config_directory` should be the new directory")
+	assert.Equal(t, filepath.Join(target, "tls", "cert.pem"), bhEnv.GetString("tls.cert_file"), "TLS paths inside the config directory should move")
+	assert.Equal(t, "/etc/bloodhound/key.pem", bhEnv.GetString("tls.key_file"), "TLS paths inside the container should not change")
+	assert.Equal(t, composeProjectName(source), move.Project, "The project should keep the old directory's name")
+	assert.Equal(t, move.Project, readStoredSettings()["compose_project"], "The project name should be saved to the JSON config file")
+	if _, ok := os.LookupEnv(composeProjectVar); !ok {
+		assert.Contains(t, ComposeEnvironment(), composeProjectVar+"="+move.Project, "Compose should be given the project name")
+	}
+
+	// Moving again keeps the recorded project name
+	again, err := MoveConfigDir(filepath.Join(t.TempDir(), "Other Disk"), false)
+	assert.NoError(t, err)
+	assert.Equal(t, move.Project, again.Project)
+
+	for _, name := range move.Files {
+		assert.True(t, FileExists(filepath.Join(target, name)), "%s should be in the new directory", name)
+	}
+	info, err := os.Stat(target)
+	assert.NoError(t, err)
+	assert.Equal(t, configDirMode(), info.Mode().Perm(), "The new directory should have the mode for `permissions_mode`")
+
+	pointer := filepath.Join(source, "bloodhound.config.json")
+	assert.True(t, isConfigPointer(pointer), "The default JSON config file should point to the new directory")
+	assert.False(t, FileExists(filepath.Join(source, prodYaml)), "Moved files should be removed from the old directory")
+	assert.False(t, DirExists(filepath.Join(source, "tls")), "Empty directories should be removed from the old directory")
+}
+
+func TestComposeProjectName(t *testing.T) {
+	assert.Equal(t, "bloodhound", composeProjectName("/home/user/.config/bloodhound"))
+	assert.Equal(t, "my_bh-data2", composeProjectName("/data/My_BH-Data.2"), "Compose lowercases the name and drops other characters")
+	assert.Equal(t, "bh", composeProjectName("/data/_BH"), "Leading separators should be removed")
+}
+
+func TestMoveConfigDirRefusesTargets(t *testing.T) {
+	source := setTestConfigDirs(t)
+
+	_, err := MoveConfigDir(source, false)
+	assert.True(t, errors.Is(err, ErrInvalidConfig), "`MoveConfigDir()` should refuse the current directory")
+	_, err = MoveConfigDir(filepath.Join(source, "nested"), false)
+	assert.True(t, errors.Is(err, ErrInvalidConfig), "`MoveConfigDir()` should refuse a directory inside the current one")
+
+	occupied := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(occupied, "notes.txt"), []byte("keep"), 0600))
+	_, err = MoveConfigDir(occupied, false)
+	assert.True(t, errors.Is(err, ErrInvalidConfig), "`MoveConfigDir()` should refuse a directory that is not empty")
+	assert.Equal(t, source, GetBloodHoundDir(), "A refused move should not change `config_directory`")
+}
+
+func TestFollowConfigPointer(t *testing.T) {
+	assert.NoError(t, ParseBloodHoundEnvironmentVariables())
+	readDir := t.TempDir()
+	missing := filepath.Join(t.TempDir(), "unmounted")
+	setTestConfig(t, map[string]string{"config_directory": missing})
+
+	assert.NoError(t, writeConfigPointerTo(t, readDir, missing))
+	err := followConfigPointer(readDir)
+	assert.True(t, errors.Is(err, ErrInvalidConfig), "A pointer to a directory without a JSON config file should be an error")
+
+	// A full config file with a hand-edited `config_directory` is still used
+	assert.NoError(t, os.WriteFile(filepath.Join(readDir, "bloodhound.config.json"), []byte("{\"config_directory\": \""+missing+"\", \"log_level\": \"INFO\"}"), 0600))
+	assert.NoError(t, followConfigPointer(readDir))
+
+	assert.NoError(t, followConfigPointer(missing), "Nothing should happen when the file was read from the config directory")
+}
+
+// writeConfigPointerTo writes a pointer to "configDir" in "dir" as if "dir" were the default config directory.
+func writeConfigPointerTo(t *testing.T, dir string, configDir string) error {
+	original := defaultConfigDir
+	defaultConfigDir = func() string { return dir }
+	t.Cleanup(func() { defaultConfigDir = original })
+	return writeConfigPointer(configDir)
+}
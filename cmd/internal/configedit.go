@@ -0,0 +1,204 @@
+package internal
+
+// Functions for editing the JSON config file in the user's editor
+// The edited file is validated against the schema (see schema.go) before it replaces the configuration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// editorCommand returns the command and arguments for the user's editor from the VISUAL or EDITOR environment
+// variables, falling back to vi (or Notepad on Windows).
+func editorCommand() (string, []string) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	// Editors like `code --wait` need their arguments
+	if fields := strings.Fields(editor); len(fields) > 0 {
+		return fields[0], fields[1:]
+	}
+	if runtime.GOOS == "windows" {
+		return "notepad", nil
+	}
+	return "vi", nil
+}
+
+// EditConfig opens the JSON config file in the user's editor and saves the changes after validating them. The file is
+// edited as a temporary copy, so the configuration is only replaced if the result is valid; if it is not, the user can
+// edit it again or discard the changes. The previous JSON config file is kept as "bloodhound.config.json.bak". Unknown
+// keys are rejected unless "force" is true.
+func EditConfig(force bool) error {
+	configFile, original, current, err := readConfigFile()
+	if err != nil {
+		return err
+	}
+
+	// The copy holds the same secrets as the file, so it gets the same permissions
+	edit, err := os.CreateTemp(GetBloodHoundDir(), ".bloodhound.config.*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create a copy of the JSON config file to edit: %w", err)
+	}
+	editPath := edit.Name()
+	defer os.Remove(editPath)
+	_, err = edit.Write(original)
+	if closeErr := edit.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create a copy of the JSON config file to edit: %w", err)
+	}
+	if err := os.Chmod(editPath, configFileMode()); err != nil {
+		return fmt.Errorf("failed to set the permissions on the copy of the JSON config file: %w", err)
+	}
+
+	editor, args := editorCommand()
+	for {
+		if err := RunCmdWithIO(editor, append(args, editPath), nil, os.Stdin, os.Stdout, os.Stderr); err != nil {
+			return fmt.Errorf("the editor, `%s`, failed: %w", editor, err)
+		}
+		edited, err := os.ReadFile(editPath)
+		if err != nil {
+			return fmt.Errorf("failed to read the edited JSON config file: %w", err)
+		}
+		if bytes.Equal(edited, original) {
+			fmt.Println("[+] The JSON config file was not changed.")
+			return nil
+		}
+
+		settings, err := validateEditedConfig(edited, current, force)
+		if err == nil {
+			if err := replaceConfigWithBackup(settings); err != nil {
+				return err
+			}
+			fmt.Printf("[+] Configuration successfully updated. The previous file was saved to %s.bak.\n", configFile)
+			return nil
+		}
+
+		fmt.Printf("[-] The edited JSON config file is not valid: %s\n", err)
+		if !AskForConfirmation("[*] Do you want to edit it again? Answering no discards your changes.") {
+			fmt.Println("[+] Your changes were discarded, and the JSON config file was not changed.")
+			return nil
+		}
+	}
+}
+
+// readConfigFile returns the path, content, and settings of the JSON config file.
+func readConfigFile() (string, []byte, map[string]interface{}, error) {
+	configFile := filepath.Join(GetBloodHoundDir(), "bloodhound.config.json")
+	content, err := os.ReadFile(configFile)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to read the JSON config file: %w", err)
+	}
+	var settings map[string]interface{}
+	if err := json.Unmarshal(content, &settings); err != nil {
+		return "", nil, nil, fmt.Errorf("%w: error while parsing the JSON config file: %w", ErrInvalidConfig, err)
+	}
+	return configFile, content, settings, nil
+}
+
+// replaceConfigWithBackup copies the JSON config file to "bloodhound.config.json.bak" and replaces the configuration
+// with the nested settings.
+func replaceConfigWithBackup(settings map[string]interface{}) error {
+	configFile := filepath.Join(GetBloodHoundDir(), "bloodhound.config.json")
+	if err := CopyFile(configFile, configFile+".bak"); err != nil {
+		return fmt.Errorf("failed to back up the JSON config file: %w", err)
+	}
+	if err := os.Chmod(configFile+".bak", configFileMode()); err != nil {
+		return fmt.Errorf("failed to set the permissions on the backup of the JSON config file: %w", err)
+	}
+	return reloadConfig(settings)
+}
+
+// validateEditedConfig checks the edited JSON config file against the schema like validateConfigSettings.
+func validateEditedConfig(content []byte, current map[string]interface{}, force bool) (map[string]interface{}, error) {
+	var settings map[string]interface{}
+	if err := json.Unmarshal(content, &settings); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+	return validateConfigSettings(settings, current, force)
+}
+
+// validateConfigSettings checks nested settings against the schema and returns them with the values converted to the
+// schema's types. The settings are changed in place. The read-only keys must keep their "current" values, the secrets
+// in "current" cannot be removed, and unknown keys are rejected unless "force" is true. Returns an error wrapping
+// ErrInvalidConfig describing every problem.
+func validateConfigSettings(settings map[string]interface{}, current map[string]interface{}, force bool) (map[string]interface{}, error) {
+	var problems []string
+	for _, key := range flattenSettings(settings) {
+		value, _ := getNestedValue(settings, key)
+		entry, known := LookupConfigKey(key)
+		if !known {
+			if !force {
+				problems = append(problems, fmt.Sprintf("`%s` is not a known config key (use `--force` to keep it)", key))
+			}
+			continue
+		}
+		// Aliases are saved as the key they refer to
+		if entry.Key != key {
+			deleteNestedValue(settings, key)
+			key = entry.Key
+		}
+		if entry.ReadOnly {
+			if was, _ := getNestedValue(current, key); !reflect.DeepEqual(value, was) {
+				problems = append(problems, fmt.Sprintf("`%s` is managed by BloodHound CLI and cannot be changed", key))
+			}
+			continue
+		}
+		if value == nil {
+			problems = append(problems, fmt.Sprintf("`%s` cannot be null; remove the key to use its default", key))
+			continue
+		}
+		if _, isSection := value.(map[string]interface{}); isSection {
+			problems = append(problems, fmt.Sprintf("`%s` must be a value, not a section", key))
+			continue
+		}
+		parsed, err := entry.Parse(fmt.Sprint(value))
+		if err != nil {
+			problems = append(problems, strings.TrimPrefix(err.Error(), ErrInvalidConfig.Error()+": "))
+			continue
+		}
+		setNestedValue(settings, key, parsed)
+	}
+	// Removing a secret would replace it with a new default that does not match the deployment
+	for _, key := range secretKeys {
+		_, had := getNestedValue(current, key)
+		if _, has := getNestedValue(settings, key); had && !has {
+			problems = append(problems, fmt.Sprintf("`%s` cannot be removed, so use `resetpwd` or `rotate-secrets` to change it", key))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidConfig, strings.Join(problems, "; "))
+	}
+	return settings, nil
+}
+
+// flattenSettings returns the dotted keys (e.g., "neo4j.secret") of every value in nested settings, sorted. Sections
+// that match a key in the schema are returned as keys, so they can be reported as invalid values.
+func flattenSettings(settings map[string]interface{}) []string {
+	var keys []string
+	var walk func(prefix string, section map[string]interface{})
+	walk = func(prefix string, section map[string]interface{}) {
+		for name, value := range section {
+			key := prefix + name
+			if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+				if _, known := LookupConfigKey(key); !known {
+					walk(key+".", nested)
+					continue
+				}
+			}
+			keys = append(keys, key)
+		}
+	}
+	walk("", settings)
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+// keepBloodHoundEnv restores the configuration that reloadConfig replaces when the test finishes. Call it after
+// setTestConfigDirs and setTestConfig, so their values are restored on the original configuration.
+func keepBloodHoundEnv(t *testing.T) {
+	original := bhEnv
+	t.Cleanup(func() {
+		bhEnv = original
+		for _, key := range secretKeys {
+			delete(storedSecrets, key)
+		}
+	})
+}
+
+// useTestFileStore keeps the secrets in a file store in the test's config directory with the admin password set to
+// "hunter2".
+func useTestFileStore(t *testing.T) *fileStore {
+	lowerSecretFileIterations(t)
+	t.Setenv(secretPassphraseVar, "correct horse")
+	setTestConfig(t, map[string]string{"secret_store": secretStoreFile, "default_admin.password": "hunter2", "log_level": "INFO"})
+	assert.NoError(t, WriteBloodHoundEnvironmentVariables())
+	return &fileStore{path: filepath.Join(GetBloodHoundDir(), secretFileName), passphrase: "correct horse"}
+}
+
+func TestEditConfigKeepsStoredSecrets(t *testing.T) {
+	setTestConfigDirs(t)
+	store := useTestFileStore(t)
+	keepBloodHoundEnv(t)
+	t.Setenv("VISUAL", "sed -i s/INFO/DEBUG/")
+
+	assert.NoError(t, EditConfig(false), "`EditConfig()` should save the edited file")
+	assert.Equal(t, "DEBUG", bhEnv.GetString("log_level"))
+	assert.Equal(t, "hunter2", bhEnv.GetString("default_admin.password"), "The stored admin password should be loaded again")
+	value, err := store.Get("default_admin.password")
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", value, "The stored admin password should not be replaced")
+}
+
+func TestValidateEditedConfig(t *testing.T) {
+	current := map[string]interface{}{
+		"version":          float64(currentConfigVersion),
+		"config_directory": "/home/bloodhound/.config/bloodhound",
+		"default_admin":    map[string]interface{}{"password": "hunter2"},
+	}
+
+	settings, err := validateEditedConfig([]byte(`{
+		"version": 2,
+		"config_directory": "/home/bloodhound/.config/bloodhound",
+		"default_admin": {"password": "hunter2"},
+		"log_level": "debug",
+		"bloodhound": {"port": 8443},
+		"WAIT_TIMEOUT": "90s"
+	}`), current, false)
+	assert.NoError(t, err, "`validateEditedConfig()` should accept valid values")
+	assert.Equal(t, "DEBUG", settings["log_level"], "Values should be converted to the schema's values")
+	assert.Equal(t, 8443, settings["bloodhound"].(map[string]interface{})["port"], "Numbers should be converted to the schema's types")
+	assert.Equal(t, "1m30s", settings["wait_timeout"], "Keys should be saved in lowercase")
+	assert.NotContains(t, settings, "WAIT_TIMEOUT")
+
+	invalid := []string{
+		`{"log_level": "DEBUG",`,
+		`{"default_admin": {"password": "hunter2"}, "log_lvl": "DEBUG"}`,
+		`{"default_admin": {"password": "hunter2"}, "root_url": "127.0.0.1:8080"}`,
+		`{"default_admin": {"password": "hunter2"}, "config_directory": "/tmp"}`,
+		`{"default_admin": {"password": "hunter2"}, "bind_addr": null}`,
+		`{"log_level": "INFO"}`,
+	}
+	for _, content := range invalid {
+		_, err := validateEditedConfig([]byte(content), current, false)
+		assert.True(t, errors.Is(err, ErrInvalidConfig), "`validateEditedConfig()` should reject %s", content)
+	}
+
+	_, err = validateEditedConfig([]byte(`{"default_admin": {"password": "hunter2"}, "custom": {"flag": true}}`), current, true)
+	assert.NoError(t, err, "`validateEditedConfig()` should keep unknown keys with `force`")
+}
+
+func TestFlattenSettings(t *testing.T) {
+	keys := flattenSettings(map[string]interface{}{
+		"log_level": "INFO",
+		"neo4j":     map[string]interface{}{"user": "neo4j", "secret": "s3cret"},
+		"bind_addr": map[string]interface{}{"host": "0.0.0.0"},
+	})
+	assert.Equal(t, []string{"bind_addr", "log_level", "neo4j.secret", "neo4j.user"}, keys)
+}
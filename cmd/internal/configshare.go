@@ -0,0 +1,264 @@
+package internal
+
+// Functions for exporting the configuration to share it with another system and importing it there
+// Exports leave out the `config_directory`, `compose_source`, and `compose_project` values because they belong to the
+// system that wrote them
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ExportEnv is the export format for a Compose `.env` file.
+var ExportEnv = "env"
+
+// Values that can be written to a `.env` file without quotes
+var plainEnvValue = regexp.MustCompile(`^[A-Za-z0-9_./:@,+=-]*$`)
+
+// ExportConfig writes the configuration to the writer as JSON, YAML, or a Compose `.env` file with the variables the
+// Docker YAML files read (see composeVariables). Secret values are blanked if "redact" is true.
+func ExportConfig(w io.Writer, format string, redact bool) error {
+	settings := currentSettings()
+	// The importing system keeps its own config directory and YAML files
+	deleteNestedValue(settings, "config_directory")
+	deleteNestedValue(settings, "compose_source")
+	deleteNestedValue(settings, "compose_project")
+	if redact {
+		for _, key := range secretKeys {
+			if _, ok := getNestedValue(settings, key); ok {
+				setNestedValue(settings, key, "")
+			}
+		}
+	}
+
+	switch format {
+	case OutputJson, OutputYaml:
+		return RenderOutput(w, format, settings, nil)
+	case ExportEnv:
+		fmt.Fprintln(w, "# Compose variables exported by BloodHound CLI")
+		if redact {
+			fmt.Fprintln(w, "# Secret values were redacted, so set them before using this file")
+		}
+		for _, variable := range composeVariables {
+			value, ok := getNestedValue(settings, variable.Key)
+			if !ok || value == nil {
+				continue
+			}
+			text := fmt.Sprint(value)
+			if text == "" && !(redact && IsSecretKey(variable.Key)) {
+				continue
+			}
+			fmt.Fprintf(w, "%s=%s\n", variable.EnvVar, quoteEnvValue(text))
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported export format `%s`; use %s, %s, or %s", format, OutputJson, OutputYaml, ExportEnv)
+}
+
+// quoteEnvValue quotes a value for a `.env` file if it has characters Compose would otherwise interpret. Single quotes
+// keep the value literal, so they are used unless the value contains one.
+func quoteEnvValue(value string) string {
+	if plainEnvValue.MatchString(value) {
+		return value
+	}
+	if !strings.Contains(value, "'") {
+		return "'" + value + "'"
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// ImportConfig reads a file written by ExportConfig, shows how it changes the configuration, and applies the changes
+// after the user confirms. The format is chosen by the file's extension (".json", ".yaml", ".yml", or ".env"). If
+// "merge" is true, the imported values are added to the current configuration; otherwise, they replace it, except for
+// the config directory and the secrets, which are kept unless the file has them. The database credentials must match
+// the existing databases, so the current ones are always kept unless "credentials" is true. Blank secrets (e.g., from
+// `config export --redact`) keep their current values. Unknown keys are rejected unless "force" is true. The previous
+// JSON config file is kept as "bloodhound.config.json.bak". Returns ErrCancelled if the user declines.
+func ImportConfig(path string, merge bool, credentials bool, force bool) error {
+	imported, err := readImportFile(path)
+	if err != nil {
+		return err
+	}
+	configFile, _, current, err := readConfigFile()
+	if err != nil {
+		return err
+	}
+	if !credentials {
+		var skipped []string
+		for _, key := range databaseCredentialKeys {
+			if value, ok := getNestedValue(imported, key); ok && fmt.Sprint(value) != "" {
+				skipped = append(skipped, key)
+			}
+		}
+		if len(skipped) > 0 {
+			fmt.Printf("[!] Keeping the current database credentials instead of the file's %s because they must match the existing databases; add `--include-credentials` to import them\n", strings.Join(skipped, ", "))
+		}
+	}
+	settings, err := importedSettings(imported, current, merge, credentials, force)
+	if err != nil {
+		return err
+	}
+
+	changes := diffSettings(current, settings)
+	if len(changes) == 0 {
+		fmt.Println("[+] The imported file matches the current configuration, so nothing was changed.")
+		return nil
+	}
+	fmt.Println("[+] Importing the file makes these changes to the configuration:")
+	for _, change := range changes {
+		fmt.Println("    " + change)
+	}
+	if !AskForConfirmation("[*] Do you want to apply these changes?") {
+		return ErrCancelled
+	}
+	if err := replaceConfigWithBackup(settings); err != nil {
+		return err
+	}
+	fmt.Printf("[+] Configuration successfully imported. The previous file was saved to %s.bak.\n", configFile)
+	return nil
+}
+
+// readImportFile reads nested settings from a JSON, YAML, or `.env` file based on its extension. Returns an error
+// wrapping ErrInvalidConfig if the file cannot be parsed.
+func readImportFile(path string) (map[string]interface{}, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	settings := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(content, &settings)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &settings)
+	case ".env":
+		settings, err = parseEnvFile(content)
+	default:
+		return nil, fmt.Errorf("%w: %s must end with .json, .yaml, .yml, or .env so its format is known", ErrInvalidConfig, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: error while parsing %s: %w", ErrInvalidConfig, path, err)
+	}
+	return settings, nil
+}
+
+// parseEnvFile reads the Compose variables in composeVariables from a `.env` file into nested settings.
+func parseEnvFile(content []byte) (map[string]interface{}, error) {
+	settings := map[string]interface{}{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(strings.TrimPrefix(text, "export "), "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d is not a VARIABLE=value line", line)
+		}
+		name = strings.TrimSpace(name)
+		key := ""
+		for _, variable := range composeVariables {
+			if variable.EnvVar == name {
+				key = variable.Key
+			}
+		}
+		if key == "" {
+			return nil, fmt.Errorf("line %d sets `%s`, which is not one of the Compose variables BloodHound CLI manages", line, name)
+		}
+		setNestedValue(settings, key, unquoteEnvValue(strings.TrimSpace(value)))
+	}
+	return settings, scanner.Err()
+}
+
+// unquoteEnvValue removes the quotes added by quoteEnvValue.
+func unquoteEnvValue(value string) string {
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return value[1 : len(value)-1]
+	}
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		return strings.NewReplacer(`\\`, `\`, `\"`, `"`).Replace(value[1 : len(value)-1])
+	}
+	return value
+}
+
+// importedSettings returns the settings that result from importing the "imported" settings into the "current"
+// settings of the JSON config file, validated against the schema (see ImportConfig).
+func importedSettings(imported map[string]interface{}, current map[string]interface{}, merge bool, credentials bool, force bool) (map[string]interface{}, error) {
+	if _, err := migrateConfig(imported); err != nil {
+		return nil, err
+	}
+	// These keys belong to the importing system
+	deleteNestedValue(imported, "config_directory")
+	deleteNestedValue(imported, "compose_source")
+	deleteNestedValue(imported, "compose_project")
+	deleteNestedValue(imported, "version")
+	if !credentials {
+		for _, key := range databaseCredentialKeys {
+			deleteNestedValue(imported, key)
+		}
+	}
+	for _, key := range secretKeys {
+		if value, ok := getNestedValue(imported, key); ok && fmt.Sprint(value) == "" {
+			deleteNestedValue(imported, key)
+		}
+	}
+
+	settings := preservedSettings(current)
+	if merge {
+		settings = map[string]interface{}{}
+		for _, key := range flattenSettings(current) {
+			value, _ := getNestedValue(current, key)
+			setNestedValue(settings, key, value)
+		}
+	}
+	for _, key := range flattenSettings(imported) {
+		value, _ := getNestedValue(imported, key)
+		setNestedValue(settings, key, value)
+	}
+	return validateConfigSettings(settings, current, force)
+}
+
+// diffSettings describes the differences between two sets of nested settings, one line per key, sorted by key. Secret
+// values are redacted.
+func diffSettings(before map[string]interface{}, after map[string]interface{}) []string {
+	describe := func(key string, value interface{}) string {
+		if IsSecretKey(key) && fmt.Sprint(value) != "" {
+			return redactedValue
+		}
+		return fmt.Sprint(value)
+	}
+
+	keys := map[string]bool{}
+	for _, key := range append(flattenSettings(before), flattenSettings(after)...) {
+		keys[key] = true
+	}
+	var changes []string
+	for key := range keys {
+		old, had := getNestedValue(before, key)
+		value, has := getNestedValue(after, key)
+		switch {
+		case had && !has:
+			changes = append(changes, fmt.Sprintf("- %s (was %s)", key, describe(key, old)))
+		case !had && has:
+			changes = append(changes, fmt.Sprintf("+ %s = %s", key, describe(key, value)))
+		case fmt.Sprint(old) != fmt.Sprint(value):
+			if IsSecretKey(key) {
+				changes = append(changes, fmt.Sprintf("~ %s changes", key))
+			} else {
+				changes = append(changes, fmt.Sprintf("~ %s = %s (was %s)", key, describe(key, value), describe(key, old)))
+			}
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i][2:] < changes[j][2:] })
+	return changes
+}
@@ -0,0 +1,156 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportConfig(t *testing.T) {
+	assert.NoError(t, ParseBloodHoundEnvironmentVariables())
+	setTestConfig(t, map[string]string{
+		"neo4j.secret":    "s3cret pass",
+		"neo4j.user":      "neo4j",
+		"bloodhound.host": "0.0.0.0",
+	})
+
+	var out bytes.Buffer
+	assert.NoError(t, ExportConfig(&out, OutputJson, true))
+	assert.NotContains(t, out.String(), "s3cret", "Secrets should be blanked with `redact`")
+	assert.NotContains(t, out.String(), "config_directory", "The config directory should not be exported")
+
+	out.Reset()
+	assert.NoError(t, ExportConfig(&out, ExportEnv, false))
+	assert.Contains(t, out.String(), "NEO4J_SECRET='s3cret pass'\n", "Values with spaces should be quoted")
+	assert.Contains(t, out.String(), "BLOODHOUND_HOST=0.0.0.0\n")
+
+	out.Reset()
+	assert.NoError(t, ExportConfig(&out, ExportEnv, true))
+	assert.Contains(t, out.String(), "NEO4J_SECRET=\n", "Redacted secrets should be left blank")
+
+	assert.Error(t, ExportConfig(&out, OutputTable, false), "`ExportConfig()` should reject the table format")
+}
+
+func TestEnvValueQuoting(t *testing.T) {
+	values := []string{"plain", "", "with space", "it's", `say "hi" \o/`, "$HOME"}
+	for _, value := range values {
+		assert.Equal(t, value, unquoteEnvValue(quoteEnvValue(value)), "%q should survive quoting", value)
+	}
+	assert.Equal(t, "plain", quoteEnvValue("plain"))
+	assert.Equal(t, "'$HOME'", quoteEnvValue("$HOME"), "Values Compose would interpolate should be single-quoted")
+}
+
+func TestReadImportFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.json": `{"neo4j": {"user": "graph"}}`,
+		"config.yaml": "neo4j:\n  user: graph\n",
+		"config.env":  "# Exported\nexport NEO4J_USER='graph'\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+		settings, err := readImportFile(path)
+		assert.NoError(t, err, "`readImportFile()` should read %s", name)
+		value, _ := getNestedValue(settings, "neo4j.user")
+		assert.Equal(t, "graph", value, "%s should set `neo4j.user`", name)
+	}
+
+	invalid := map[string]string{
+		"config.txt":  `{}`,
+		"broken.json": `{"neo4j": `,
+		"unknown.env": "ROOT_URL=http://127.0.0.1:8080\n",
+	}
+	for name, content := range invalid {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+		_, err := readImportFile(path)
+		assert.True(t, errors.Is(err, ErrInvalidConfig), "`readImportFile()` should reject %s", name)
+	}
+}
+
+func TestImportedSettings(t *testing.T) {
+	current := func() map[string]interface{} {
+		return map[string]interface{}{
+			"version":          float64(currentConfigVersion),
+			"config_directory": "/home/bloodhound/.config/bloodhound",
+			"default_admin":    map[string]interface{}{"password": "hunter2"},
+			"neo4j":            map[string]interface{}{"user": "neo4j", "secret": "s3cret"},
+			"log_level":        "INFO",
+			"bind_addr":        "0.0.0.0:8080",
+		}
+	}
+	imported := func() map[string]interface{} {
+		return map[string]interface{}{
+			"version":          float64(currentConfigVersion),
+			"config_directory": "/other/system",
+			"default_admin":    map[string]interface{}{"password": ""},
+			"neo4j":            map[string]interface{}{"secret": "other-system"},
+			"log_level":        "debug",
+		}
+	}
+
+	settings, err := importedSettings(imported(), current(), false, false, false)
+	assert.NoError(t, err, "`importedSettings()` should accept an exported file")
+	assert.Equal(t, "/home/bloodhound/.config/bloodhound", settings["config_directory"], "The config directory should be kept")
+	assert.Equal(t, "hunter2", settings["default_admin"].(map[string]interface{})["password"], "Blank secrets should keep their current values")
+	assert.Equal(t, "s3cret", settings["neo4j"].(map[string]interface{})["secret"], "Database credentials should be kept")
+	assert.Equal(t, "neo4j", settings["neo4j"].(map[string]interface{})["user"])
+	assert.Equal(t, "DEBUG", settings["log_level"])
+	assert.NotContains(t, settings, "bind_addr", "Replacing should drop values the file does not set")
+
+	settings, err = importedSettings(imported(), current(), true, false, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "0.0.0.0:8080", settings["bind_addr"], "Merging should keep values the file does not set")
+	assert.Equal(t, "s3cret", settings["neo4j"].(map[string]interface{})["secret"], "Merging should also keep the database credentials")
+
+	credentials, err := importedSettings(imported(), current(), false, true, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "other-system", credentials["neo4j"].(map[string]interface{})["secret"], "Database credentials should only be imported when requested")
+
+	_, err = importedSettings(map[string]interface{}{"log_lvl": "INFO"}, current(), true, false, false)
+	assert.True(t, errors.Is(err, ErrInvalidConfig), "Unknown keys should be rejected without `force`")
+
+	changes := diffSettings(current(), settings)
+	assert.Equal(t, []string{"~ log_level = DEBUG (was INFO)"}, changes)
+	changes = diffSettings(map[string]interface{}{"neo4j": map[string]interface{}{"secret": "old"}, "bind_addr": "0.0.0.0:8080"},
+		map[string]interface{}{"neo4j": map[string]interface{}{"secret": "new"}, "log_level": "INFO"})
+	assert.Equal(t, []string{"- bind_addr (was 0.0.0.0:8080)", "+ log_level = INFO", "~ neo4j.secret changes"}, changes, "Secrets should not be shown")
+}
+
+// answerPrompts feeds the input to AskForConfirmation until the test finishes.
+func answerPrompts(t *testing.T, input string) {
+	path := filepath.Join(t.TempDir(), "stdin")
+	assert.NoError(t, os.WriteFile(path, []byte(input), 0600))
+	stdin, err := os.Open(path)
+	assert.NoError(t, err)
+	original := os.Stdin
+	os.Stdin = stdin
+	t.Cleanup(func() {
+		os.Stdin = original
+		stdin.Close()
+	})
+}
+
+func TestImportConfigKeepsStoredSecrets(t *testing.T) {
+	setTestConfigDirs(t)
+	store := useTestFileStore(t)
+	keepBloodHoundEnv(t)
+	path := filepath.Join(t.TempDir(), "bloodhound.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"log_level": "DEBUG", "default_admin": {"password": ""}, "neo4j": {"secret": "other-system"}}`), 0600))
+
+	answerPrompts(t, "n\n")
+	assert.ErrorIs(t, ImportConfig(path, true, false, false), ErrCancelled, "Declining should return `ErrCancelled`")
+	assert.Equal(t, "INFO", bhEnv.GetString("log_level"))
+
+	answerPrompts(t, "y\n")
+	assert.NoError(t, ImportConfig(path, true, false, false))
+	assert.Equal(t, "DEBUG", bhEnv.GetString("log_level"))
+	assert.Empty(t, bhEnv.GetString("neo4j.secret"), "The file's database password should not be imported")
+	value, err := store.Get("default_admin.password")
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", value, "The stored admin password should not be replaced")
+}
@@ -2,14 +2,12 @@ package internal
 
 import (
 	"context"
-	"encoding/binary"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/moby/moby/api/types/container"
-	"github.com/moby/moby/client"
 )
 
 // Vars for tracking the list of BloodHound images
@@ -21,23 +19,19 @@ var (
 	devImages = []string{
 		"bhce_bloodhound", "bhce_neo4j", "bhce_postgres",
 	}
-	// Default root command for Docker commands, will fallback to Podman if Docker is not found
-	dockerCmd = "docker"
-	// URLs for the BloodHound compose files
+	// Names of the BloodHound compose files
 	devYaml  = "docker-compose.dev.yml"
 	prodYaml = "docker-compose.yml"
-	devUrl   = "https://raw.githubusercontent.com/SpecterOps/BloodHound_CLI/refs/heads/main/docker-compose.dev.yml"
-	prodUrl  = "https://raw.githubusercontent.com/SpecterOps/BloodHound_CLI/refs/heads/main/docker-compose.yml"
 	loginUri = "/ui/login"
 )
 
 // Container is a custom type for storing container information similar to output from "docker containers ls".
 type Container struct {
-	ID     string
-	Image  string
-	Status string
-	Ports  []container.PortSummary
-	Name   string
+	ID     string                  `json:"id"`
+	Image  string                  `json:"image"`
+	Status string                  `json:"status"`
+	Ports  []container.PortSummary `json:"ports"`
+	Name   string                  `json:"name"`
 }
 
 // Containers is a collection of Container structs
@@ -58,316 +52,272 @@ func (c Containers) Swap(i, j int) {
 	c[i], c[j] = c[j], c[i]
 }
 
-// EvaluateDockerComposeStatus checks if Docker (or Podman in Docker compatibility mode) and the Docker Compose plugin are installed and operational.
-// It verifies the presence of the CLI, ensures the daemon is running, and sets the global dockerCmd variable to either `docker` or `podman`.
-// The function exits fatally via log.Fatal* if any requirement is not met; otherwise it returns normally.
-func EvaluateDockerComposeStatus() {
-	fmt.Println("[+] Checking the status of Docker and the Compose plugin...")
-	// Check for ``docker`` first because it's required for everything to come
-	dockerExists := CheckPath("docker")
-	if !dockerExists {
-		podmanExists := CheckPath("podman")
-		if podmanExists {
-			fmt.Println("[+] Docker is not installed, but Podman is installed. Using Podman as a Docker alternative.")
-			dockerCmd = "podman"
-		} else {
-			log.Fatalln("Neither Docker nor Podman is installed on this system, so please install Docker or Podman (in Docker compatibility mode) and try again.")
-		}
-	}
-
-	// Check if the Docker Engine is running
-	_, engineErr := RunBasicCmd(dockerCmd, []string{"info"})
-	if engineErr != nil {
-		log.Fatalf("%s is installed on this system, but the daemon is not running or access was denied.", dockerCmd)
-	}
-
-	// Check for the ``compose`` plugin as our first choice
-	_, composeErr := RunBasicCmd(dockerCmd, []string{"compose", "version"})
-	if composeErr != nil {
-		// Check if the deprecated v1 script is installed
-		composeScriptExists := CheckPath("docker-compose")
-		if composeScriptExists {
-			fmt.Println("[!] The deprecated `docker-compose` v1 script was detected on your system")
-			fmt.Println("[!] Docker has deprecated v1 and this CLI tool no longer supports it")
-			log.Fatalln("Please upgrade to Docker Compose v2 and try again: https://docs.docker.com/compose/install/")
-		} else {
-			log.Fatalln("Docker Compose is not installed, so please install it and try again: https://docs.docker.com/compose/install/")
-		}
-	}
-
-	fmt.Println("[+] Docker and the Compose plugin checks have passed")
+// EvaluateEnvironment checks for the presence of Docker YAML files and writes them if necessary, downloading them if
+// "refresh" is true (see WriteDockerComposeFiles).
+func EvaluateEnvironment(refresh bool) error {
+	fmt.Println("[+] Checking for the Docker YAML files...")
+	return WriteDockerComposeFiles(refresh)
 }
 
-// DownloadDockerComposeFiles downloads the production and development Docker Compose YAML files into the BloodHound directory.
-// If either file already exists, prompts the user for confirmation before overwriting. Exits fatally on download failure.
-func DownloadDockerComposeFiles() {
-	workingDir := GetBloodHoundDir()
-	downloadProd := true
-	downloadDev := true
-	if FileExists(filepath.Join(workingDir, prodYaml)) {
-		c := AskForConfirmation("[*] A production YAML file already exists in the current directory. Do you want to overwrite it?")
-		if !c {
-			downloadProd = false
-		}
-	}
-	if downloadProd {
-		fmt.Printf("[+] Downloading the production YAML file from %s...\n", prodUrl)
-		prodDownloadErr := DownloadFile(prodUrl, filepath.Join(workingDir, prodYaml))
-		if prodDownloadErr != nil {
-			log.Fatalf("Error trying to download the production YAML file: %v\n", prodDownloadErr)
-		}
+// RunDockerComposeInstall performs a first-time installation of BloodHound containers using the specified Docker Compose
+// YAML file. It ensures required YAML files are present, generates the database passwords for a new deployment, pulls
+// container images, starts the environment in detached mode, and waits up to "timeout" for the services to become
+// healthy. If "secure" is true, the secrets are generated as described for EnsureComposeSecrets. Prints login
+// credentials and UI access information upon successful setup.
+func RunDockerComposeInstall(rt Runtime, yaml string, timeout time.Duration, secure bool) error {
+	// If the YAML files don't exist, write the copies built into the binary
+	if err := WriteDockerComposeFiles(false); err != nil {
+		return err
 	}
 
-	if FileExists(filepath.Join(workingDir, devYaml)) {
-		c := AskForConfirmation("[*] A development YAML file already exists in the current directory. Do you want to overwrite it?")
-		if !c {
-			downloadDev = false
-		}
+	if err := CheckYamlExists(yaml); err != nil {
+		return err
 	}
-	if downloadDev {
-		fmt.Printf("[+] Downloading the development YAML file from %s...\n", devUrl)
-		devDownloadErr := DownloadFile(devUrl, filepath.Join(workingDir, devYaml))
-		if devDownloadErr != nil {
-			log.Fatalf("Error trying to download the development YAML file: %v\n", devDownloadErr)
-		}
+	if err := EnsureComposeSecrets(rt, secure); err != nil {
+		return err
 	}
-}
-
-// EvaluateEnvironment checks for the presence of Docker YAML files and initiates their download if necessary.
-func EvaluateEnvironment() {
-	fmt.Println("[+] Checking for the Docker YAML files...")
-	DownloadDockerComposeFiles()
-}
-
-// RunDockerComposeInstall performs a first-time installation of BloodHound containers using the specified Docker Compose YAML file.
-// It ensures required YAML files are present, pulls container images, and starts the environment in detached mode.
-// Prints login credentials and UI access information upon successful setup. Exits fatally on errors.
-func RunDockerComposeInstall(yaml string) {
-	// If the YAML files don't exist, download them from the BloodHound repo
-	DownloadDockerComposeFiles()
-
-	CheckYamlExists(yaml)
-	buildErr := RunCmd(dockerCmd, []string{"-f", yaml, "pull"})
+	buildErr := rt.Pull(yaml)
 	if buildErr != nil {
-		log.Fatalf("Error trying to build with %s: %v\n", yaml, buildErr)
+		return fmt.Errorf("error trying to build with %s: %w", yaml, buildErr)
 	}
-	upErr := RunCmd(dockerCmd, []string{"-f", yaml, "up", "-d"})
+	if err := exposeAdminPassword(); err != nil {
+		return err
+	}
+	upErr := rt.Up(yaml)
 	if upErr != nil {
-		log.Fatalf("Error trying to bring up environment with %s: %v\n", yaml, upErr)
+		return fmt.Errorf("error trying to bring up environment with %s: %w", yaml, upErr)
 	}
-	fmt.Println("[+] BloodHound is ready to go!")
-	fmt.Printf("[+] You can log in as `%s` with this password: %s\n", bhEnv.GetString("default_admin.principal_name"), bhEnv.GetString("default_admin.password"))
-	fmt.Println("[+] You can get your admin password by running: bloodhound-cli config get default_password")
-	fmt.Printf("[+] You can access the BloodHound UI at: %s%s\n", bhEnv.GetString("root_url"), loginUri)
+	if err := WaitForStack(rt, timeout); err != nil {
+		return err
+	}
+	if err := concealAdminPassword(timeout); err != nil {
+		return err
+	}
+	printReadyMessage()
+	return nil
 }
 
 // RunDockerComposeUninstall removes all BloodHound containers, images, and volumes defined in the specified Docker
 // Compose YAML file, then optionally deletes the BloodHound config directory after user confirmation. The process is
-// interactive and exits if the user declines any confirmation prompt. Fatal errors are logged if uninstallation or
-// directory deletion fails.
-func RunDockerComposeUninstall(yaml string) {
+// interactive and returns ErrCancelled if the user declines the first confirmation prompt.
+func RunDockerComposeUninstall(rt Runtime, yaml string) error {
 	c := AskForConfirmation("[!] This command removes all containers, images, and volume data. Are you sure you want to uninstall?")
 	if !c {
-		os.Exit(0)
+		return ErrCancelled
 	}
 
 	fmt.Println("[+] Uninstalling the BloodHound containers...")
-	CheckYamlExists(yaml)
-	uninstallErr := RunCmd(dockerCmd, []string{"-f", yaml, "down", "--rmi", "all", "-v", "--remove-orphans"})
+	if err := CheckYamlExists(yaml); err != nil {
+		return err
+	}
+	uninstallErr := rt.Down(yaml, DownOptions{Volumes: true, Images: true, RemoveOrphans: true})
 	if uninstallErr != nil {
-		log.Fatalf("Error trying to uninstall with %s: %v\n", yaml, uninstallErr)
+		return fmt.Errorf("error trying to uninstall with %s: %w", yaml, uninstallErr)
 	}
 
 	configDir := GetBloodHoundDir()
 	delConf := AskForConfirmation("[!] Do you want to also delete the config directory, " + configDir + ", and its contents?")
 	if !delConf {
-		os.Exit(0)
+		return nil
 	}
 
 	delErr := os.RemoveAll(configDir)
 	if delErr != nil {
-		log.Fatalf("Error trying to delete the config directory: %v\n", delErr)
-	} else {
-		fmt.Println("[+] Successfully deleted the BloodHound config directory!")
+		return fmt.Errorf("error trying to delete the config directory: %w", delErr)
 	}
+	// A config directory moved with `config move-dir` leaves a pointer in the default directory
+	pointer := filepath.Join(defaultConfigDir(), "bloodhound.config.json")
+	if isConfigPointer(pointer) {
+		if err := os.Remove(pointer); err != nil {
+			return fmt.Errorf("error trying to delete the pointer to the config directory: %w", err)
+		}
+	}
+	fmt.Println("[+] Successfully deleted the BloodHound config directory!")
 	fmt.Println("[+] Uninstall was successful. You can re-install with `./bloodhound-cli install`.")
 	fmt.Println("[+] The config directory and JSON config file will be recreated if you continue using BloodHound CLI.")
+	return nil
 }
 
 // RunDockerComposeUpgrade rebuilds and restarts all containers defined in the specified Docker Compose YAML file.
 // It brings down any running containers, rebuilds images, and brings the environment back up in detached mode.
-// Exits fatally if any Docker command fails.
-func RunDockerComposeUpgrade(yaml string) {
-	fmt.Printf("[+] Running `%s` commands to build containers with %s...\n", dockerCmd, yaml)
-	CheckYamlExists(yaml)
-	downErr := RunCmd(dockerCmd, []string{"-f", yaml, "down"})
+func RunDockerComposeUpgrade(rt Runtime, yaml string) error {
+	fmt.Printf("[+] Running `%s` commands to build containers with %s...\n", rt.Name(), yaml)
+	if err := CheckYamlExists(yaml); err != nil {
+		return err
+	}
+	downErr := rt.Down(yaml, DownOptions{})
 	if downErr != nil {
-		log.Fatalf("Error trying to bring down any running containers with %s: %v\n", yaml, downErr)
+		return fmt.Errorf("error trying to bring down any running containers with %s: %w", yaml, downErr)
 	}
-	buildErr := RunCmd(dockerCmd, []string{"-f", yaml, "build"})
+	buildErr := rt.Build(yaml)
 	if buildErr != nil {
-		log.Fatalf("Error trying to build with %s: %v\n", yaml, buildErr)
+		return fmt.Errorf("error trying to build with %s: %w", yaml, buildErr)
 	}
-	upErr := RunCmd(dockerCmd, []string{"-f", yaml, "up", "-d"})
+	upErr := rt.Up(yaml)
 	if upErr != nil {
-		log.Fatalf("Error trying to bring up environment with %s: %v\n", yaml, upErr)
+		return fmt.Errorf("error trying to bring up environment with %s: %w", yaml, upErr)
 	}
 	fmt.Println("[+] All containers have been built!")
+	return nil
 }
 
 // RunDockerComposeStart starts all services defined in the specified Docker Compose YAML file.
-// Exits fatally if the YAML file does not exist or if starting the containers fails.
-func RunDockerComposeStart(yaml string) {
-	fmt.Printf("[+] Running `%s` to restart containers with %s...\n", dockerCmd, yaml)
-	CheckYamlExists(yaml)
-	startErr := RunCmd(dockerCmd, []string{"-f", yaml, "start"})
+func RunDockerComposeStart(rt Runtime, yaml string) error {
+	fmt.Printf("[+] Running `%s` to restart containers with %s...\n", rt.Name(), yaml)
+	if err := CheckYamlExists(yaml); err != nil {
+		return err
+	}
+	startErr := rt.Start(yaml)
 	if startErr != nil {
-		log.Fatalf("Error trying to restart the containers with %s: %v\n", yaml, startErr)
+		return fmt.Errorf("error trying to restart the containers with %s: %w", yaml, startErr)
 	}
+	return nil
 }
 
 // RunDockerComposeStop stops all services defined in the specified Docker Compose YAML file.
-// Exits the program if stopping services fails.
-func RunDockerComposeStop(yaml string) {
-	fmt.Printf("[+] Running `%s` to stop services with %s...\n", dockerCmd, yaml)
-	CheckYamlExists(yaml)
-	stopErr := RunCmd(dockerCmd, []string{"-f", yaml, "stop"})
+func RunDockerComposeStop(rt Runtime, yaml string) error {
+	fmt.Printf("[+] Running `%s` to stop services with %s...\n", rt.Name(), yaml)
+	if err := CheckYamlExists(yaml); err != nil {
+		return err
+	}
+	stopErr := rt.Stop(yaml)
 	if stopErr != nil {
-		log.Fatalf("Error trying to stop services with %s: %v\n", yaml, stopErr)
+		return fmt.Errorf("error trying to stop services with %s: %w", yaml, stopErr)
 	}
+	return nil
 }
 
 // RunDockerComposeRestart restarts all containers defined in the specified Docker Compose YAML file.
-// Exits fatally if the YAML file does not exist or if the restart operation fails.
-func RunDockerComposeRestart(yaml string) {
-	fmt.Printf("[+] Running `%s` to restart containers with %s...\n", dockerCmd, yaml)
-	CheckYamlExists(yaml)
-	startErr := RunCmd(dockerCmd, []string{"-f", yaml, "restart"})
+func RunDockerComposeRestart(rt Runtime, yaml string) error {
+	fmt.Printf("[+] Running `%s` to restart containers with %s...\n", rt.Name(), yaml)
+	if err := CheckYamlExists(yaml); err != nil {
+		return err
+	}
+	startErr := rt.Restart(yaml)
 	if startErr != nil {
-		log.Fatalf("Error trying to restart the containers with %s: %v\n", yaml, startErr)
+		return fmt.Errorf("error trying to restart the containers with %s: %w", yaml, startErr)
 	}
+	return nil
 }
 
 // RunDockerComposeUp brings up Docker containers in detached mode using the specified Docker Compose YAML file.
-// Exits fatally if the YAML file does not exist or if the command fails.
-func RunDockerComposeUp(yaml string) {
-	fmt.Printf("[+] Running `%s` to bring up the containers with %s...\n", dockerCmd, yaml)
-	CheckYamlExists(yaml)
-	upErr := RunCmd(dockerCmd, []string{"-f", yaml, "up", "-d"})
+func RunDockerComposeUp(rt Runtime, yaml string) error {
+	fmt.Printf("[+] Running `%s` to bring up the containers with %s...\n", rt.Name(), yaml)
+	if err := CheckYamlExists(yaml); err != nil {
+		return err
+	}
+	upErr := rt.Up(yaml)
 	if upErr != nil {
-		log.Fatalf("Error trying to bring up the containers with %s: %v\n", yaml, upErr)
+		return fmt.Errorf("error trying to bring up the containers with %s: %w", yaml, upErr)
 	}
+	return nil
 }
 
 // RunDockerComposeDown stops and removes containers defined in the specified Docker Compose YAML file.
-// If volumes is true, associated Docker volumes are also removed. Exits fatally on failure.
-func RunDockerComposeDown(yaml string, volumes bool) {
-	fmt.Printf("[+] Running `%s` to bring down the containers with %s...\n", dockerCmd, yaml)
-	args := []string{"-f", yaml, "down"}
-	if volumes {
-		args = append(args, "--volumes")
-	}
-	CheckYamlExists(yaml)
-	downErr := RunCmd(dockerCmd, args)
+// If volumes is true, associated Docker volumes are also removed.
+func RunDockerComposeDown(rt Runtime, yaml string, volumes bool) error {
+	fmt.Printf("[+] Running `%s` to bring down the containers with %s...\n", rt.Name(), yaml)
+	if err := CheckYamlExists(yaml); err != nil {
+		return err
+	}
+	downErr := rt.Down(yaml, DownOptions{Volumes: volumes})
 	if downErr != nil {
-		log.Fatalf("Error trying to bring down the containers with %s: %v\n", yaml, downErr)
+		return fmt.Errorf("error trying to bring down the containers with %s: %w", yaml, downErr)
 	}
+	return nil
 }
 
 // RunDockerComposePull pulls the latest container images defined in the specified Docker Compose YAML file.
-// Exits fatally if the YAML file does not exist or if the pull operation fails.
-func RunDockerComposePull(yaml string) {
-	fmt.Printf("[+] Running `%s` to pull container images with %s...\n", dockerCmd, yaml)
-	CheckYamlExists(yaml)
-	startErr := RunCmd(dockerCmd, []string{"-f", yaml, "pull"})
-	if startErr != nil {
-		log.Fatalf("Error trying to pull the container images with %s: %v\n", yaml, startErr)
-	}
-}
-
-// FetchLogs fetches logs from the container with the specified "name" label ("containerName" parameter).
-func FetchLogs(containerName string, lines string) []string {
-	var logs []string
-	cli, err := client.New(client.FromEnv, client.WithAPIVersionNegotiation())
-	if err != nil {
-		log.Fatalf("Failed to get client in logs: %v", err)
+func RunDockerComposePull(rt Runtime, yaml string) error {
+	fmt.Printf("[+] Running `%s` to pull container images with %s...\n", rt.Name(), yaml)
+	if err := CheckYamlExists(yaml); err != nil {
+		return err
 	}
-	containers, err := cli.ContainerList(context.Background(), client.ContainerListOptions{})
-	if err != nil {
-		log.Fatalf("Failed to get container list: %v", err)
-	}
-	if len(containers.Items) > 0 {
-		for _, container := range containers.Items {
-			if container.Labels["name"] == containerName || containerName == "all" || container.Labels["name"] == "bhce_"+containerName {
-				logs = append(logs, fmt.Sprintf("\n*** Logs for `%s` ***\n\n", container.Labels["name"]))
-				reader, err := cli.ContainerLogs(context.Background(), container.ID, client.ContainerLogsOptions{
-					ShowStdout: true,
-					ShowStderr: true,
-					Tail:       lines,
-				})
-				if err != nil {
-					log.Fatalf("Failed to get container logs: %v", err)
-				}
-				defer reader.Close()
-				// Reference: https://medium.com/@dhanushgopinath/reading-docker-container-logs-with-golang-docker-engine-api-702233fac044
-				p := make([]byte, 8)
-				_, err = reader.Read(p)
-				for err == nil {
-					content := make([]byte, binary.BigEndian.Uint32(p[4:]))
-					reader.Read(content)
-					logs = append(logs, string(content))
-					_, err = reader.Read(p)
-				}
-			}
-		}
-
-		if len(logs) == 0 {
-			logs = append(logs, fmt.Sprintf("\n*** No logs found for requested container '%s' ***\n", containerName))
-		}
-	} else {
-		fmt.Println("Failed to find that container")
+	startErr := rt.Pull(yaml)
+	if startErr != nil {
+		return fmt.Errorf("error trying to pull the container images with %s: %w", yaml, startErr)
 	}
-	return logs
+	return nil
 }
 
-// GetRunning determines if the container with the specified "name" label ("containerName" parameter) is running.
-func GetRunning() Containers {
+// GetRunning returns the running BloodHound containers.
+func GetRunning(rt Runtime) (Containers, error) {
 	var running Containers
 
-	cli, err := client.New(client.FromEnv, client.WithAPIVersionNegotiation())
+	containers, err := rt.ListContainers(context.Background(), false)
 	if err != nil {
-		log.Fatalf("Failed to get client connection to Docker: %v", err)
+		return nil, err
 	}
-	containers, err := cli.ContainerList(context.Background(), client.ContainerListOptions{
-		All: false,
-	})
-	if err != nil {
-		log.Fatalf("Failed to get container list from Docker: %v", err)
-	}
-	if len(containers.Items) > 0 {
-		for _, container := range containers.Items {
-			if Contains(devImages, container.Labels["name"]) || Contains(prodImages, container.Labels["name"]) {
-				running = append(running, Container{
-					container.ID, container.Image, container.Status, container.Ports, container.Labels["name"],
-				})
-			}
+	for _, container := range containers {
+		if Contains(devImages, container.Labels["name"]) || Contains(prodImages, container.Labels["name"]) {
+			running = append(running, Container{
+				container.ID, container.Image, container.Status, container.Ports, container.Labels["name"],
+			})
 		}
 	}
 
-	return running
+	return running, nil
 }
 
 // ResetAdminPassword executes the "docker compose" commands to brings containers down and back up to reset the default
-// admin account for the specified YAML file ("yaml" parameter).
-func ResetAdminPassword(yaml string) {
-	RunDockerComposeDown(yaml, false)
+// admin account for the specified YAML file ("yaml" parameter). It waits up to "timeout" for the services to become
+// healthy before printing the new credentials.
+func ResetAdminPassword(rt Runtime, yaml string, timeout time.Duration) error {
+	if err := RunDockerComposeDown(rt, yaml, false); err != nil {
+		return err
+	}
 	bhEnv.Set("default_admin.password", GenerateRandomPassword(32, true))
-	WriteBloodHoundEnvironmentVariables()
+	if err := exposeAdminPassword(); err != nil {
+		return err
+	}
 	envErr := os.Setenv("bhe_recreate_default_admin", "true")
 	if envErr != nil {
-		log.Fatalf("Error setting the necessary `bhe_recreate_default_admin` environment variable: %v\n", envErr)
+		return fmt.Errorf("error setting the necessary `bhe_recreate_default_admin` environment variable: %w", envErr)
+	}
+	if err := RunDockerComposeUp(rt, yaml); err != nil {
+		return err
+	}
+	if err := WaitForStack(rt, timeout); err != nil {
+		return err
+	}
+	if err := concealAdminPassword(timeout); err != nil {
+		return err
 	}
-	RunDockerComposeUp(yaml)
+	printReadyMessage()
+	return nil
+}
+
+// exposeAdminPassword writes the configuration with the default admin password in the JSON config file, even if a
+// secret store is configured, because the BloodHound container reads the password from the file when it creates the
+// admin user.
+func exposeAdminPassword() error {
+	return writeBloodHoundConfig("default_admin.password")
+}
+
+// concealAdminPassword moves the default admin password back to the secret store once BloodHound is ready. If the
+// wait was skipped ("timeout" is zero), the password is left in the file for the container to read, and the next
+// command that writes the configuration removes it.
+func concealAdminPassword(timeout time.Duration) error {
+	if timeout <= 0 {
+		if bhEnv.GetString("secret_store") != secretStoreConfig {
+			fmt.Println("[*] The admin password stays in the JSON config file until the next bloodhound-cli command moves it to the secret store")
+		}
+		return nil
+	}
+	return WriteBloodHoundEnvironmentVariables()
+}
+
+// WaitForStack waits up to "timeout" for the BloodHound services to become healthy and returns an error wrapping
+// ErrUnhealthy if they do not. A timeout of zero skips the wait.
+func WaitForStack(rt Runtime, timeout time.Duration) error {
+	if timeout <= 0 {
+		return nil
+	}
+	return WaitForReady(rt, timeout)
+}
+
+// printReadyMessage prints the login credentials and the URL for the BloodHound UI.
+func printReadyMessage() {
 	fmt.Println("[+] BloodHound is ready to go!")
 	fmt.Printf("[+] You can log in as `%s` with this password: %s\n", bhEnv.GetString("default_admin.principal_name"), bhEnv.GetString("default_admin.password"))
 	fmt.Println("[+] You can get your admin password by running: bloodhound-cli config get default_password")
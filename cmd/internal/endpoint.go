@@ -0,0 +1,306 @@
+package internal
+
+// Functions for resolving the container engine endpoint shared by the Compose CLI and the Docker API client
+// The endpoint can come from a flag, the environment, the JSON config file, or a Docker context
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/moby/moby/client"
+)
+
+// Vars for locating Docker contexts
+var (
+	// Name of the context that uses the default socket or DOCKER_HOST
+	defaultContextName = "default"
+	// Placeholder URL for SSH connections; requests are sent through `docker system dial-stdio` on the remote host
+	sshClientHost = "http://docker.example.com"
+)
+
+// Endpoint is the container engine API endpoint used by both the Compose CLI subprocesses and the Docker API client.
+type Endpoint struct {
+	// Name of the Docker context the endpoint came from, if any
+	Context string `json:"context,omitempty"`
+	// URL of the engine (e.g., "unix:///var/run/docker.sock", "tcp://10.0.0.5:2376", or "ssh://user@host"); empty
+	// for the engine's default socket
+	Host string `json:"host,omitempty"`
+	// Directory with the "ca.pem", "cert.pem", and "key.pem" files for a context's TLS connection, if any
+	TLSDir string `json:"tls_dir,omitempty"`
+	// Where the endpoint was selected (e.g., "--host flag")
+	Source string `json:"source"`
+}
+
+// dockerContextMeta is the part of a Docker context's `meta.json` file used to find its endpoint.
+type dockerContextMeta struct {
+	Name      string `json:"Name"`
+	Endpoints map[string]struct {
+		Host string `json:"Host"`
+	} `json:"Endpoints"`
+}
+
+// ResolveEndpoint selects the container engine endpoint. The first of these that is set wins:
+//
+//   - The "hostFlag" parameter (the `--host` flag)
+//   - The "contextFlag" parameter (the `--context` flag)
+//   - The DOCKER_HOST environment variable
+//   - The DOCKER_CONTEXT environment variable
+//   - The `docker_host` config key
+//   - The current context in Docker's `config.json` file
+//
+// If none are set, the engine's default socket is used. Contexts are read from Docker's context store, so SSH and TLS
+// contexts created with `docker context create` work as they do with the Docker CLI.
+func ResolveEndpoint(hostFlag string, contextFlag string) (Endpoint, error) {
+	if hostFlag != "" && contextFlag != "" {
+		return Endpoint{}, fmt.Errorf("%w: use either `--host` or `--context`, not both", ErrInvalidConfig)
+	}
+	switch {
+	case hostFlag != "":
+		return hostEndpoint(hostFlag, "--host flag")
+	case contextFlag != "":
+		return contextEndpoint(contextFlag, "--context flag")
+	case os.Getenv("DOCKER_HOST") != "":
+		return hostEndpoint(os.Getenv("DOCKER_HOST"), "DOCKER_HOST environment variable")
+	case os.Getenv("DOCKER_CONTEXT") != "":
+		return contextEndpoint(os.Getenv("DOCKER_CONTEXT"), "DOCKER_CONTEXT environment variable")
+	case bhEnv.GetString("docker_host") != "":
+		return hostEndpoint(bhEnv.GetString("docker_host"), "docker_host config key")
+	}
+
+	current, err := currentDockerContext()
+	if err != nil {
+		return Endpoint{}, err
+	}
+	if current != "" && current != defaultContextName {
+		return contextEndpoint(current, "current Docker context")
+	}
+	return Endpoint{Source: "default"}, nil
+}
+
+// hostEndpoint validates a host URL and returns it as an Endpoint.
+func hostEndpoint(host string, source string) (Endpoint, error) {
+	if _, err := client.ParseHostURL(host); err != nil {
+		return Endpoint{}, fmt.Errorf("%w: the %s is not a valid host: %w", ErrInvalidConfig, source, err)
+	}
+	return Endpoint{Host: host, Source: source}, nil
+}
+
+// contextEndpoint looks up the named Docker context and returns its Docker endpoint.
+func contextEndpoint(name string, source string) (Endpoint, error) {
+	if name == defaultContextName {
+		return Endpoint{Context: name, Source: source}, nil
+	}
+
+	id := dockerContextId(name)
+	metaPath := filepath.Join(dockerConfigDir(), "contexts", "meta", id, "meta.json")
+	data, err := os.ReadFile(metaPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Endpoint{}, fmt.Errorf("%w: the Docker context `%s` from the %s does not exist", ErrInvalidConfig, name, source)
+		}
+		return Endpoint{}, fmt.Errorf("failed to read the Docker context `%s`: %w", name, err)
+	}
+	var meta dockerContextMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return Endpoint{}, fmt.Errorf("%w: failed to parse the Docker context `%s`: %w", ErrInvalidConfig, name, err)
+	}
+	docker, ok := meta.Endpoints["docker"]
+	if !ok || docker.Host == "" {
+		return Endpoint{}, fmt.Errorf("%w: the Docker context `%s` has no Docker endpoint", ErrInvalidConfig, name)
+	}
+
+	endpoint := Endpoint{Context: name, Host: docker.Host, Source: source}
+	tlsDir := filepath.Join(dockerConfigDir(), "contexts", "tls", id, "docker")
+	if DirExists(tlsDir) {
+		endpoint.TLSDir = tlsDir
+	}
+	return endpoint, nil
+}
+
+// dockerConfigDir returns the directory with Docker's `config.json` file and context store.
+func dockerConfigDir() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".docker"
+	}
+	return filepath.Join(home, ".docker")
+}
+
+// dockerContextId returns the directory name Docker uses for a context in its context store.
+func dockerContextId(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(sum[:])
+}
+
+// currentDockerContext returns the `currentContext` value from Docker's `config.json` file, or an empty string if the
+// file does not exist or does not set it.
+func currentDockerContext() (string, error) {
+	data, err := os.ReadFile(filepath.Join(dockerConfigDir(), "config.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read Docker's config file: %w", err)
+	}
+	var config struct {
+		CurrentContext string `json:"currentContext"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return "", fmt.Errorf("failed to parse Docker's config file: %w", err)
+	}
+	return config.CurrentContext, nil
+}
+
+// CommandEnv returns the environment variables that point a runtime's CLI (e.g., "docker" or "podman") at the
+// endpoint. Entries with empty values clear variables that would otherwise select a different endpoint.
+func (e Endpoint) CommandEnv(runtime string) []string {
+	if runtime == "podman" {
+		if e.Host == "" {
+			return nil
+		}
+		return []string{"CONTAINER_HOST=" + e.Host, "DOCKER_HOST=" + e.Host}
+	}
+	if e.Context != "" {
+		return []string{"DOCKER_HOST=", "DOCKER_CONTEXT=" + e.Context}
+	}
+	if e.Host != "" {
+		return []string{"DOCKER_HOST=" + e.Host, "DOCKER_CONTEXT="}
+	}
+	return nil
+}
+
+// ClientOptions returns the options for a Docker API client that connects to the endpoint. SSH hosts are reached by
+// running `docker system dial-stdio` on the remote host, like the Docker CLI does.
+func (e Endpoint) ClientOptions() ([]client.Opt, error) {
+	options := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+	if e.Context == defaultContextName && e.Host == "" {
+		// The default context ignores DOCKER_HOST, so reset the host FromEnv may have set
+		options = append(options, client.WithHost(client.DefaultDockerHost))
+	}
+	if e.Host == "" {
+		return options, nil
+	}
+
+	hostUrl, err := url.Parse(e.Host)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid host `%s`: %w", ErrInvalidConfig, e.Host, err)
+	}
+	if hostUrl.Scheme == "ssh" {
+		args, err := sshDialArgs(hostUrl)
+		if err != nil {
+			return nil, err
+		}
+		return append(options, client.WithHost(sshClientHost), client.WithDialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialCommand("ssh", args)
+		})), nil
+	}
+
+	options = append(options, client.WithHost(e.Host))
+	if e.TLSDir != "" {
+		var paths []string
+		for _, name := range []string{"ca.pem", "cert.pem", "key.pem"} {
+			path := filepath.Join(e.TLSDir, name)
+			if !FileExists(path) {
+				path = ""
+			}
+			paths = append(paths, path)
+		}
+		options = append(options, client.WithTLSClientConfig(paths[0], paths[1], paths[2]))
+	}
+	return options, nil
+}
+
+// sshDialArgs returns the arguments for running `docker system dial-stdio` over SSH on the host in an `ssh://` URL.
+func sshDialArgs(hostUrl *url.URL) ([]string, error) {
+	if hostUrl.Hostname() == "" {
+		return nil, fmt.Errorf("%w: the SSH host `%s` has no hostname", ErrInvalidConfig, hostUrl.String())
+	}
+	if hostUrl.Path != "" && hostUrl.Path != "/" {
+		return nil, fmt.Errorf("%w: the SSH host `%s` must not include a path", ErrInvalidConfig, hostUrl.String())
+	}
+	args := []string{"-o", "ConnectTimeout=30", "-T"}
+	if hostUrl.User != nil {
+		args = append(args, "-l", hostUrl.User.Username())
+	}
+	if hostUrl.Port() != "" {
+		args = append(args, "-p", hostUrl.Port())
+	}
+	return append(args, "--", hostUrl.Hostname(), "docker", "system", "dial-stdio"), nil
+}
+
+// commandConn is a net.Conn that reads from a command's stdout and writes to its stdin.
+type commandConn struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+}
+
+// dialCommand starts a command and returns a connection to its standard streams.
+func dialCommand(name string, args []string) (net.Conn, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start `%s`: %w", name, err)
+	}
+	return &commandConn{cmd: cmd, stdin: stdin, stdout: stdout}, nil
+}
+
+func (c *commandConn) Read(p []byte) (int, error) {
+	return c.stdout.Read(p)
+}
+
+func (c *commandConn) Write(p []byte) (int, error) {
+	return c.stdin.Write(p)
+}
+
+// Close closes the command's stdin and stops the command.
+func (c *commandConn) Close() error {
+	_ = c.stdin.Close()
+	if c.cmd.Process != nil {
+		_ = c.cmd.Process.Kill()
+	}
+	_ = c.cmd.Wait()
+	return nil
+}
+
+func (c *commandConn) LocalAddr() net.Addr {
+	return &net.UnixAddr{Name: "local", Net: "unix"}
+}
+
+func (c *commandConn) RemoteAddr() net.Addr {
+	return &net.UnixAddr{Name: c.cmd.Path, Net: "unix"}
+}
+
+// Deadlines are not supported on the command's pipes, so they are ignored.
+func (c *commandConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *commandConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *commandConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTestContext adds a Docker context with the host to the context store in the Docker config directory.
+func writeTestContext(t *testing.T, configDir string, name string, host string) {
+	metaDir := filepath.Join(configDir, "contexts", "meta", dockerContextId(name))
+	assert.NoError(t, os.MkdirAll(metaDir, 0700))
+	meta := `{"Name":"` + name + `","Metadata":{},"Endpoints":{"docker":{"Host":"` + host + `","SkipTLSVerify":false}}}`
+	assert.NoError(t, os.WriteFile(filepath.Join(metaDir, "meta.json"), []byte(meta), 0600))
+}
+
+// isolateEndpoint clears the endpoint settings from the environment and config and points DOCKER_CONFIG at a temporary
+// directory, which is returned.
+func isolateEndpoint(t *testing.T) string {
+	configDir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", configDir)
+	t.Setenv("DOCKER_HOST", "")
+	t.Setenv("DOCKER_CONTEXT", "")
+	original := bhEnv.GetString("docker_host")
+	bhEnv.Set("docker_host", "")
+	t.Cleanup(func() { bhEnv.Set("docker_host", original) })
+	return configDir
+}
+
+func TestResolveEndpointPrecedence(t *testing.T) {
+	configDir := isolateEndpoint(t)
+	writeTestContext(t, configDir, "remote", "ssh://admin@remote.example.com")
+
+	endpoint, err := ResolveEndpoint("", "")
+	assert.NoError(t, err)
+	assert.Equal(t, Endpoint{Source: "default"}, endpoint)
+
+	bhEnv.Set("docker_host", "tcp://10.0.0.5:2375")
+	endpoint, err = ResolveEndpoint("", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "tcp://10.0.0.5:2375", endpoint.Host)
+	assert.Equal(t, "docker_host config key", endpoint.Source)
+
+	t.Setenv("DOCKER_CONTEXT", "remote")
+	endpoint, err = ResolveEndpoint("", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "remote", endpoint.Context, "Expected DOCKER_CONTEXT to override the config key")
+
+	t.Setenv("DOCKER_HOST", "unix:///run/user/1000/docker.sock")
+	endpoint, err = ResolveEndpoint("", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "unix:///run/user/1000/docker.sock", endpoint.Host, "Expected DOCKER_HOST to override DOCKER_CONTEXT")
+
+	endpoint, err = ResolveEndpoint("", "remote")
+	assert.NoError(t, err)
+	assert.Equal(t, "ssh://admin@remote.example.com", endpoint.Host, "Expected `--context` to override DOCKER_HOST")
+
+	endpoint, err = ResolveEndpoint("tcp://127.0.0.1:2375", "")
+	assert.NoError(t, err)
+	assert.Equal(t, Endpoint{Host: "tcp://127.0.0.1:2375", Source: "--host flag"}, endpoint)
+
+	_, err = ResolveEndpoint("tcp://127.0.0.1:2375", "remote")
+	assert.True(t, errors.Is(err, ErrInvalidConfig), "Expected `--host` and `--context` together to be rejected")
+
+	_, err = ResolveEndpoint("not a host", "")
+	assert.True(t, errors.Is(err, ErrInvalidConfig), "Expected an invalid host to be rejected")
+}
+
+func TestResolveEndpointDockerContexts(t *testing.T) {
+	configDir := isolateEndpoint(t)
+	writeTestContext(t, configDir, "secure", "tcp://10.0.0.5:2376")
+	tlsDir := filepath.Join(configDir, "contexts", "tls", dockerContextId("secure"), "docker")
+	assert.NoError(t, os.MkdirAll(tlsDir, 0700))
+	assert.NoError(t, os.WriteFile(filepath.Join(configDir, "config.json"), []byte(`{"currentContext":"secure"}`), 0600))
+
+	endpoint, err := ResolveEndpoint("", "")
+	assert.NoError(t, err)
+	assert.Equal(t, Endpoint{Context: "secure", Host: "tcp://10.0.0.5:2376", TLSDir: tlsDir, Source: "current Docker context"}, endpoint)
+
+	endpoint, err = ResolveEndpoint("", "default")
+	assert.NoError(t, err)
+	assert.Equal(t, Endpoint{Context: "default", Source: "--context flag"}, endpoint, "Expected the default context to use the default socket")
+
+	_, err = ResolveEndpoint("", "missing")
+	assert.True(t, errors.Is(err, ErrInvalidConfig), "Expected a missing context to be reported")
+}
+
+func TestEndpointCommandEnv(t *testing.T) {
+	assert.Nil(t, Endpoint{}.CommandEnv("docker"))
+	assert.Equal(t, []string{"DOCKER_HOST=", "DOCKER_CONTEXT=remote"}, Endpoint{Context: "remote", Host: "ssh://remote"}.CommandEnv("docker"))
+	assert.Equal(t, []string{"DOCKER_HOST=ssh://remote", "DOCKER_CONTEXT="}, Endpoint{Host: "ssh://remote"}.CommandEnv("docker"))
+	assert.Equal(t, []string{"CONTAINER_HOST=ssh://remote", "DOCKER_HOST=ssh://remote"}, Endpoint{Host: "ssh://remote"}.CommandEnv("podman"))
+}
+
+func TestSshDialArgs(t *testing.T) {
+	hostUrl, _ := url.Parse("ssh://admin@remote.example.com:2222")
+	args, err := sshDialArgs(hostUrl)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-o", "ConnectTimeout=30", "-T", "-l", "admin", "-p", "2222", "--", "remote.example.com", "docker", "system", "dial-stdio"}, args)
+
+	hostUrl, _ = url.Parse("ssh://remote.example.com/var/run/docker.sock")
+	_, err = sshDialArgs(hostUrl)
+	assert.True(t, errors.Is(err, ErrInvalidConfig), "Expected an SSH host with a path to be rejected")
+}
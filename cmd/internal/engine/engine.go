@@ -0,0 +1,86 @@
+package engine
+
+// The container runtime interface the BloodHound CLI manages its services through
+// Only the interface and the types of its options live here, so the runtimes in the internal package and the fake in
+// the runtimetest package can both implement it without importing each other
+
+import (
+	"context"
+	"io"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/image"
+)
+
+// Runtime is a container engine that manages the BloodHound services described by a Docker Compose YAML file and
+// queries the BloodHound containers.
+type Runtime interface {
+	// Name returns the name of the runtime (e.g., "docker" or "podman")
+	Name() string
+	// Check returns an error if the runtime is not running or has no Compose support
+	Check() error
+
+	// Compose commands for the services in the YAML file; their output is printed as they run
+	Up(yaml string, services ...string) error
+	// Recreate creates or recreates only the listed services, leaving the services they depend on alone
+	Recreate(yaml string, services ...string) error
+	Down(yaml string, options DownOptions) error
+	Pull(yaml string) error
+	Build(yaml string) error
+	Create(yaml string) error
+	Start(yaml string, services ...string) error
+	Stop(yaml string, services ...string) error
+	Restart(yaml string) error
+	// Exec runs a command inside a running service with the provided standard streams
+	Exec(yaml string, service string, command []string, streams ExecStreams) error
+
+	// ListContainers returns the running containers, or every container if "all" is true
+	ListContainers(ctx context.Context, all bool) ([]container.Summary, error)
+	// InspectContainer returns the full details of a container
+	InspectContainer(ctx context.Context, id string) (container.InspectResponse, error)
+	// ContainerLogs writes a container's logs, with stdout and stderr combined, to the writer
+	ContainerLogs(ctx context.Context, id string, options ContainerLogOptions, out io.Writer) error
+	// CopyFromContainer writes a tar archive of a path inside a container to the writer
+	CopyFromContainer(ctx context.Context, id string, path string, out io.Writer) error
+	// CopyToContainer extracts a tar archive into a directory inside a container
+	CopyToContainer(ctx context.Context, id string, dir string, content io.Reader) error
+	// InspectImage returns the full details of a local image, including the registry digests it was pulled with
+	InspectImage(ctx context.Context, id string) (image.InspectResponse, error)
+	// TagImage adds a reference (e.g., "docker.io/specterops/bloodhound:latest") to a local image, moving the
+	// reference if another image has it
+	TagImage(ctx context.Context, image string, reference string) error
+}
+
+// DownOptions controls what Runtime.Down removes along with the containers.
+type DownOptions struct {
+	// Remove the named volumes
+	Volumes bool
+	// Remove every image used by the services
+	Images bool
+	// Remove containers for services that are no longer in the YAML file
+	RemoveOrphans bool
+}
+
+// ExecStreams holds the standard streams and extra environment for Runtime.Exec. A nil reader or writer is connected
+// to the null device.
+type ExecStreams struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	// "KEY=value" variables set for the command; only the names are passed on the command line, so values such as
+	// passwords do not show up in the process list
+	Env []string
+}
+
+// ContainerLogOptions selects the log entries Runtime.ContainerLogs reads.
+type ContainerLogOptions struct {
+	// Number of lines to read from the end of the logs (or "all")
+	Tail string
+	// Only read entries after or before these times (e.g., "2025-01-02T15:04:05Z" or a relative time like "10m")
+	Since string
+	Until string
+	// Include the timestamp Docker recorded for each entry
+	Timestamps bool
+	// Keep streaming new entries until the context is cancelled
+	Follow bool
+}
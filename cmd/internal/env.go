@@ -4,19 +4,23 @@ package internal
 // configuration of the BloodHound containers.
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/spf13/viper"
-	"log"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 )
 
 // Configuration is a custom type for storing configuration values as Key:Val pairs.
 type Configuration struct {
-	Key string
-	Val string
+	Key string `json:"key"`
+	Val string `json:"value"`
 }
 
 // Configurations is a custom type for storing `Configuration` values
@@ -40,151 +44,555 @@ func (c Configurations) Swap(i, j int) {
 // Initialize the environment variables.
 var bhEnv = viper.New()
 
+// composeVariable maps a config key to the environment variable the Docker YAML files read it from.
+type composeVariable struct {
+	Key    string
+	EnvVar string
+	// Value the production YAML file uses when the variable is not set
+	Default string
+}
+
+// Well-known password the Docker YAML files use for the databases when no secret is set
+var defaultDatabaseSecret = "bloodhoundcommunityedition"
+
+// Config keys passed to every Compose command as environment variables
+var composeVariables = []composeVariable{
+	{"database.postgres_user", "POSTGRES_USER", "bloodhound"},
+	{"database.postgres_password", "POSTGRES_PASSWORD", defaultDatabaseSecret},
+	{"database.postgres_db", "POSTGRES_DB", "bloodhound"},
+	{"neo4j.user", "NEO4J_USER", "neo4j"},
+	{"neo4j.secret", "NEO4J_SECRET", defaultDatabaseSecret},
+	{"bloodhound.host", "BLOODHOUND_HOST", "127.0.0.1"},
+	{"bloodhound.port", "BLOODHOUND_PORT", "8080"},
+	{"bloodhound.tag", "BLOODHOUND_TAG", "latest"},
+}
+
+// Environment variable Compose reads the project name from instead of deriving it from the YAML file's directory
+var composeProjectVar = "COMPOSE_PROJECT_NAME"
+
+// Config keys for the database secrets generated by EnsureComposeSecrets and RotateSecrets
+var composeSecretKeys = []string{"database.postgres_password", "neo4j.secret"}
+
+// Config keys that pin the image versions, which `upgrade` records and `config reset` keeps
+var pinnedVersionKeys = []string{"bloodhound.tag"}
+
+// Config keys for the database credentials, which must match the credentials the databases were created with
+var databaseCredentialKeys = []string{"database.postgres_user", "database.postgres_password", "database.postgres_db", "neo4j.user", "neo4j.secret"}
+
 // Set sane defaults for a basic BloodHound deployment.
-// setBloodHoundConfigDefaultValues sets default configuration values for BloodHound, including version, admin credentials, server settings, logging, TLS paths, and directory locations. Defaults are intended for development environments.
+// setBloodHoundConfigDefaultValues sets the default configuration values from the schema (see schema.go), including version, admin credentials, server settings, logging, TLS paths, and directory locations. Defaults are intended for development environments.
 func setBloodHoundConfigDefaultValues() {
-	bhEnv.SetDefault("version", 1)
-
-	// Initial user setup
-	bhEnv.SetDefault("default_admin.principal_name", "admin")
+	for _, entry := range configSchema {
+		if entry.Default != nil {
+			bhEnv.SetDefault(entry.Key, entry.Default)
+		}
+	}
+	// Defaults that are not fixed values
 	bhEnv.SetDefault("default_admin.password", GenerateRandomPassword(32, true))
+	bhEnv.SetDefault("config_directory", GetDefaultConfigDir())
 
-	// Base config
-	bhEnv.SetDefault("bind_addr", "0.0.0.0:8080")
-	bhEnv.SetDefault("metrics_port", ":2112")
-	bhEnv.SetDefault("root_url", "http://127.0.0.1:8080")
-	bhEnv.SetDefault("work_dir", "/opt/bloodhound/work")
-	bhEnv.SetDefault("log_level", "INFO")
-	bhEnv.SetDefault("log_path", "bloodhound.log")
-	bhEnv.SetDefault("collectors_base_path", "/etc/bloodhound/collectors")
-	bhEnv.SetDefault("RecreateDefaultAdmin", "false")
-
-	// TLS config
-	bhEnv.SetDefault("tls.cert_file", "")
-	bhEnv.SetDefault("tls.key_file", "")
+	// The `database`, `neo4j`, and `bloodhound` keys in composeVariables have no defaults on purpose, so the YAML file's
+	// defaults and any `.env` file still apply until a value is set (see ComposeEnvironment)
 
 	// Set some helpful aliases for common settings
-	bhEnv.RegisterAlias("default_password", "default_admin.password")
+	for alias, key := range configAliases {
+		bhEnv.RegisterAlias(alias, key)
+	}
+}
 
-	bhEnv.SetDefault("config_directory", GetDefaultConfigDir())
+// WriteBloodHoundEnvironmentVariables writes the current BloodHound configuration to the JSON config file, ensuring the file exists before writing. Secret values are saved to the secret store instead if one is configured. Returns an error if writing fails.
+func WriteBloodHoundEnvironmentVariables() error {
+	return writeBloodHoundConfig()
 }
 
-// WriteBloodHoundEnvironmentVariables writes the current BloodHound configuration to the JSON config file, ensuring the file exists before writing. Logs a fatal error and exits if writing fails.
-func WriteBloodHoundEnvironmentVariables() {
-	checkJsonFileExistsAndCreate()
-	err := bhEnv.WriteConfig()
+// writeBloodHoundConfig writes the current BloodHound configuration to the JSON config file like
+// WriteBloodHoundEnvironmentVariables, but leaves the secrets in "keep" in the file even if a secret store is configured.
+func writeBloodHoundConfig(keep ...string) error {
+	if err := checkJsonFileExistsAndCreate(); err != nil {
+		return err
+	}
+	// Aliases are resolved when the file is read, so only the keys they refer to are written
+	settings := currentSettings()
+	if err := saveSecrets(settings, keep...); err != nil {
+		return err
+	}
+	configJSON, err := json.MarshalIndent(settings, "", "  ")
 	if err != nil {
-		log.Fatalf("Error while writing the JSON config file: %s", err)
+		return fmt.Errorf("failed to marshal configuration to JSON: %w", err)
+	}
+	// The mode only applies to a new file, so an existing file keeps its permissions
+	if err := os.WriteFile(filepath.Join(GetBloodHoundDir(), "bloodhound.config.json"), configJSON, configFileMode()); err != nil {
+		return fmt.Errorf("error while writing the JSON config file: %w", err)
+	}
+	return nil
+}
+
+// removeEnvOverrides replaces the values AutomaticEnv read from environment variables (e.g., ROOT_URL for `root_url`)
+// with the values they hide, so a variable set in the shell is not saved to the JSON config file. The settings are
+// changed in place.
+func removeEnvOverrides(settings map[string]interface{}) {
+	for _, key := range flattenSettings(settings) {
+		name := strings.ToUpper(key)
+		value := os.Getenv(name)
+		// Viper ignores empty environment variables
+		if value == "" {
+			continue
+		}
+		// Viper reads the environment on every lookup, so the value without the variable is the one it hides
+		os.Unsetenv(name)
+		stored := bhEnv.Get(key)
+		os.Setenv(name, value)
+		if stored == nil {
+			deleteNestedValue(settings, key)
+		} else {
+			setNestedValue(settings, key, stored)
+		}
 	}
 }
 
 // checkJsonFileExistsAndCreate ensures that the BloodHound JSON configuration file exists in the designated directory
-// with proper permissions, creating the file and config directory if necessary. If the file or directory cannot be
-// created or permissions are insufficient, the function logs a fatal error and terminates the program.
-func checkJsonFileExistsAndCreate() {
+// with proper permissions, creating the file and config directory if necessary. Returns an error if the file or
+// directory cannot be created or permissions are insufficient.
+func checkJsonFileExistsAndCreate() (err error) {
 	if !FileExists(filepath.Join(GetBloodHoundDir(), "bloodhound.config.json")) {
 		configErr := MakeConfigDir()
 		if configErr != nil {
-			log.Fatalf("Error creating config directory: %s", configErr)
+			return fmt.Errorf("error creating config directory: %w", configErr)
 		}
 
-		file, err := os.Create(filepath.Join(GetBloodHoundDir(), "bloodhound.config.json"))
-
-		if err != nil {
-			log.Fatalf("The JSON config file doesn't exist and couldn't be created.")
+		file, createErr := os.OpenFile(filepath.Join(GetBloodHoundDir(), "bloodhound.config.json"), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, configFileMode())
+		if createErr != nil {
+			return fmt.Errorf("the JSON config file doesn't exist and couldn't be created: %w", createErr)
 		}
 
 		defer func(file *os.File) {
-			err := file.Close()
-			if err != nil {
-				log.Fatalf("Failed to close file: %v", err)
+			if closeErr := file.Close(); closeErr != nil && err == nil {
+				err = fmt.Errorf("failed to close file: %w", closeErr)
 			}
 		}(file)
 
 		emptyJSON := make(map[string]interface{})
 		encoder := json.NewEncoder(file)
 		if err := encoder.Encode(emptyJSON); err != nil {
-			log.Fatalf("Failed to write JSON to file: %v", err)
+			return fmt.Errorf("failed to write JSON to file: %w", err)
 		}
 	} else {
 		permCheck, permErr := CheckConfigDir(GetBloodHoundDir())
 		if permErr != nil {
-			log.Fatalf("Error checking the permissions on the config directory: %s", permErr)
+			return fmt.Errorf("error checking the permissions on the config directory: %w", permErr)
 		}
 
 		if !permCheck {
-			log.Fatalf("The permissions set on the config directory, %s, must be at least allow read and write for the current user (e.g., 0600).", GetBloodHoundDir())
+			return fmt.Errorf("%w: the permissions set on the config directory, %s, must at least allow read and write for the current user (e.g., 0600)", ErrInvalidConfig, GetBloodHoundDir())
 		}
 	}
+	return nil
 }
 
 // ParseBloodHoundEnvironmentVariables initializes default configuration values, ensures the BloodHound config file and
 // directory exist with correct permissions, loads configuration from the JSON file and environment variables, and
-// writes the final configuration back to the file. The function terminates the program on critical errors.
-func ParseBloodHoundEnvironmentVariables() {
+// writes the final configuration back to the file. Files written by older versions are migrated to the current layout
+// first (see migrate.go). Returns an error wrapping ErrInvalidConfig if the JSON config file cannot be read or parsed.
+func ParseBloodHoundEnvironmentVariables() error {
 	setBloodHoundConfigDefaultValues()
 	bhEnv.SetConfigName("bloodhound.config.json")
 	bhEnv.SetConfigType("json")
 	bhEnv.AddConfigPath(GetBloodHoundDir())
 	bhEnv.AutomaticEnv()
 	// Check if the expected JSON file exists
-	checkJsonFileExistsAndCreate()
+	if err := checkJsonFileExistsAndCreate(); err != nil {
+		return err
+	}
+	// Upgrade files written by older versions before reading them
+	readDir := GetBloodHoundDir()
+	if err := migrateConfigFileWithNotice(filepath.Join(readDir, "bloodhound.config.json")); err != nil {
+		return err
+	}
 	// Try reading the env file
 	if err := bhEnv.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			log.Fatalf("Error while reading in the JSON config file: %s", err)
-		} else {
-			log.Fatalf("Error while parsing the JSON config file: %s", err)
+			return fmt.Errorf("%w: error while reading in the JSON config file: %w", ErrInvalidConfig, err)
 		}
+		return fmt.Errorf("%w: error while parsing the JSON config file: %w", ErrInvalidConfig, err)
+	}
+	// After `config move-dir`, the file only points to the config directory
+	if err := followConfigPointer(readDir); err != nil {
+		return err
+	}
+	if err := loadSecrets(); err != nil {
+		return err
+	}
+	return WriteBloodHoundEnvironmentVariables()
+}
+
+// GetConfigSettings retrieves all values from the JSON config file as a nested map. Secret values are replaced with a
+// placeholder unless "showSecrets" is true.
+func GetConfigSettings(showSecrets bool) map[string]interface{} {
+	if !showSecrets {
+		return RedactSecrets(bhEnv.AllSettings())
 	}
-	WriteBloodHoundEnvironmentVariables()
+	return bhEnv.AllSettings()
 }
 
-// GetConfigAll retrieves all values from the JSON config configuration file.
-func GetConfigAll() []byte {
-	configuration := bhEnv.AllSettings()
+// GetConfigAll retrieves all values from the JSON config configuration file. Secret values are replaced with a
+// placeholder unless "showSecrets" is true.
+func GetConfigAll(showSecrets bool) ([]byte, error) {
+	configuration := GetConfigSettings(showSecrets)
 	configJSON, err := json.MarshalIndent(configuration, "", "  ")
 	if err != nil {
-		log.Fatalf("Failed to marshal configuration to JSON: %v", err)
+		return nil, fmt.Errorf("failed to marshal configuration to JSON: %w", err)
 	}
 
-	return configJSON
+	return configJSON, nil
 }
 
-// GetConfig retrieves the specified values from the JSON config file.
-func GetConfig(args []string) Configurations {
+// GetConfig retrieves the specified values from the JSON config file. Returns an error wrapping ErrConfigKeyNotFound
+// if any of the keys has no value.
+func GetConfig(args []string) (Configurations, error) {
 	var values Configurations
 	for i := 0; i < len(args[0:]); i++ {
 		setting := strings.ToLower(args[i])
 		val := bhEnv.GetString(setting)
 		if val == "" {
-			log.Fatalf("Config variable `%s` not found.", setting)
-		} else {
-			values = append(values, Configuration{setting, val})
+			return nil, fmt.Errorf("%w: `%s`", ErrConfigKeyNotFound, setting)
 		}
+		values = append(values, Configuration{setting, val})
 	}
 
 	sort.Sort(values)
 
-	return values
+	return values, nil
 }
 
-// SetConfig sets the value of the specified key in the JSON config file.
-func SetConfig(key string, value string) {
-	// We do not support changing the `config_directory` at this time. We can explore that at a later time.
-	// Allowing it to be changed will cause the new directory to be created with a blank config file, so we disable the
-	// option here to avoid any confusion.
-	if strings.ToLower(key) == "config_directory" {
-		log.Fatalf("The config directory cannot be changed here, but you can use `--file` a different Docker YAML file to use.")
+// SetConfig sets the value of the specified key in the JSON config file after validating it against the schema (see
+// schema.go). Unknown keys are rejected unless "force" is true, in which case "true" and "false" are saved as booleans
+// and other values as strings. Returns an error wrapping ErrInvalidConfig if the key or value is not valid.
+func SetConfig(key string, value string, force bool) error {
+	entry, known := LookupConfigKey(key)
+	if !known {
+		if !force {
+			return fmt.Errorf("%w: `%s` is not a known config key; run `bloodhound-cli config describe` to list the keys or use `--force` to set it anyway", ErrInvalidConfig, key)
+		}
+		if strings.ToLower(value) == "true" {
+			bhEnv.Set(key, true)
+		} else if strings.ToLower(value) == "false" {
+			bhEnv.Set(key, false)
+		} else {
+			bhEnv.Set(key, value)
+		}
+		return WriteBloodHoundEnvironmentVariables()
 	}
 
-	if strings.ToLower(value) == "true" {
-		bhEnv.Set(key, true)
-	} else if strings.ToLower(value) == "false" {
-		bhEnv.Set(key, false)
-	} else {
-		bhEnv.Set(key, value)
+	// Changing the `config_directory` here would start the new directory with a blank config file, so the files are
+	// moved with MoveConfigDir instead
+	if entry.Key == "config_directory" {
+		return fmt.Errorf("%w: use `config move-dir` to move the config directory, or use `--file` to choose a different Docker YAML file", ErrInvalidConfig)
+	}
+	if entry.ReadOnly {
+		return fmt.Errorf("%w: `%s` is managed by BloodHound CLI and cannot be changed", ErrInvalidConfig, entry.Key)
+	}
+	parsed, err := entry.Parse(value)
+	if err != nil {
+		return err
+	}
+	bhEnv.Set(entry.Key, parsed)
+
+	return WriteBloodHoundEnvironmentVariables()
+}
+
+// IsDatabaseCredential reports whether "key" is one of the database credentials, which must match the credentials the
+// databases were created with.
+func IsDatabaseCredential(key string) bool {
+	entry, known := LookupConfigKey(key)
+	return known && Contains(databaseCredentialKeys, entry.Key)
+}
+
+// CheckCredentialChange returns an error wrapping ErrInvalidConfig if "key" is a database credential and BloodHound
+// containers exist, because the databases keep the credentials they were created with and changing only the config
+// would lock BloodHound out of them.
+func CheckCredentialChange(rt Runtime, key string) error {
+	if !IsDatabaseCredential(key) {
+		return nil
 	}
+	entry, _ := LookupConfigKey(key)
+	images, err := GetBloodHoundImages(rt)
+	if err != nil {
+		return fmt.Errorf("failed to check for an existing deployment: %w", err)
+	}
+	if len(images) > 0 {
+		return fmt.Errorf("%w: `%s` must match the existing databases, so it cannot be changed while BloodHound containers exist; run `bloodhound-cli rotate-secrets` to change the database passwords", ErrInvalidConfig, entry.Key)
+	}
+	return nil
+}
+
+// UnsetConfig removes the specified key from the JSON config file, so a key with a default goes back to its default
+// and other keys (e.g., one set with `--force`) are removed. Returns an error wrapping ErrConfigKeyNotFound if the key
+// is not in the file and an error wrapping ErrInvalidConfig for keys that cannot be removed.
+func UnsetConfig(key string) error {
+	name := strings.ToLower(key)
+	if entry, known := LookupConfigKey(key); known {
+		if err := checkResettable(entry); err != nil {
+			return err
+		}
+		name = entry.Key
+	}
+	settings := currentSettings()
+	value, ok := getNestedValue(settings, name)
+	if !ok {
+		return fmt.Errorf("%w: `%s`", ErrConfigKeyNotFound, key)
+	}
+	if _, isSection := value.(map[string]interface{}); isSection {
+		return fmt.Errorf("%w: `%s` is a section, so unset its keys one at a time", ErrInvalidConfig, key)
+	}
+	deleteNestedValue(settings, name)
+	return reloadConfig(settings)
+}
+
+// ResetConfig reverts the specified keys to their defaults. Without keys, every key is reverted and unknown keys are
+// removed after the user confirms, except for the read-only keys, the pinned image tag, and the database credentials
+// and other secrets, which must match the existing deployment. Returns an error wrapping ErrInvalidConfig for keys that
+// cannot be reset and ErrCancelled if the user declines.
+func ResetConfig(keys []string) error {
+	settings := currentSettings()
+	if len(keys) == 0 {
+		c := AskForConfirmation("[!] This reverts every config value except the config directory, the pinned image tag, the database credentials, and the secrets to its default and removes unknown keys. Are you sure you want to continue?")
+		if !c {
+			return ErrCancelled
+		}
+		return reloadConfig(preservedSettings(settings))
+	}
+
+	for _, key := range keys {
+		entry, known := LookupConfigKey(key)
+		if !known {
+			return fmt.Errorf("%w: `%s` is not a known config key, so it has no default; use `config unset` to remove it", ErrInvalidConfig, key)
+		}
+		if err := checkResettable(entry); err != nil {
+			return err
+		}
+		deleteNestedValue(settings, entry.Key)
+	}
+	return reloadConfig(settings)
+}
+
+// preservedSettings returns the values of the read-only keys, the database credentials, the secrets, and the pinned
+// image tag from nested settings, which must match the existing deployment when the rest of the configuration is
+// replaced.
+func preservedSettings(settings map[string]interface{}) map[string]interface{} {
+	kept := map[string]interface{}{}
+	for _, entry := range configSchema {
+		if !entry.ReadOnly && !entry.Secret && !Contains(databaseCredentialKeys, entry.Key) && !Contains(pinnedVersionKeys, entry.Key) {
+			continue
+		}
+		if value, ok := getNestedValue(settings, entry.Key); ok {
+			setNestedValue(kept, entry.Key, value)
+		}
+	}
+	return kept
+}
+
+// checkResettable returns an error wrapping ErrInvalidConfig if the key cannot be unset or reset because it is managed
+// by BloodHound CLI or holds a secret.
+func checkResettable(entry ConfigKey) error {
+	if entry.ReadOnly {
+		return fmt.Errorf("%w: `%s` is managed by BloodHound CLI and cannot be changed", ErrInvalidConfig, entry.Key)
+	}
+	// A new admin password only applies after `resetpwd`, and new database passwords do not match the databases
+	if entry.Secret {
+		return fmt.Errorf("%w: `%s` is a secret, so use `resetpwd` or `rotate-secrets` to change it", ErrInvalidConfig, entry.Key)
+	}
+	return nil
+}
+
+// currentSettings returns the current configuration as nested settings without the aliases or the values read from
+// environment variables.
+func currentSettings() map[string]interface{} {
+	settings := effectiveSettings()
+	removeEnvOverrides(settings)
+	return settings
+}
+
+// effectiveSettings returns the values the commands use as nested settings without the aliases.
+func effectiveSettings() map[string]interface{} {
+	settings := bhEnv.AllSettings()
+	for alias := range configAliases {
+		delete(settings, alias)
+	}
+	return settings
+}
+
+// reloadConfig replaces the configuration with the nested settings, as if they were read from the JSON config file,
+// and writes them to the file. Keys missing from the settings go back to their defaults, except for secrets held by a
+// secret store, and values set since the file was read are dropped so they cannot hide a removed key.
+func reloadConfig(settings map[string]interface{}) error {
+	content, err := json.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal configuration to JSON: %w", err)
+	}
+	bhEnv = viper.New()
+	setBloodHoundConfigDefaultValues()
+	bhEnv.SetConfigType("json")
+	bhEnv.AutomaticEnv()
+	if err := bhEnv.ReadConfig(bytes.NewReader(content)); err != nil {
+		return fmt.Errorf("%w: error while reading the new configuration: %w", ErrInvalidConfig, err)
+	}
+	// Secrets kept in a secret store are not in the settings, and the generated defaults would replace them
+	if err := loadSecrets(); err != nil {
+		return err
+	}
+	return WriteBloodHoundEnvironmentVariables()
+}
+
+// RestoreConfig replaces the current configuration with the values from the JSON config file at the specified path
+// and writes them to the JSON config file. The current `config_directory` value is kept because the restored file may
+// come from a system with a different directory layout, and files from older versions are migrated to the current
+// layout.
+func RestoreConfig(path string) error {
+	configDir := GetBloodHoundDir()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var settings map[string]interface{}
+	if err := json.Unmarshal(content, &settings); err != nil {
+		return fmt.Errorf("%w: error while parsing the restored JSON config file: %w", ErrInvalidConfig, err)
+	}
+	if _, err := migrateConfig(settings); err != nil {
+		return err
+	}
+	migrated, err := json.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal configuration to JSON: %w", err)
+	}
+	if err := bhEnv.ReadConfig(bytes.NewReader(migrated)); err != nil {
+		return err
+	}
+	bhEnv.Set("config_directory", configDir)
+	// Secrets missing from the restored file come from the secret store
+	if err := loadSecrets(); err != nil {
+		return err
+	}
+	return WriteBloodHoundEnvironmentVariables()
+}
+
+// ComposeEnvironment returns the "KEY=value" environment variables for the config keys in composeVariables that have
+// a value, plus COMPOSE_PROJECT_NAME for the `compose_project` config value. Variables already set in the CLI's
+// environment are left out, so they still take precedence over the config.
+func ComposeEnvironment() []string {
+	var env []string
+	if project := bhEnv.GetString("compose_project"); project != "" {
+		if _, ok := os.LookupEnv(composeProjectVar); !ok {
+			env = append(env, composeProjectVar+"="+project)
+		}
+	}
+	for _, variable := range composeVariables {
+		value := bhEnv.GetString(variable.Key)
+		if value == "" {
+			continue
+		}
+		if _, ok := os.LookupEnv(variable.EnvVar); ok {
+			continue
+		}
+		env = append(env, variable.EnvVar+"="+value)
+	}
+	return env
+}
+
+// composeValue returns the value Compose uses for a config key in composeVariables: the CLI's environment variable,
+// then the config value, then the production YAML file's default. Values from a `.env` file are not considered.
+func composeValue(key string) string {
+	for _, variable := range composeVariables {
+		if variable.Key != key {
+			continue
+		}
+		if value, ok := os.LookupEnv(variable.EnvVar); ok {
+			return value
+		}
+		if value := bhEnv.GetString(key); value != "" {
+			return value
+		}
+		return variable.Default
+	}
+	return bhEnv.GetString(key)
+}
+
+// composeEnvVar returns the environment variable for a config key in composeVariables.
+func composeEnvVar(key string) string {
+	for _, variable := range composeVariables {
+		if variable.Key == key {
+			return variable.EnvVar
+		}
+	}
+	return ""
+}
+
+// EnsureComposeSecrets generates random values for the database secrets that are not set yet and writes them to the
+// JSON config file. Existing deployments keep the secrets their databases were created with, so nothing is generated
+// if any BloodHound containers already exist.
+//
+// If "secure" is true, secrets set to the well-known default password are replaced too, an existing deployment that
+// still needs secrets is an error (use RotateSecrets instead), the Neo4j ports are no longer published, and the JSON
+// config file is restricted to the current user.
+func EnsureComposeSecrets(rt Runtime, secure bool) error {
+	changed, err := generateComposeSecrets(rt, secure)
+	if err != nil {
+		return err
+	}
+	if secure && bhEnv.GetBool("neo4j.publish_ports") {
+		fmt.Println("[+] Turning off the published Neo4j ports...")
+		bhEnv.Set("neo4j.publish_ports", false)
+		changed = true
+	}
+	if changed {
+		if err := WriteBloodHoundEnvironmentVariables(); err != nil {
+			return err
+		}
+	}
+	if secure {
+		configFile := filepath.Join(GetBloodHoundDir(), "bloodhound.config.json")
+		if err := os.Chmod(configFile, 0600); err != nil {
+			return fmt.Errorf("failed to restrict the permissions on the JSON config file: %w", err)
+		}
+	}
+	return nil
+}
+
+// generateComposeSecrets sets random values for the unset database secrets, and for secrets set to the well-known
+// default if "secure" is true, and reports whether it set any.
+func generateComposeSecrets(rt Runtime, secure bool) (bool, error) {
+	var missing []string
+	for _, key := range composeSecretKeys {
+		value := bhEnv.GetString(key)
+		if value == "" || (secure && value == defaultDatabaseSecret) {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return false, nil
+	}
+
+	containers, err := rt.ListContainers(context.Background(), true)
+	if err != nil {
+		return false, fmt.Errorf("failed to check for an existing deployment: %w", err)
+	}
+	for _, c := range containers {
+		if Contains(prodImages, c.Labels["name"]) || Contains(devImages, c.Labels["name"]) {
+			if secure {
+				return false, errors.New("found existing BloodHound containers that use the default database passwords; run `bloodhound-cli rotate-secrets` to change them")
+			}
+			fmt.Println("[*] Found existing BloodHound containers, so the current database passwords will be kept")
+			return false, nil
+		}
+	}
+
+	fmt.Println("[+] Generating random passwords for the databases...")
+	for _, key := range missing {
+		bhEnv.Set(key, GenerateRandomPassword(32, true))
+	}
+	return true, nil
+}
 
-	WriteBloodHoundEnvironmentVariables()
+// GetWaitTimeout returns the `wait_timeout` config value, the default for the `--timeout` flags.
+func GetWaitTimeout() time.Duration {
+	return bhEnv.GetDuration("wait_timeout")
 }
@@ -2,6 +2,8 @@ package internal
 
 import (
 	"encoding/json"
+	"errors"
+	"github.com/SpecterOps/BloodHound_CLI/cmd/internal/runtimetest"
 	"github.com/stretchr/testify/assert"
 	"log"
 	"path/filepath"
@@ -10,7 +12,10 @@ import (
 
 // CountConfigProperties returns the number of keys in the JSON configuration file.
 func CountConfigProperties() int {
-	config := GetConfigAll()
+	config, err := GetConfigAll(true)
+	if err != nil {
+		log.Fatalf("Failed to get configuration: %v", err)
+	}
 	var configMap map[string]interface{}
 	if err := json.Unmarshal(config, &configMap); err != nil {
 		log.Fatalf("Failed to unmarshal configuration: %v", err)
@@ -22,7 +27,7 @@ func TestBloodHoundEnvironmentVariables(t *testing.T) {
 	//defer quietTests()()
 
 	// Test parsing values and writing to the JSON config file
-	ParseBloodHoundEnvironmentVariables()
+	assert.NoError(t, ParseBloodHoundEnvironmentVariables(), "Expected `ParseBloodHoundEnvironmentVariables()` to return no error")
 	envFile := filepath.Join(GetBloodHoundDir(), "bloodhound.config.json")
 
 	assert.True(t, FileExists(envFile), "Expected the JSON file to exist")
@@ -31,7 +36,8 @@ func TestBloodHoundEnvironmentVariables(t *testing.T) {
 	assert.Equal(t, bhEnv.Get("default_admin.principal_name"), "admin", "Value of `principal_name` should be `admin`")
 
 	// Test ``GetConfig()``
-	format := GetConfig([]string{"collectors_base_path", "default_admin.principal_name"})
+	format, err := GetConfig([]string{"collectors_base_path", "default_admin.principal_name"})
+	assert.NoError(t, err, "`GetConfig()` with valid variables should return no error")
 	assert.Equal(
 		t,
 		format,
@@ -40,10 +46,132 @@ func TestBloodHoundEnvironmentVariables(t *testing.T) {
 	)
 	assert.Equal(t, len(format), 2, "`GetConfig()` with two valid variables should return a two values")
 
+	// Test ``GetConfig()`` with a missing variable
+	_, err = GetConfig([]string{"not_a_real_setting"})
+	assert.True(t, errors.Is(err, ErrConfigKeyNotFound), "`GetConfig()` with a missing variable should return `ErrConfigKeyNotFound`")
+
 	// Test ``GetConfigAll()``
-	assert.Equal(t, 13, CountConfigProperties(), "`GetConfigAll()` should return all values")
+	assert.Equal(t, 17, CountConfigProperties(), "`GetConfigAll()` should return all values")
 
 	// Test ``SetConfig()``
-	SetConfig("log_path", "bhce.log")
+	assert.NoError(t, SetConfig("log_path", "bhce.log", false), "`SetConfig()` should return no error")
 	assert.Equal(t, bhEnv.GetString("log_path"), "bhce.log", "New value of `log_path` should be `bhce.log`")
+	err = SetConfig("config_directory", "/tmp", false)
+	assert.True(t, errors.Is(err, ErrInvalidConfig), "`SetConfig()` should refuse to change `config_directory`")
+}
+
+// setTestConfig sets config values for a test and restores the original values when the test finishes.
+func setTestConfig(t *testing.T, values map[string]string) {
+	for key, value := range values {
+		// Restoring an unset key to nil leaves it unset
+		original := bhEnv.Get(key)
+		bhEnv.Set(key, value)
+		t.Cleanup(func() { bhEnv.Set(key, original) })
+	}
+}
+
+func TestComposeEnvironment(t *testing.T) {
+	t.Setenv("NEO4J_SECRET", "from-the-shell")
+	setTestConfig(t, map[string]string{
+		"database.postgres_password": "pg-secret",
+		"database.postgres_user":     "",
+		"neo4j.secret":               "neo4j-secret",
+		"bloodhound.port":            "8443",
+	})
+
+	env := ComposeEnvironment()
+	assert.Contains(t, env, "POSTGRES_PASSWORD=pg-secret")
+	assert.Contains(t, env, "BLOODHOUND_PORT=8443")
+	assert.NotContains(t, env, "NEO4J_SECRET=neo4j-secret", "Expected the shell's value to take precedence")
+	for _, entry := range env {
+		assert.NotEqual(t, "POSTGRES_USER=", entry, "Expected unset keys to be left out")
+	}
+}
+
+func TestGenerateComposeSecrets(t *testing.T) {
+	defer quietTests()()
+	setTestConfig(t, map[string]string{"database.postgres_password": "", "neo4j.secret": "kept"})
+
+	generated, err := generateComposeSecrets(newFakeStack(), false)
+	assert.NoError(t, err)
+	assert.False(t, generated, "Expected an existing deployment to keep its passwords")
+	assert.Empty(t, bhEnv.GetString("database.postgres_password"))
+
+	generated, err = generateComposeSecrets(runtimetest.NewFakeRuntime(), false)
+	assert.NoError(t, err)
+	assert.True(t, generated)
+	assert.Len(t, bhEnv.GetString("database.postgres_password"), 32)
+	assert.Equal(t, "kept", bhEnv.GetString("neo4j.secret"), "Expected existing secrets to be kept")
+
+	generated, err = generateComposeSecrets(runtimetest.NewFakeRuntime(), false)
+	assert.NoError(t, err)
+	assert.False(t, generated, "Expected nothing to be generated once every secret is set")
+}
+
+func TestGenerateComposeSecretsSecure(t *testing.T) {
+	defer quietTests()()
+	setTestConfig(t, map[string]string{"database.postgres_password": defaultDatabaseSecret, "neo4j.secret": "kept"})
+
+	_, err := generateComposeSecrets(newFakeStack(), true)
+	assert.Error(t, err, "Expected an existing deployment with a default password to be an error")
+
+	generated, err := generateComposeSecrets(runtimetest.NewFakeRuntime(), true)
+	assert.NoError(t, err)
+	assert.True(t, generated)
+	assert.NotEqual(t, defaultDatabaseSecret, bhEnv.GetString("database.postgres_password"), "Expected the default password to be replaced")
+	assert.Equal(t, "kept", bhEnv.GetString("neo4j.secret"))
+}
+
+func TestEnsureComposeSecretsSecure(t *testing.T) {
+	defer quietTests()()
+	setTestConfigDirs(t)
+	setTestConfig(t, map[string]string{"database.postgres_password": "kept", "neo4j.secret": "kept", "neo4j.publish_ports": "true"})
+
+	assert.NoError(t, EnsureComposeSecrets(newFakeStack(), true))
+	assert.False(t, bhEnv.GetBool("neo4j.publish_ports"), "Expected `--secure` to stop publishing the Neo4j ports")
+	publish, _ := getNestedValue(readStoredSettings(), "neo4j.publish_ports")
+	assert.Equal(t, false, publish, "Expected the change to be saved to the JSON config file")
+}
+
+func TestComposeValue(t *testing.T) {
+	t.Setenv("POSTGRES_USER", "from-the-shell")
+	setTestConfig(t, map[string]string{"neo4j.user": "graph", "bloodhound.port": ""})
+
+	assert.Equal(t, "from-the-shell", composeValue("database.postgres_user"))
+	assert.Equal(t, "graph", composeValue("neo4j.user"))
+	assert.Equal(t, "8080", composeValue("bloodhound.port"), "Expected the YAML file's default")
+}
+
+func TestUnsetAndResetConfig(t *testing.T) {
+	assert.NoError(t, ParseBloodHoundEnvironmentVariables())
+	assert.NoError(t, bhEnv.MergeConfigMap(map[string]interface{}{"not_a_real_setting": "typo", "log_path": "bhce.log"}))
+
+	assert.NoError(t, UnsetConfig("not_a_real_setting"), "`UnsetConfig()` should remove an unknown key")
+	assert.False(t, bhEnv.IsSet("not_a_real_setting"), "The unknown key should be gone")
+	err := UnsetConfig("not_a_real_setting")
+	assert.True(t, errors.Is(err, ErrConfigKeyNotFound), "`UnsetConfig()` should report a missing key")
+
+	assert.NoError(t, ResetConfig([]string{"LOG_PATH"}), "`ResetConfig()` should reset a known key")
+	assert.Equal(t, "bloodhound.log", bhEnv.GetString("log_path"), "`log_path` should have its default")
+
+	err = ResetConfig([]string{"not_a_real_setting"})
+	assert.True(t, errors.Is(err, ErrInvalidConfig), "`ResetConfig()` should refuse a key without a default")
+	for _, key := range []string{"neo4j.secret", "default_password", "config_directory", "version"} {
+		assert.True(t, errors.Is(UnsetConfig(key), ErrInvalidConfig), "`UnsetConfig()` should refuse `%s`", key)
+		assert.True(t, errors.Is(ResetConfig([]string{key}), ErrInvalidConfig), "`ResetConfig()` should refuse `%s`", key)
+	}
+}
+
+func TestPreservedSettings(t *testing.T) {
+	kept := preservedSettings(map[string]interface{}{
+		"log_level":  "DEBUG",
+		"version":    2,
+		"bloodhound": map[string]interface{}{"tag": "v8.0.0", "port": "8443"},
+		"neo4j":      map[string]interface{}{"secret": "s3cret"},
+	})
+	assert.Equal(t, map[string]interface{}{
+		"version":    2,
+		"bloodhound": map[string]interface{}{"tag": "v8.0.0"},
+		"neo4j":      map[string]interface{}{"secret": "s3cret"},
+	}, kept, "The pinned tag, read-only keys, and secrets should be kept")
 }
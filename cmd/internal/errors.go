@@ -0,0 +1,29 @@
+package internal
+
+// Sentinel errors returned by the internal package
+// Callers can match these with `errors.Is()` to decide how to report a failure
+
+import (
+	"errors"
+)
+
+var (
+	// ErrDockerNotFound means neither Docker nor Podman is installed or available in the PATH
+	ErrDockerNotFound = errors.New("neither Docker nor Podman is installed")
+	// ErrDaemonUnavailable means the Docker or Podman CLI is installed but its daemon is not running or is inaccessible
+	ErrDaemonUnavailable = errors.New("the container engine is not running or access was denied")
+	// ErrComposeMissing means the Docker Compose v2 plugin is not installed
+	ErrComposeMissing = errors.New("docker compose is not installed")
+	// ErrYamlMissing means the Docker Compose YAML file does not exist
+	ErrYamlMissing = errors.New("the Docker YAML file does not exist")
+	// ErrConfigKeyNotFound means a requested configuration key has no value
+	ErrConfigKeyNotFound = errors.New("config variable not found")
+	// ErrInvalidConfig means the configuration file or a configuration value is invalid
+	ErrInvalidConfig = errors.New("invalid configuration")
+	// ErrUnhealthy means one or more BloodHound services are not healthy
+	ErrUnhealthy = errors.New("one or more BloodHound services are unhealthy")
+	// ErrVerificationFailed means a download does not match its published checksum or signature
+	ErrVerificationFailed = errors.New("verification failed")
+	// ErrCancelled means the user declined a confirmation prompt
+	ErrCancelled = errors.New("cancelled by the user")
+)
@@ -0,0 +1,268 @@
+package internal
+
+// Functions for evaluating the health of a running BloodHound deployment
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+)
+
+// Vars for the health checks
+var (
+	// Types of health issues; only errors make a deployment unhealthy
+	healthError   = "Error"
+	healthWarning = "Warning"
+	// Ports used inside the containers for the probes
+	neo4jBoltPort uint16 = 7687
+	// Command run inside the Neo4j container to check the bolt port when it is not published on the host
+	neo4jBoltCmd = []string{"bash", "-c", fmt.Sprintf("exec 3<>/dev/tcp/127.0.0.1/%d", neo4jBoltPort)}
+	// Timeout for each network probe
+	probeTimeout = 5 * time.Second
+	// Time between checks while waiting for the services to become ready
+	readyPollInterval = 2 * time.Second
+)
+
+// HasErrors returns true if any of the issues is an error rather than a warning.
+func (c HealthIssues) HasErrors() bool {
+	for _, issue := range c {
+		if issue.Type == healthError {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckHealth inspects every BloodHound container and probes the BloodHound web server, the Neo4j bolt port, and
+// Postgres readiness for the deployment described by the specified Docker Compose YAML file. It returns a sorted list
+// of any issues found. Returns an error if the Docker client cannot be used.
+func CheckHealth(rt Runtime, yaml string) (HealthIssues, error) {
+	var issues HealthIssues
+
+	for _, name := range prodImages {
+		summary, findErr := findContainerByName(rt, name)
+		if findErr != nil {
+			issues = append(issues, HealthIssue{healthError, name, "Container was not found; run `bloodhound-cli up` to create it"})
+			continue
+		}
+		inspect, inspectErr := rt.InspectContainer(context.Background(), summary.ID)
+		if inspectErr != nil {
+			issues = append(issues, HealthIssue{healthError, name, fmt.Sprintf("Failed to inspect the container: %v", inspectErr)})
+			continue
+		}
+		issues = append(issues, EvaluateContainerHealth(name, inspect)...)
+
+		if name == "bhce_neo4j" {
+			issues = append(issues, probeNeo4jBolt(rt, yaml, summary)...)
+		}
+	}
+
+	rootUrl := strings.TrimSuffix(bhEnv.GetString("root_url"), "/") + loginUri
+	if probeErr := ProbeHttp(rootUrl); probeErr != nil {
+		issues = append(issues, HealthIssue{healthError, "bhce_bloodhound", fmt.Sprintf("The web server at %s is not responding: %v", rootUrl, probeErr)})
+	}
+
+	if FileExists(yaml) {
+		readyErr := rt.Exec(yaml, "app-db", []string{"sh", "-c", pgReadyCmd}, ExecStreams{})
+		if readyErr != nil {
+			issues = append(issues, HealthIssue{healthError, "bhce_postgres", "Postgres is not accepting connections (`pg_isready` failed)"})
+		}
+	} else {
+		issues = append(issues, HealthIssue{healthWarning, "bhce_postgres", fmt.Sprintf("Skipped the Postgres readiness check because the YAML file %s does not exist", yaml)})
+	}
+
+	sort.Stable(issues)
+	return issues, nil
+}
+
+// probeNeo4jBolt checks that the Neo4j bolt port accepts connections. The port is probed from the host if it is
+// published, and from inside the container otherwise (e.g., after `install --secure`).
+func probeNeo4jBolt(rt Runtime, yaml string, summary container.Summary) HealthIssues {
+	service := "bhce_neo4j"
+	if addr := publishedAddress(summary.Ports, neo4jBoltPort); addr != "" {
+		if probeErr := ProbeTcp(addr); probeErr != nil {
+			return HealthIssues{{healthError, service, fmt.Sprintf("Bolt port %s is not accepting connections: %v", addr, probeErr)}}
+		}
+		return nil
+	}
+	if !FileExists(yaml) {
+		return HealthIssues{{healthWarning, service, fmt.Sprintf("Skipped the bolt port check because the port is not published and the YAML file %s does not exist", yaml)}}
+	}
+	if execErr := rt.Exec(yaml, "graph-db", neo4jBoltCmd, ExecStreams{}); execErr != nil {
+		return HealthIssues{{healthError, service, fmt.Sprintf("Bolt port %d is not accepting connections inside the container", neo4jBoltPort)}}
+	}
+	return nil
+}
+
+// EvaluateContainerHealth returns any issues found in a container's inspect data, including the Docker healthcheck
+// state, unexpected exits, OOM kills, and restarts.
+func EvaluateContainerHealth(service string, inspect container.InspectResponse) HealthIssues {
+	var issues HealthIssues
+	state := inspect.State
+	if state == nil {
+		return append(issues, HealthIssue{healthError, service, "Container has no state information"})
+	}
+
+	if !state.Running {
+		issues = append(issues, HealthIssue{healthError, service, fmt.Sprintf("Container is %s (exit code %d)", state.Status, state.ExitCode)})
+	} else if state.Health != nil {
+		switch state.Health.Status {
+		case container.Unhealthy:
+			message := "Healthcheck is failing"
+			if len(state.Health.Log) > 0 {
+				last := state.Health.Log[len(state.Health.Log)-1]
+				message = fmt.Sprintf("Healthcheck is failing (exit code %d): %s", last.ExitCode, strings.TrimSpace(last.Output))
+			}
+			issues = append(issues, HealthIssue{healthError, service, message})
+		case container.Starting:
+			issues = append(issues, HealthIssue{healthWarning, service, "Healthcheck is still starting"})
+		}
+	}
+
+	if state.OOMKilled {
+		issues = append(issues, HealthIssue{healthError, service, "Container was killed after running out of memory"})
+	}
+	if inspect.RestartCount > 0 {
+		issues = append(issues, HealthIssue{healthWarning, service, fmt.Sprintf("Container has restarted %d times", inspect.RestartCount)})
+	}
+
+	return issues
+}
+
+// ProbeHttp sends a GET request to the URL and returns an error if the request fails or the server returns an error
+// status code.
+func ProbeHttp(url string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("unexpected HTTP status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ProbeTcp returns an error if a TCP connection to the address cannot be opened.
+func ProbeTcp(addr string) error {
+	conn, err := net.DialTimeout("tcp", addr, probeTimeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// publishedAddress returns the host address where the container's private port is published, or an empty string if
+// the port is not published. Wildcard addresses are replaced with the loopback address.
+func publishedAddress(ports []container.PortSummary, privatePort uint16) string {
+	for _, port := range ports {
+		if port.PrivatePort != privatePort || port.PublicPort == 0 {
+			continue
+		}
+		host := "127.0.0.1"
+		if port.IP.IsValid() && !port.IP.IsUnspecified() {
+			host = port.IP.String()
+		}
+		return net.JoinHostPort(host, strconv.Itoa(int(port.PublicPort)))
+	}
+	return ""
+}
+
+// WaitForReady polls the BloodHound containers and the login page at the configured `root_url` until every service is
+// healthy and the UI responds, printing a progress line while it waits. If the timeout expires, it prints the services
+// that never became ready along with the tail of their logs and returns an error wrapping ErrUnhealthy.
+func WaitForReady(rt Runtime, timeout time.Duration) error {
+	loginUrl := strings.TrimSuffix(bhEnv.GetString("root_url"), "/") + loginUri
+	start := time.Now()
+	lastLength := 0
+	var pending []string
+	for {
+		pending = pending[:0]
+		for _, name := range prodImages {
+			ready, status := serviceReady(rt, name)
+			if !ready {
+				pending = append(pending, fmt.Sprintf("%s (%s)", name, status))
+			}
+		}
+		if len(pending) == 0 {
+			if probeErr := ProbeHttp(loginUrl); probeErr != nil {
+				pending = append(pending, fmt.Sprintf("%s (waiting for %s)", "bhce_bloodhound", loginUrl))
+			}
+		}
+
+		elapsed := time.Since(start).Round(time.Second)
+		if len(pending) == 0 {
+			fmt.Printf("\r%-*s\n", lastLength, fmt.Sprintf("[+] All BloodHound services are ready after %s", elapsed))
+			return nil
+		}
+		line := fmt.Sprintf("[*] Waiting for BloodHound to become ready (%s / %s): %s", elapsed, timeout, strings.Join(pending, ", "))
+		fmt.Printf("\r%-*s", lastLength, line)
+		lastLength = len(line)
+
+		if elapsed >= timeout {
+			fmt.Println()
+			break
+		}
+		time.Sleep(readyPollInterval)
+	}
+
+	fmt.Printf("[-] BloodHound did not become ready within %s\n", timeout)
+	for _, service := range pending {
+		fmt.Printf("[-] Not ready: %s\n", service)
+	}
+	for _, name := range prodImages {
+		if ready, _ := serviceReady(rt, name); !ready {
+			fmt.Printf("[-] Last log entries for `%s`:\n", name)
+			// Stopped containers are included because a service that exited is the one whose logs explain why
+			logErr := FetchLogs(context.Background(), rt, name, LogOptions{Tail: "25", All: true}, os.Stdout)
+			if logErr != nil {
+				fmt.Printf("[-] Could not fetch the logs: %v\n", logErr)
+			}
+		}
+	}
+	return fmt.Errorf("%w: timed out after %s waiting for the BloodHound services", ErrUnhealthy, timeout)
+}
+
+// serviceReady reports whether the container with the specified "name" label is running and healthy, along with a
+// short description of its current state.
+func serviceReady(rt Runtime, name string) (bool, string) {
+	summary, err := findContainerByName(rt, name)
+	if err != nil {
+		return false, "not found"
+	}
+	inspect, err := rt.InspectContainer(context.Background(), summary.ID)
+	if err != nil {
+		return false, "inspect failed"
+	}
+	return ContainerReady(inspect)
+}
+
+// ContainerReady reports whether a container is running and, if it has a healthcheck, healthy. The second value
+// describes the container's current state.
+func ContainerReady(inspect container.InspectResponse) (bool, string) {
+	state := inspect.State
+	if state == nil {
+		return false, "unknown"
+	}
+	if !state.Running {
+		return false, string(state.Status)
+	}
+	if state.Health == nil || state.Health.Status == container.NoHealthcheck {
+		return true, "running"
+	}
+	return state.Health.Status == container.Healthy, string(state.Health.Status)
+}
@@ -0,0 +1,157 @@
+package internal
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateContainerHealth(t *testing.T) {
+	healthy := container.InspectResponse{
+		State: &container.State{Running: true, Health: &container.Health{Status: container.Healthy}},
+	}
+	assert.Empty(t, EvaluateContainerHealth("bhce_neo4j", healthy), "Expected a healthy container to have no issues")
+
+	unhealthy := container.InspectResponse{
+		RestartCount: 3,
+		State: &container.State{
+			Running:   true,
+			OOMKilled: true,
+			Health: &container.Health{
+				Status: container.Unhealthy,
+				Log:    []*container.HealthcheckResult{{ExitCode: 1, Output: "connection refused\n"}},
+			},
+		},
+	}
+	issues := EvaluateContainerHealth("bhce_neo4j", unhealthy)
+	assert.Len(t, issues, 3, "Expected issues for the healthcheck, the OOM kill, and the restarts")
+	assert.Equal(t, "Healthcheck is failing (exit code 1): connection refused", issues[0].Message)
+	assert.True(t, issues.HasErrors(), "Expected `HasErrors()` to return true")
+
+	exited := container.InspectResponse{State: &container.State{Status: container.StateExited, ExitCode: 137}}
+	issues = EvaluateContainerHealth("bhce_postgres", exited)
+	assert.Equal(t, HealthIssues{{healthError, "bhce_postgres", "Container is exited (exit code 137)"}}, issues)
+
+	restarted := container.InspectResponse{RestartCount: 1, State: &container.State{Running: true}}
+	issues = EvaluateContainerHealth("bhce_bloodhound", restarted)
+	assert.False(t, issues.HasErrors(), "Expected restarts alone to only produce warnings")
+}
+
+func TestProbeHttp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ui/login" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	assert.NoError(t, ProbeHttp(server.URL+"/ui/login"), "Expected `ProbeHttp()` to succeed for a 200 response")
+	assert.Error(t, ProbeHttp(server.URL+"/broken"), "Expected `ProbeHttp()` to fail for a 502 response")
+}
+
+// httpClientFunc is an HttpClient that answers every request with the function.
+type httpClientFunc func(req *http.Request) (*http.Response, error)
+
+func (f httpClientFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestProbeHttpUsesPackageClient(t *testing.T) {
+	previous := httpClient
+	t.Cleanup(func() { httpClient = previous })
+	var deadline bool
+	httpClient = httpClientFunc(func(req *http.Request) (*http.Response, error) {
+		_, deadline = req.Context().Deadline()
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody}, nil
+	})
+
+	err := ProbeHttp("http://bloodhound.invalid/ui/login")
+	assert.ErrorContains(t, err, "503", "Expected `ProbeHttp()` to send the request with the package's client")
+	assert.True(t, deadline, "Expected the request to time out after the probe timeout")
+}
+
+func TestProbeTcp(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := listener.Addr().String()
+	assert.NoError(t, ProbeTcp(addr), "Expected `ProbeTcp()` to connect to an open port")
+
+	listener.Close()
+	assert.Error(t, ProbeTcp(addr), "Expected `ProbeTcp()` to fail for a closed port")
+}
+
+func TestPublishedAddress(t *testing.T) {
+	ports := []container.PortSummary{
+		{PrivatePort: 7474, PublicPort: 7474, IP: netip.MustParseAddr("127.0.0.1")},
+		{PrivatePort: 7687, PublicPort: 17687, IP: netip.MustParseAddr("0.0.0.0")},
+	}
+	assert.Equal(t, "127.0.0.1:17687", publishedAddress(ports, 7687), "Expected wildcard addresses to use the loopback address")
+	assert.Equal(t, "", publishedAddress(ports, 5432), "Expected an empty string for unpublished ports")
+}
+
+func TestContainerReady(t *testing.T) {
+	ready, status := ContainerReady(container.InspectResponse{State: &container.State{Running: true}})
+	assert.True(t, ready, "Expected a running container without a healthcheck to be ready")
+	assert.Equal(t, "running", status)
+
+	ready, status = ContainerReady(container.InspectResponse{
+		State: &container.State{Running: true, Health: &container.Health{Status: container.Starting}},
+	})
+	assert.False(t, ready, "Expected a container with a starting healthcheck to not be ready")
+	assert.Equal(t, "starting", status)
+
+	ready, _ = ContainerReady(container.InspectResponse{
+		State: &container.State{Running: true, Health: &container.Health{Status: container.Healthy}},
+	})
+	assert.True(t, ready, "Expected a healthy container to be ready")
+
+	ready, status = ContainerReady(container.InspectResponse{State: &container.State{Status: container.StateExited}})
+	assert.False(t, ready, "Expected a stopped container to not be ready")
+	assert.Equal(t, "exited", status)
+}
+
+// captureStdout returns what "run" writes to stdout.
+func captureStdout(t *testing.T, run func()) string {
+	out, err := os.CreateTemp(t.TempDir(), "stdout")
+	assert.NoError(t, err)
+	stdout := os.Stdout
+	os.Stdout = out
+	run()
+	os.Stdout = stdout
+	out.Close()
+	content, err := os.ReadFile(out.Name())
+	assert.NoError(t, err)
+	return string(content)
+}
+
+func TestWaitForReadyShowsExitedLogs(t *testing.T) {
+	previous := readyPollInterval
+	readyPollInterval = time.Millisecond
+	t.Cleanup(func() { readyPollInterval = previous })
+
+	rt := newFakeStack()
+	for i, c := range rt.Containers {
+		if c.Labels["name"] == "bhce_bloodhound" {
+			rt.Containers[i].State = container.StateExited
+		}
+	}
+	rt.Inspects["bhce_bloodhound-id"] = container.InspectResponse{
+		ID: "bhce_bloodhound-id", State: &container.State{Status: container.StateExited},
+	}
+	rt.Logs["bhce_bloodhound-id"] = "failed to connect to the database\n"
+
+	var err error
+	out := captureStdout(t, func() { err = WaitForReady(rt, time.Millisecond) })
+	assert.ErrorIs(t, err, ErrUnhealthy)
+	assert.Contains(t, out, "Not ready: bhce_bloodhound (exited)")
+	assert.Contains(t, out, "failed to connect to the database", "The log tail of a service that exited should be printed")
+}
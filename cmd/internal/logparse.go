@@ -0,0 +1,264 @@
+package internal
+
+// Functions for parsing, filtering, and formatting the structured logs written by the BloodHound containers
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Vars for normalizing log levels
+var (
+	// Severity of each normalized level; higher is more severe
+	logLevelRanks = map[string]int{
+		"TRACE": 0, "DEBUG": 1, "INFO": 2, "WARN": 3, "ERROR": 4, "FATAL": 5,
+	}
+	// Level names used by BloodHound (slog and zerolog), Neo4j, and Postgres mapped to a normalized level
+	logLevelAliases = map[string]string{
+		"trace": "TRACE", "trc": "TRACE",
+		"debug": "DEBUG", "dbg": "DEBUG", "debug1": "DEBUG", "debug2": "DEBUG", "debug3": "DEBUG", "debug4": "DEBUG", "debug5": "DEBUG",
+		"info": "INFO", "inf": "INFO", "log": "INFO", "notice": "INFO",
+		"warn": "WARN", "warning": "WARN", "wrn": "WARN",
+		"error": "ERROR", "err": "ERROR",
+		"fatal": "FATAL", "ftl": "FATAL", "panic": "FATAL", "pnc": "FATAL", "critical": "FATAL",
+	}
+	// ANSI colors for each normalized level
+	logLevelColors = map[string]string{
+		"TRACE": "\033[90m", "DEBUG": "\033[90m", "INFO": "\033[32m", "WARN": "\033[33m", "ERROR": "\033[31m", "FATAL": "\033[1;31m",
+	}
+	// Keys that hold the standard values in JSON and logfmt entries
+	logLevelKeys   = []string{"level", "lvl", "severity"}
+	logMessageKeys = []string{"msg", "message"}
+	logTimeKeys    = []string{"time", "timestamp", "ts"}
+)
+
+// LogEntry is a single parsed log line.
+type LogEntry struct {
+	Service string                 `json:"service"`
+	Time    string                 `json:"time,omitempty"`
+	Level   string                 `json:"level,omitempty"`
+	Message string                 `json:"message"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// LogFilter selects log entries by minimum severity and a regular expression matched against the message.
+type LogFilter struct {
+	Level   string
+	Pattern *regexp.Regexp
+}
+
+// NewLogFilter builds a LogFilter from a level name and a regular expression. Either may be empty.
+func NewLogFilter(level string, pattern string) (LogFilter, error) {
+	var filter LogFilter
+	if level != "" {
+		normalized := NormalizeLogLevel(level)
+		if normalized == "" {
+			return filter, fmt.Errorf("unknown log level `%s`; use trace, debug, info, warn, error, or fatal", level)
+		}
+		filter.Level = normalized
+	}
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return filter, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		filter.Pattern = re
+	}
+	return filter, nil
+}
+
+// Match reports whether the entry passes the filter. Entries without a recognized level never pass a level filter.
+func (f LogFilter) Match(entry LogEntry) bool {
+	if f.Level != "" {
+		rank, ok := logLevelRanks[entry.Level]
+		if !ok || rank < logLevelRanks[f.Level] {
+			return false
+		}
+	}
+	if f.Pattern != nil && !f.Pattern.MatchString(entry.Message) {
+		return false
+	}
+	return true
+}
+
+// NormalizeLogLevel maps a level name from any of the supported log formats to TRACE, DEBUG, INFO, WARN, ERROR, or
+// FATAL. Returns an empty string for unknown names.
+func NormalizeLogLevel(level string) string {
+	return logLevelAliases[strings.ToLower(strings.Trim(level, "[]:"))]
+}
+
+// ParseLogLine parses a line written by the specified service. It understands BloodHound's JSON logger, logfmt-style
+// text output (e.g., `time=... level=INFO msg="..."`), and lines that start with a timestamp and a level, as written
+// by BloodHound's console logger, Neo4j, and Postgres. If the line starts with a timestamp added by Docker's
+// "timestamps" option, that timestamp is used when the entry has none of its own. Lines that match none of the
+// formats are returned with the whole line as the message and no level.
+func ParseLogLine(service string, line string) LogEntry {
+	line = strings.TrimRight(line, "\r\n")
+	entry := LogEntry{Service: service, Message: line}
+
+	dockerTime := ""
+	if first, rest, found := strings.Cut(line, " "); found {
+		if _, err := time.Parse(time.RFC3339Nano, first); err == nil {
+			dockerTime = first
+			line = rest
+			entry.Message = rest
+		}
+	}
+
+	trimmed := strings.TrimSpace(line)
+	switch {
+	case strings.HasPrefix(trimmed, "{"):
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(trimmed), &fields); err == nil {
+			entry = entryFromFields(service, fields)
+		}
+	case strings.Contains(trimmed, "level=") || strings.Contains(trimmed, "msg="):
+		if fields, ok := parseLogfmt(trimmed); ok {
+			entry = entryFromFields(service, fields)
+		}
+	default:
+		parsePrefixedLine(&entry, trimmed)
+	}
+
+	if entry.Time == "" {
+		entry.Time = dockerTime
+	}
+	return entry
+}
+
+// entryFromFields builds a LogEntry from a map of keys and values, moving the level, message, and time keys into
+// their own fields.
+func entryFromFields(service string, fields map[string]interface{}) LogEntry {
+	entry := LogEntry{Service: service}
+	take := func(keys []string) string {
+		for _, key := range keys {
+			if value, ok := fields[key]; ok {
+				delete(fields, key)
+				return fmt.Sprint(value)
+			}
+		}
+		return ""
+	}
+	if level := take(logLevelKeys); level != "" {
+		entry.Level = NormalizeLogLevel(level)
+		if entry.Level == "" {
+			entry.Level = strings.ToUpper(level)
+		}
+	}
+	entry.Message = take(logMessageKeys)
+	entry.Time = take(logTimeKeys)
+	if len(fields) > 0 {
+		entry.Fields = fields
+	}
+	return entry
+}
+
+// parseLogfmt parses a line of space-separated key=value pairs. Values may be double-quoted. Returns false if the
+// line is not valid logfmt.
+func parseLogfmt(line string) (map[string]interface{}, bool) {
+	fields := map[string]interface{}{}
+	for len(line) > 0 {
+		line = strings.TrimLeft(line, " ")
+		if line == "" {
+			break
+		}
+		eq := strings.IndexByte(line, '=')
+		space := strings.IndexByte(line, ' ')
+		if eq <= 0 || (space >= 0 && space < eq) {
+			return nil, false
+		}
+		key := line[:eq]
+		line = line[eq+1:]
+
+		var value string
+		if strings.HasPrefix(line, `"`) {
+			end := 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil, false
+			}
+			unquoted, err := strconv.Unquote(line[:end+1])
+			if err != nil {
+				return nil, false
+			}
+			value = unquoted
+			line = line[end+1:]
+		} else {
+			end := strings.IndexByte(line, ' ')
+			if end < 0 {
+				end = len(line)
+			}
+			value = line[:end]
+			line = line[end:]
+		}
+		fields[key] = value
+	}
+	return fields, len(fields) > 0
+}
+
+// parsePrefixedLine looks for a level name in the first few words of a line, such as
+// `2025-01-02T15:04:05Z INF Server started` or `2025-01-02 15:04:05.123 UTC [1] LOG:  database system is ready`.
+// The words before the level become the time and the words after it become the message.
+func parsePrefixedLine(entry *LogEntry, line string) {
+	words := strings.Fields(line)
+	// Only consider lines that start with a timestamp so messages that merely start with a word like "error" are
+	// left alone
+	if len(words) == 0 || words[0][0] < '0' || words[0][0] > '9' {
+		return
+	}
+	for i := 1; i < len(words) && i < 5; i++ {
+		level := NormalizeLogLevel(words[i])
+		if level == "" {
+			continue
+		}
+		var timeWords []string
+		for _, word := range words[:i] {
+			if !strings.HasPrefix(word, "[") {
+				timeWords = append(timeWords, word)
+			}
+		}
+		entry.Level = level
+		entry.Time = strings.Join(timeWords, " ")
+		entry.Message = strings.Join(words[i+1:], " ")
+		return
+	}
+}
+
+// FormatLogEntry renders an entry as a single line, optionally coloring the level, followed by any extra fields in
+// sorted key=value form.
+func FormatLogEntry(entry LogEntry, color bool) string {
+	var b strings.Builder
+	if entry.Time != "" {
+		b.WriteString(entry.Time)
+		b.WriteString(" ")
+	}
+	if entry.Level != "" {
+		level := fmt.Sprintf("%-5s", entry.Level)
+		if color {
+			level = logLevelColors[entry.Level] + level + logReset
+		}
+		b.WriteString(level)
+		b.WriteString(" ")
+	}
+	b.WriteString(entry.Message)
+
+	keys := make([]string, 0, len(entry.Fields))
+	for key := range entry.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&b, " %s=%v", key, entry.Fields[key])
+	}
+	return b.String()
+}
@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLogLineJson(t *testing.T) {
+	line := `{"time":"2025-07-22T15:04:05.123Z","level":"WARN","message":"Analysis took longer than expected","duration":"35s"}`
+	entry := ParseLogLine("bhce_bloodhound", line)
+	assert.Equal(t, LogEntry{
+		Service: "bhce_bloodhound",
+		Time:    "2025-07-22T15:04:05.123Z",
+		Level:   "WARN",
+		Message: "Analysis took longer than expected",
+		Fields:  map[string]interface{}{"duration": "35s"},
+	}, entry)
+
+	// Older releases used lowercase zerolog levels and the `msg` key
+	entry = ParseLogLine("bhce_bloodhound", `{"level":"error","msg":"Failed to connect","time":"2025-07-22T15:04:05Z"}`)
+	assert.Equal(t, "ERROR", entry.Level)
+	assert.Equal(t, "Failed to connect", entry.Message)
+	assert.Nil(t, entry.Fields, "Expected no extra fields")
+}
+
+func TestParseLogLineText(t *testing.T) {
+	line := `time=2025-07-22T15:04:05.123Z level=INFO msg="Server started successfully" addr=0.0.0.0:8080`
+	entry := ParseLogLine("bhce_bloodhound", line)
+	assert.Equal(t, LogEntry{
+		Service: "bhce_bloodhound",
+		Time:    "2025-07-22T15:04:05.123Z",
+		Level:   "INFO",
+		Message: "Server started successfully",
+		Fields:  map[string]interface{}{"addr": "0.0.0.0:8080"},
+	}, entry)
+
+	entry = ParseLogLine("bhce_bloodhound", `level=ERROR msg="quoted \"value\""`)
+	assert.Equal(t, `quoted "value"`, entry.Message, "Expected escaped quotes to be unquoted")
+}
+
+func TestParseLogLinePrefixed(t *testing.T) {
+	entry := ParseLogLine("bhce_neo4j", "2025-07-22 15:04:05.123+0000 WARN  Use of deprecated setting.")
+	assert.Equal(t, "WARN", entry.Level)
+	assert.Equal(t, "2025-07-22 15:04:05.123+0000", entry.Time)
+	assert.Equal(t, "Use of deprecated setting.", entry.Message)
+
+	entry = ParseLogLine("bhce_postgres", "2025-07-22 15:04:05.123 UTC [1] LOG:  database system is ready to accept connections")
+	assert.Equal(t, "INFO", entry.Level)
+	assert.Equal(t, "2025-07-22 15:04:05.123 UTC", entry.Time)
+	assert.Equal(t, "database system is ready to accept connections", entry.Message)
+
+	entry = ParseLogLine("bhce_bloodhound", "error: this is not a timestamped line")
+	assert.Equal(t, "", entry.Level, "Expected lines without a timestamp to have no level")
+	assert.Equal(t, "error: this is not a timestamped line", entry.Message)
+}
+
+func TestParseLogLineDockerTimestamp(t *testing.T) {
+	entry := ParseLogLine("bhce_bloodhound", `2025-07-22T15:04:05.000000001Z {"level":"info","message":"ready"}`)
+	assert.Equal(t, "2025-07-22T15:04:05.000000001Z", entry.Time, "Expected Docker's timestamp to be used when the entry has none")
+	assert.Equal(t, "INFO", entry.Level)
+
+	entry = ParseLogLine("bhce_bloodhound", `2025-07-22T15:04:05.000000001Z level=INFO time=2025-07-22T15:04:04Z msg=ready`)
+	assert.Equal(t, "2025-07-22T15:04:04Z", entry.Time, "Expected the entry's own timestamp to take precedence")
+}
+
+func TestLogFilter(t *testing.T) {
+	filter, err := NewLogFilter("warning", "analysis|ingest")
+	assert.NoError(t, err, "Expected `NewLogFilter()` to accept level aliases")
+	assert.Equal(t, "WARN", filter.Level)
+
+	assert.True(t, filter.Match(LogEntry{Level: "ERROR", Message: "ingest failed"}))
+	assert.False(t, filter.Match(LogEntry{Level: "INFO", Message: "ingest failed"}), "Expected lower levels to be filtered")
+	assert.False(t, filter.Match(LogEntry{Level: "ERROR", Message: "login failed"}), "Expected messages that do not match to be filtered")
+	assert.False(t, filter.Match(LogEntry{Message: "ingest failed"}), "Expected entries without a level to be filtered")
+
+	_, err = NewLogFilter("loud", "")
+	assert.Error(t, err, "Expected an unknown level to return an error")
+	_, err = NewLogFilter("", "(")
+	assert.Error(t, err, "Expected an invalid regular expression to return an error")
+}
+
+func TestFormatLogEntry(t *testing.T) {
+	entry := LogEntry{Time: "15:04:05", Level: "INFO", Message: "ready", Fields: map[string]interface{}{"port": "8080", "addr": "0.0.0.0"}}
+	assert.Equal(t, "15:04:05 INFO  ready addr=0.0.0.0 port=8080", FormatLogEntry(entry, false))
+	assert.Equal(t, "15:04:05 \033[32mINFO \033[0m ready addr=0.0.0.0 port=8080", FormatLogEntry(entry, true))
+}
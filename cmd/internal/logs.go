@@ -0,0 +1,203 @@
+package internal
+
+// Functions for reading and streaming the logs of the BloodHound containers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/moby/moby/api/types/container"
+)
+
+// Vars for formatting log output
+var (
+	// ANSI colors assigned to each container's prefix in turn
+	logColors = []string{"\033[36m", "\033[33m", "\033[35m", "\033[32m", "\033[34m", "\033[31m"}
+	logReset  = "\033[0m"
+)
+
+// LogOptions controls which log entries are read from the BloodHound containers and how they are written.
+type LogOptions struct {
+	// Number of lines to read from the end of the logs (or "all")
+	Tail string
+	// Only read entries after or before these times (e.g., "2025-01-02T15:04:05Z" or a relative time like "10m")
+	Since string
+	Until string
+	// Include the timestamp Docker recorded for each entry
+	Timestamps bool
+	// Keep streaming new entries until the context is cancelled
+	Follow bool
+	// Color each container's prefix and, for parsed entries, the level
+	Color bool
+	// Only write parsed entries that match this filter
+	Filter LogFilter
+	// Write parsed entries as newline-delimited JSON instead of text
+	JSON bool
+	// Include stopped containers, e.g., to show why a service exited
+	All bool
+}
+
+// containerOptions returns the options the runtime needs to read the logs.
+func (o LogOptions) containerOptions() ContainerLogOptions {
+	return ContainerLogOptions{Tail: o.Tail, Since: o.Since, Until: o.Until, Timestamps: o.Timestamps, Follow: o.Follow}
+}
+
+// structured reports whether the options require each line to be parsed into a LogEntry.
+func (o LogOptions) structured() bool {
+	return o.JSON || o.Filter.Level != "" || o.Filter.Pattern != nil
+}
+
+// FetchLogs writes the logs from the container with the specified "name" label ("containerName" parameter), or from
+// every BloodHound container if "containerName" is "all", to the "out" writer. Each line is prefixed with the name of
+// the container that wrote it. If "options" sets a filter or JSON output, each line is parsed with ParseLogLine,
+// filtered, and written as formatted text or as JSON. Logs are read one container at a time unless "options.Follow"
+// is set, in which case the containers are streamed together until the context is cancelled.
+func FetchLogs(ctx context.Context, rt Runtime, containerName string, options LogOptions, out io.Writer) error {
+	containers, err := rt.ListContainers(ctx, options.All)
+	if err != nil {
+		return err
+	}
+
+	var matches []container.Summary
+	for _, c := range containers {
+		name := c.Labels["name"]
+		if !Contains(devImages, name) && !Contains(prodImages, name) {
+			continue
+		}
+		if containerName == "all" || name == containerName || name == "bhce_"+containerName {
+			matches = append(matches, c)
+		}
+	}
+	if len(matches) == 0 {
+		if options.All {
+			return fmt.Errorf("no BloodHound container found for `%s`", containerName)
+		}
+		return fmt.Errorf("no running BloodHound container found for `%s`", containerName)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Labels["name"] < matches[j].Labels["name"]
+	})
+
+	width := 0
+	for _, c := range matches {
+		width = max(width, len(c.Labels["name"]))
+	}
+
+	var mu sync.Mutex
+	streamContainer := func(index int, c container.Summary) error {
+		service := c.Labels["name"]
+		prefix := fmt.Sprintf("%-*s | ", width, service)
+		if options.Color {
+			prefix = logColors[index%len(logColors)] + prefix + logReset
+		}
+		emit := func(line []byte) error {
+			_, err := fmt.Fprintf(out, "%s%s", prefix, line)
+			return err
+		}
+		if options.structured() {
+			emit = func(line []byte) error {
+				return writeLogEntry(out, prefix, ParseLogLine(service, string(line)), options)
+			}
+		}
+		writer := &lineWriter{mu: &mu, emit: emit}
+		defer writer.Flush()
+		return rt.ContainerLogs(ctx, c.ID, options.containerOptions(), writer)
+	}
+
+	if !options.Follow {
+		for i, c := range matches {
+			if err := streamContainer(i, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(matches))
+	for i, c := range matches {
+		wg.Add(1)
+		go func(i int, c container.Summary) {
+			defer wg.Done()
+			errs[i] = streamContainer(i, c)
+		}(i, c)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeLogEntry writes a parsed entry to the writer if it matches the filter in the options, either as a JSON object
+// or as a formatted line after the container's prefix.
+func writeLogEntry(out io.Writer, prefix string, entry LogEntry, options LogOptions) error {
+	if !options.Filter.Match(entry) {
+		return nil
+	}
+	if options.JSON {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "%s\n", data)
+		return err
+	}
+	_, err := fmt.Fprintf(out, "%s%s\n", prefix, FormatLogEntry(entry, options.Color))
+	return err
+}
+
+// lineWriter is an io.Writer that buffers data and passes each complete line, including the newline, to its emit
+// function. Writers that share a mutex never interleave their lines.
+type lineWriter struct {
+	mu   *sync.Mutex
+	emit func(line []byte) error
+	buf  []byte
+}
+
+// Write buffers the data and emits every complete line.
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		if err := w.writeLine(w.buf[:i+1]); err != nil {
+			return len(p), err
+		}
+		w.buf = w.buf[i+1:]
+	}
+}
+
+// Flush emits any buffered partial line followed by a newline.
+func (w *lineWriter) Flush() {
+	if len(w.buf) > 0 {
+		_ = w.writeLine(append(w.buf, '\n'))
+		w.buf = nil
+	}
+}
+
+// writeLine emits a single line while holding the shared lock.
+func (w *lineWriter) writeLine(line []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.emit(line)
+}
+
+// IsTerminal reports whether the file is a character device, such as an interactive terminal.
+func IsTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineWriter(t *testing.T) {
+	var out bytes.Buffer
+	var mu sync.Mutex
+	writer := &lineWriter{mu: &mu, emit: func(line []byte) error {
+		_, err := fmt.Fprintf(&out, "bhce_neo4j | %s", line)
+		return err
+	}}
+
+	// Lines split across writes are only emitted once they are complete
+	_, err := writer.Write([]byte("first li"))
+	assert.NoError(t, err)
+	assert.Equal(t, "", out.String(), "Expected partial lines to be buffered")
+	_, err = writer.Write([]byte("ne\nsecond line\nthird"))
+	assert.NoError(t, err)
+	assert.Equal(t, "bhce_neo4j | first line\nbhce_neo4j | second line\n", out.String())
+
+	writer.Flush()
+	assert.Equal(t, "bhce_neo4j | first line\nbhce_neo4j | second line\nbhce_neo4j | third\n", out.String(), "Expected `Flush()` to emit the partial line")
+}
+
+func TestWriteLogEntry(t *testing.T) {
+	filter, err := NewLogFilter("warn", "")
+	assert.NoError(t, err)
+	options := LogOptions{Filter: filter}
+
+	var out bytes.Buffer
+	assert.NoError(t, writeLogEntry(&out, "bhce_bloodhound | ", LogEntry{Service: "bhce_bloodhound", Level: "INFO", Message: "skipped"}, options))
+	assert.Equal(t, "", out.String(), "Expected entries below the filter level to be skipped")
+
+	assert.NoError(t, writeLogEntry(&out, "bhce_bloodhound | ", LogEntry{Service: "bhce_bloodhound", Level: "ERROR", Message: "kept"}, options))
+	assert.Equal(t, "bhce_bloodhound | ERROR kept\n", out.String())
+
+	out.Reset()
+	options.JSON = true
+	assert.NoError(t, writeLogEntry(&out, "bhce_bloodhound | ", LogEntry{Service: "bhce_bloodhound", Level: "WARN", Message: "kept"}, options))
+	assert.Equal(t, `{"service":"bhce_bloodhound","level":"WARN","message":"kept"}`+"\n", out.String(), "Expected JSON output to omit the prefix")
+}
@@ -0,0 +1,184 @@
+package internal
+
+// Functions for upgrading JSON config files written by older versions of BloodHound CLI
+// Each migration upgrades the layout by one `version`, and the old file is backed up before it is replaced
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// configMigration upgrades the settings of a JSON config file from one layout version to the next.
+type configMigration struct {
+	// Version the migration upgrades from; the result has version From+1
+	From        int
+	Description string
+	Apply       func(settings map[string]interface{}) error
+}
+
+// Migrations for every historical layout, in order
+var configMigrations = []configMigration{
+	{
+		From:        1,
+		Description: "drop `recreatedefaultadmin`, move the `default_password` alias to `default_admin.password`, and store typed values",
+		Apply:       migrateConfigV1,
+	},
+}
+
+// currentConfigVersion is the layout version written by this version of BloodHound CLI. It must be one more than the
+// last migration's From.
+const currentConfigVersion = 2
+
+// configVersion returns the layout version of the settings. Files written before the version was checked always
+// have version 1, and a file without a version (e.g., written by hand) is treated as version 1.
+func configVersion(settings map[string]interface{}) (int, error) {
+	value, ok := settings["version"]
+	if !ok {
+		return 1, nil
+	}
+	switch version := value.(type) {
+	case float64:
+		if version == float64(int(version)) && version >= 1 {
+			return int(version), nil
+		}
+	case int:
+		if version >= 1 {
+			return version, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: the JSON config file has an invalid `version`: %v", ErrInvalidConfig, value)
+}
+
+// migrateConfig applies the migrations the settings need to reach currentConfigVersion. The settings are changed in
+// place. Returns the version the settings had before the migrations and an error wrapping ErrInvalidConfig if the
+// version is invalid or newer than this version of BloodHound CLI supports.
+func migrateConfig(settings map[string]interface{}) (int, error) {
+	from, err := configVersion(settings)
+	if err != nil {
+		return 0, err
+	}
+	if from > currentConfigVersion {
+		return from, fmt.Errorf("%w: the JSON config file has version %d, but this version of BloodHound CLI only supports version %d or earlier; upgrade BloodHound CLI", ErrInvalidConfig, from, currentConfigVersion)
+	}
+	for _, migration := range configMigrations {
+		if migration.From < from {
+			continue
+		}
+		if err := migration.Apply(settings); err != nil {
+			return from, fmt.Errorf("failed to migrate the JSON config file from version %d: %w", migration.From, err)
+		}
+		settings["version"] = migration.From + 1
+	}
+	return from, nil
+}
+
+// migrateConfigFile upgrades the JSON config file at the path to currentConfigVersion. The original file is copied to
+// a backup with the old version in its name (e.g., "bloodhound.config.json.v1.bak") before it is replaced. Returns
+// the path of the backup, or an empty string if the file did not need a migration.
+func migrateConfigFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the JSON config file: %w", err)
+	}
+	var settings map[string]interface{}
+	if err := json.Unmarshal(content, &settings); err != nil {
+		return "", fmt.Errorf("%w: error while parsing the JSON config file: %w", ErrInvalidConfig, err)
+	}
+	// A new, empty file gets the current version from the defaults
+	if len(settings) == 0 {
+		return "", nil
+	}
+	from, err := migrateConfig(settings)
+	if err != nil {
+		return "", err
+	}
+	if from == currentConfigVersion {
+		return "", nil
+	}
+
+	backup := fmt.Sprintf("%s.v%d.bak", path, from)
+	if err := os.WriteFile(backup, content, configFileMode()); err != nil {
+		return "", fmt.Errorf("failed to back up the JSON config file before migrating it: %w", err)
+	}
+	migrated, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal configuration to JSON: %w", err)
+	}
+	// Write a temporary file and rename it so an interrupted write cannot leave a partial config behind
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err := os.WriteFile(tmp, migrated, configFileMode()); err != nil {
+		return "", fmt.Errorf("error while writing the JSON config file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("error while writing the JSON config file: %w", err)
+	}
+	return backup, nil
+}
+
+// migrateConfigFileWithNotice migrates the JSON config file at the path like migrateConfigFile and tells the user where
+// the old file was saved.
+func migrateConfigFileWithNotice(path string) error {
+	backup, err := migrateConfigFile(path)
+	if err != nil {
+		return err
+	}
+	if backup != "" {
+		fmt.Fprintf(os.Stderr, "[+] Migrated the JSON config file to version %d and saved the old file to %s\n", currentConfigVersion, backup)
+	}
+	return nil
+}
+
+// migrateConfigV1 upgrades files written by BloodHound CLI v0.2.0 and earlier. Those files have `version` 1 whether
+// or not they have the `config_directory` key added in v0.1.7, and the missing key comes from the defaults.
+func migrateConfigV1(settings map[string]interface{}) error {
+	// The `bhe_recreate_default_admin` environment variable (set by `resetpwd`) controls this, so the key did nothing
+	delete(settings, "recreatedefaultadmin")
+
+	// Older versions wrote the alias next to the key it refers to
+	for alias, key := range configAliases {
+		value, ok := settings[alias]
+		if !ok {
+			continue
+		}
+		if _, exists := getNestedValue(settings, key); !exists {
+			setNestedValue(settings, key, value)
+		}
+		delete(settings, alias)
+	}
+
+	// `config set` stored every value other than true and false as a string, so convert the values the schema accepts
+	// to their types; values it rejects are left alone so a migration never loses a setting
+	for _, entry := range configSchema {
+		value, ok := getNestedValue(settings, entry.Key)
+		if !ok {
+			continue
+		}
+		text, ok := value.(string)
+		if !ok || text == "" {
+			continue
+		}
+		if parsed, err := entry.Parse(text); err == nil {
+			setNestedValue(settings, entry.Key, parsed)
+		}
+	}
+	return nil
+}
+
+// getNestedValue returns the value of a dotted key (e.g., "neo4j.secret") in nested settings.
+func getNestedValue(settings map[string]interface{}, key string) (interface{}, bool) {
+	section := settings
+	parts := strings.Split(key, ".")
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := section[part].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		section = next
+	}
+	value, ok := section[parts[len(parts)-1]]
+	return value, ok
+}
@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// loadConfigFixture reads a JSON config file from testdata/config.
+func loadConfigFixture(t *testing.T, name string) map[string]interface{} {
+	content, err := os.ReadFile(filepath.Join("testdata", "config", name))
+	assert.NoError(t, err, "Expected the fixture %s to exist", name)
+	var settings map[string]interface{}
+	assert.NoError(t, json.Unmarshal(content, &settings), "Expected the fixture %s to be valid JSON", name)
+	return settings
+}
+
+func TestConfigMigrationsAreOrdered(t *testing.T) {
+	for i, migration := range configMigrations {
+		assert.Equal(t, i+1, migration.From, "Migrations should upgrade one version at a time, in order")
+	}
+	assert.Equal(t, len(configMigrations)+1, currentConfigVersion, "`currentConfigVersion` should follow the last migration")
+}
+
+func TestMigrateConfigV1(t *testing.T) {
+	for _, name := range []string{"v1-0.1.6.json", "v1-0.1.7.json", "v1-unversioned.json"} {
+		settings := loadConfigFixture(t, name)
+		from, err := migrateConfig(settings)
+		assert.NoError(t, err, "`migrateConfig()` should migrate %s", name)
+		assert.Equal(t, 1, from, "%s should be detected as version 1", name)
+		assert.Equal(t, currentConfigVersion, settings["version"], "%s should be migrated to the current version", name)
+		assert.NotContains(t, settings, "recreatedefaultadmin", "%s should no longer have `recreatedefaultadmin`", name)
+		assert.NotContains(t, settings, "default_password", "%s should no longer have the `default_password` alias", name)
+		password, ok := getNestedValue(settings, "default_admin.password")
+		assert.True(t, ok, "%s should keep the admin password", name)
+		assert.Equal(t, "Xk3vR9pQ2mW7tZ5bN8cJ4hF6gD1sA0eL", password, "%s should keep the admin password's value", name)
+	}
+
+	// Values set with the old `config set` are converted to their types
+	settings := loadConfigFixture(t, "v1-0.1.7.json")
+	_, err := migrateConfig(settings)
+	assert.NoError(t, err)
+	assert.Equal(t, "DEBUG", settings["log_level"], "`log_level` should be converted to the schema's value")
+	assert.Equal(t, "https://bloodhound.example.com", settings["root_url"], "`root_url` should lose its trailing slash")
+	assert.Equal(t, "/home/bloodhound/.config/bloodhound", settings["config_directory"], "`config_directory` should be kept")
+
+	// Values the schema rejects are kept as they are
+	settings = loadConfigFixture(t, "v1-unversioned.json")
+	_, err = migrateConfig(settings)
+	assert.NoError(t, err)
+	assert.Equal(t, "verbose", settings["log_level"], "Invalid values should not be changed by a migration")
+}
+
+func TestMigrateConfigVersions(t *testing.T) {
+	settings := map[string]interface{}{"version": float64(currentConfigVersion), "log_level": "debug"}
+	from, err := migrateConfig(settings)
+	assert.NoError(t, err, "`migrateConfig()` should accept the current version")
+	assert.Equal(t, currentConfigVersion, from)
+	assert.Equal(t, "debug", settings["log_level"], "A current file should not be changed")
+
+	_, err = migrateConfig(map[string]interface{}{"version": float64(currentConfigVersion + 1)})
+	assert.True(t, errors.Is(err, ErrInvalidConfig), "`migrateConfig()` should refuse a newer version")
+
+	_, err = migrateConfig(map[string]interface{}{"version": "one"})
+	assert.True(t, errors.Is(err, ErrInvalidConfig), "`migrateConfig()` should refuse an invalid version")
+}
+
+func TestMigrateConfigFile(t *testing.T) {
+	original, err := os.ReadFile(filepath.Join("testdata", "config", "v1-0.1.6.json"))
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "bloodhound.config.json")
+	assert.NoError(t, os.WriteFile(path, original, 0600))
+
+	backup, err := migrateConfigFile(path)
+	assert.NoError(t, err, "`migrateConfigFile()` should migrate a version 1 file")
+	assert.Equal(t, path+".v1.bak", backup, "The backup should be named after the old version")
+	saved, err := os.ReadFile(backup)
+	assert.NoError(t, err, "The backup should exist")
+	assert.Equal(t, original, saved, "The backup should be the original file")
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	var settings map[string]interface{}
+	assert.NoError(t, json.Unmarshal(content, &settings))
+	assert.Equal(t, float64(currentConfigVersion), settings["version"], "The migrated file should have the current version")
+	assert.NotContains(t, settings, "recreatedefaultadmin", "The migrated file should not have `recreatedefaultadmin`")
+
+	// A second run finds nothing to do
+	backup, err = migrateConfigFile(path)
+	assert.NoError(t, err)
+	assert.Empty(t, backup, "A migrated file should not be migrated again")
+
+	// A new, empty file is left alone
+	empty := filepath.Join(t.TempDir(), "bloodhound.config.json")
+	assert.NoError(t, os.WriteFile(empty, []byte("{}\n"), 0600))
+	backup, err = migrateConfigFile(empty)
+	assert.NoError(t, err)
+	assert.Empty(t, backup, "An empty file should not be migrated")
+}
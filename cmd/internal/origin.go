@@ -0,0 +1,129 @@
+package internal
+
+// Functions for explaining where each config value comes from
+// Viper's AutomaticEnv lets an environment variable named after a key (e.g., ROOT_URL for `root_url`) override the
+// JSON config file, and some keys have other environment variables or flags that take precedence over them
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Vars for the origins of config values
+var (
+	OriginDefault     = "default"
+	OriginFile        = "file"
+	OriginSecretStore = "secret store"
+	OriginEnv         = "env"
+	OriginFlag        = "flag"
+)
+
+// ConfigOrigin describes the effective value of a config key and where it comes from.
+type ConfigOrigin struct {
+	Key    string      `json:"key"`
+	Value  interface{} `json:"value"`
+	Origin string      `json:"origin"`
+	// Environment variable or flag that sets the value
+	Source string `json:"source,omitempty"`
+	// Value from the JSON config file or secret store that the environment variable or flag hides
+	Shadowed interface{} `json:"shadowed,omitempty"`
+}
+
+// GetConfigOrigins returns the effective value of every config key with a value, sorted by key, and where the value
+// comes from. The "hostFlag" and "contextFlag" parameters are the `--host` and `--context` flags, which take
+// precedence over `docker_host` (see ResolveEndpoint). Secret values are redacted unless "showSecrets" is true.
+func GetConfigOrigins(hostFlag string, contextFlag string, showSecrets bool) []ConfigOrigin {
+	fileSettings := readStoredSettings()
+	settings := effectiveSettings()
+
+	keys := map[string]bool{}
+	for _, key := range flattenSettings(settings) {
+		keys[key] = true
+	}
+	// Compose variables without a value still matter to Compose if they are set in the environment
+	for _, variable := range composeVariables {
+		if _, ok := os.LookupEnv(variable.EnvVar); ok {
+			keys[variable.Key] = true
+		}
+	}
+
+	var origins []ConfigOrigin
+	for key := range keys {
+		value, _ := getNestedValue(settings, key)
+		origin := ConfigOrigin{Key: key, Value: value, Origin: OriginDefault}
+		stored, inFile := getNestedValue(fileSettings, key)
+		switch {
+		case inFile:
+			origin.Origin = OriginFile
+		case storedSecrets[key] != "":
+			origin.Origin, stored, inFile = OriginSecretStore, storedSecrets[key], true
+		}
+
+		source, sourceValue, sourceOrigin := overridingSource(key, hostFlag, contextFlag)
+		if source != "" {
+			origin.Origin = sourceOrigin
+			origin.Source = source
+			origin.Value = sourceValue
+			if inFile && fmt.Sprint(stored) != sourceValue {
+				origin.Shadowed = stored
+			}
+		}
+
+		if !showSecrets {
+			origin.Value = redactValue(key, origin.Value)
+			if origin.Shadowed != nil {
+				origin.Shadowed = redactValue(key, origin.Shadowed)
+			}
+		}
+		origins = append(origins, origin)
+	}
+	sort.Slice(origins, func(i, j int) bool { return origins[i].Key < origins[j].Key })
+	return origins
+}
+
+// overridingSource returns the flag or environment variable that takes precedence over the config key's stored value,
+// if any, along with its value and origin.
+func overridingSource(key string, hostFlag string, contextFlag string) (string, string, string) {
+	if key == "docker_host" {
+		switch {
+		case hostFlag != "":
+			return "--host", hostFlag, OriginFlag
+		case contextFlag != "":
+			return "--context", contextFlag, OriginFlag
+		}
+		for _, name := range []string{"DOCKER_HOST", "DOCKER_CONTEXT"} {
+			if value := os.Getenv(name); value != "" {
+				return name, value, OriginEnv
+			}
+		}
+	}
+	// Viper ignores empty environment variables
+	name := strings.ToUpper(key)
+	if value := os.Getenv(name); value != "" {
+		return name, value, OriginEnv
+	}
+	// Compose reads its variables from the environment before the values the CLI passes (see ComposeEnvironment)
+	if name := composeEnvVar(key); name != "" {
+		if value, ok := os.LookupEnv(name); ok {
+			return name, value, OriginEnv
+		}
+	}
+	return "", "", ""
+}
+
+// readStoredSettings returns the settings in the JSON config file, or no settings if the file cannot be read.
+func readStoredSettings() map[string]interface{} {
+	settings := map[string]interface{}{}
+	content, err := os.ReadFile(filepath.Join(GetBloodHoundDir(), "bloodhound.config.json"))
+	if err != nil {
+		return settings
+	}
+	if err := json.Unmarshal(content, &settings); err != nil {
+		return map[string]interface{}{}
+	}
+	return settings
+}
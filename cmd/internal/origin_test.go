@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetConfigOrigins(t *testing.T) {
+	dir := setTestConfigDirs(t)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "bloodhound.config.json"), []byte(`{"version": 2, "root_url": "http://127.0.0.1:8080"}`), 0600))
+	t.Setenv("ROOT_URL", "http://ci-runner:8080")
+	t.Setenv("POSTGRES_PASSWORD", "from-shell")
+
+	origins := map[string]ConfigOrigin{}
+	for _, origin := range GetConfigOrigins("ssh://user@host", "", false) {
+		origins[origin.Key] = origin
+	}
+	assert.Equal(t, OriginFile, origins["version"].Origin)
+	assert.Equal(t, OriginDefault, origins["wait_timeout"].Origin)
+	assert.Equal(t, ConfigOrigin{Key: "root_url", Value: "http://ci-runner:8080", Origin: OriginEnv, Source: "ROOT_URL", Shadowed: "http://127.0.0.1:8080"}, origins["root_url"],
+		"An environment variable hiding a saved value should be reported")
+	assert.Equal(t, ConfigOrigin{Key: "docker_host", Value: "ssh://user@host", Origin: OriginFlag, Source: "--host"}, origins["docker_host"])
+	assert.Equal(t, OriginEnv, origins["database.postgres_password"].Origin, "Compose variables set in the environment should be reported")
+	assert.Equal(t, redactedValue, origins["database.postgres_password"].Value, "Secrets should be redacted")
+}
+
+func TestRemoveEnvOverrides(t *testing.T) {
+	assert.NoError(t, ParseBloodHoundEnvironmentVariables())
+	saved := bhEnv.Get("root_url")
+	t.Setenv("ROOT_URL", "http://ci-runner:8080")
+
+	assert.Equal(t, "http://ci-runner:8080", bhEnv.GetString("root_url"))
+	assert.Equal(t, saved, currentSettings()["root_url"], "Environment variables should not be saved to the JSON config file")
+	assert.Equal(t, "http://ci-runner:8080", os.Getenv("ROOT_URL"), "The environment variable should be restored")
+}
@@ -0,0 +1,73 @@
+package internal
+
+// Functions for rendering command results as tables or as machine-readable JSON and YAML
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Vars for the supported output formats
+var (
+	OutputTable = "table"
+	OutputJson  = "json"
+	OutputYaml  = "yaml"
+)
+
+// ValidateOutputFormat returns an error if the format is not one of the supported output formats.
+func ValidateOutputFormat(format string) error {
+	switch format {
+	case OutputTable, OutputJson, OutputYaml:
+		return nil
+	}
+	return fmt.Errorf("unsupported output format `%s`; use %s, %s, or %s", format, OutputTable, OutputJson, OutputYaml)
+}
+
+// RenderOutput writes "data" to the writer in the requested format. JSON and YAML use the same field names because the
+// YAML is converted from the JSON encoding. The "table" function writes the human-readable version for the table
+// format.
+func RenderOutput(w io.Writer, format string, data interface{}, table func(io.Writer)) error {
+	switch format {
+	case OutputTable:
+		table(w)
+		return nil
+	case OutputJson:
+		encoded, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(encoded))
+		return err
+	case OutputYaml:
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		// Decoding the JSON into a node keeps the original key order
+		var node yaml.Node
+		if err := yaml.Unmarshal(encoded, &node); err != nil {
+			return err
+		}
+		resetYamlStyle(&node)
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(&node); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+	return ValidateOutputFormat(format)
+}
+
+// resetYamlStyle clears the flow and quoting styles that come from decoding JSON so the YAML uses block style.
+func resetYamlStyle(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode || node.Tag == "!!str" {
+		node.Style = 0
+	}
+	for _, child := range node.Content {
+		resetYamlStyle(child)
+	}
+}
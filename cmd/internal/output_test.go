@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateOutputFormat(t *testing.T) {
+	assert.NoError(t, ValidateOutputFormat("table"))
+	assert.NoError(t, ValidateOutputFormat("json"))
+	assert.NoError(t, ValidateOutputFormat("yaml"))
+	assert.Error(t, ValidateOutputFormat("xml"), "Expected an unsupported format to return an error")
+}
+
+func TestRenderOutput(t *testing.T) {
+	data := Configurations{{Key: "root_url", Val: "http://127.0.0.1:8080"}, {Key: "log_level", Val: "true"}}
+	table := func(w io.Writer) {
+		_, _ = io.WriteString(w, "table output\n")
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, RenderOutput(&out, OutputTable, data, table))
+	assert.Equal(t, "table output\n", out.String(), "Expected the table format to use the table function")
+
+	out.Reset()
+	assert.NoError(t, RenderOutput(&out, OutputJson, data, table))
+	assert.Equal(t, `[
+  {
+    "key": "root_url",
+    "value": "http://127.0.0.1:8080"
+  },
+  {
+    "key": "log_level",
+    "value": "true"
+  }
+]
+`, out.String())
+
+	out.Reset()
+	assert.NoError(t, RenderOutput(&out, OutputYaml, data, table))
+	assert.Equal(t, `- key: root_url
+  value: http://127.0.0.1:8080
+- key: log_level
+  value: "true"
+`, out.String(), "Expected YAML to use block style, JSON field names, and quotes for ambiguous strings")
+
+	assert.Error(t, RenderOutput(&out, "xml", data, table), "Expected an unsupported format to return an error")
+}
@@ -4,7 +4,7 @@ package internal
 
 import (
 	"crypto/rand"
-	"log"
+	"fmt"
 	"math/big"
 	"strings"
 )
@@ -22,7 +22,8 @@ func GenerateRandomPassword(pwLength int, safe bool) string {
 	for i := 0; i < pwLength; i++ {
 		nBig, err := rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
 		if err != nil {
-			log.Fatalf("Failed to generate random number for password generation.\n")
+			// The system's secure random source is broken, so there is no safe way to continue
+			panic(fmt.Sprintf("failed to generate random number for password generation: %v", err))
 		}
 		b.WriteRune(chars[nBig.Int64()])
 	}
@@ -0,0 +1,119 @@
+package internal
+
+// Functions for keeping the config directory and its files private to the current user
+// The `permissions_mode` config value selects "private" (the default) or "shared" for multi-user systems
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Vars for the config directory's permissions
+var (
+	// Values of the `permissions_mode` config key
+	permissionsPrivate = "private"
+	permissionsShared  = "shared"
+	// Modes for each value, as the directory mode and the file mode
+	permissionModes = map[string][2]os.FileMode{
+		permissionsPrivate: {0700, 0600},
+		// Group members can use the CLI with the same config directory
+		permissionsShared: {0770, 0660},
+	}
+	// Files in the config directory that are audited, if they exist
+	auditedConfigFiles = []string{"bloodhound.config.json", "bloodhound.config.json.bak", secretFileName}
+)
+
+// PermissionIssue describes a file or directory with permissions that allow more access than the permissions mode.
+type PermissionIssue struct {
+	Path string      `json:"path"`
+	Mode os.FileMode `json:"mode"`
+	// Most permissive mode allowed for the path
+	Want os.FileMode `json:"want"`
+}
+
+// String describes the issue for a warning.
+func (p PermissionIssue) String() string {
+	return fmt.Sprintf("%s has mode %04o, which allows more access than %04o", p.Path, p.Mode, p.Want)
+}
+
+// permissionModesFor returns the directory and file modes for the `permissions_mode` config value. Returns an error
+// wrapping ErrInvalidConfig for an unknown value.
+func permissionModesFor(mode string) (os.FileMode, os.FileMode, error) {
+	if mode == "" {
+		mode = permissionsPrivate
+	}
+	modes, ok := permissionModes[mode]
+	if !ok {
+		return 0, 0, fmt.Errorf("%w: `permissions_mode` must be %s or %s, not `%s`", ErrInvalidConfig, permissionsPrivate, permissionsShared, mode)
+	}
+	return modes[0], modes[1], nil
+}
+
+// configDirMode returns the mode for new config directories. An invalid `permissions_mode` value uses the private mode.
+func configDirMode() os.FileMode {
+	dirMode, _, err := permissionModesFor(bhEnv.GetString("permissions_mode"))
+	if err != nil {
+		return permissionModes[permissionsPrivate][0]
+	}
+	return dirMode
+}
+
+// configFileMode returns the mode for new files in the config directory. An invalid `permissions_mode` value uses the
+// private mode.
+func configFileMode() os.FileMode {
+	_, fileMode, err := permissionModesFor(bhEnv.GetString("permissions_mode"))
+	if err != nil {
+		return permissionModes[permissionsPrivate][1]
+	}
+	return fileMode
+}
+
+// AuditConfigPermissions returns the config directory and files that allow more access than the `permissions_mode`
+// config value. Permissions are not audited on Windows, where they do not use Unix modes.
+func AuditConfigPermissions() ([]PermissionIssue, error) {
+	if runtime.GOOS == "windows" {
+		return nil, nil
+	}
+	dirMode, fileMode, err := permissionModesFor(bhEnv.GetString("permissions_mode"))
+	if err != nil {
+		return nil, err
+	}
+
+	configDir := GetBloodHoundDir()
+	audited := []PermissionIssue{{Path: configDir, Want: dirMode}}
+	for _, name := range auditedConfigFiles {
+		audited = append(audited, PermissionIssue{Path: filepath.Join(configDir, name), Want: fileMode})
+	}
+	// Backups made before migrating the JSON config file hold the same secrets
+	backups, _ := filepath.Glob(filepath.Join(configDir, "bloodhound.config.json.v*.bak"))
+	for _, backup := range backups {
+		audited = append(audited, PermissionIssue{Path: backup, Want: fileMode})
+	}
+
+	var issues []PermissionIssue
+	for _, candidate := range audited {
+		info, err := os.Stat(candidate.Path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to check the permissions on %s: %w", candidate.Path, err)
+		}
+		if candidate.Mode = info.Mode().Perm(); candidate.Mode&^candidate.Want != 0 {
+			issues = append(issues, candidate)
+		}
+	}
+	return issues, nil
+}
+
+// FixConfigPermissions removes the extra access from each issue's path, leaving the permissions the mode allows.
+func FixConfigPermissions(issues []PermissionIssue) error {
+	for _, issue := range issues {
+		if err := os.Chmod(issue.Path, issue.Mode&issue.Want); err != nil {
+			return fmt.Errorf("failed to change the permissions on %s: %w", issue.Path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditConfigPermissions(t *testing.T) {
+	configDir := filepath.Join(t.TempDir(), "bloodhound")
+	assert.NoError(t, os.Mkdir(configDir, 0755))
+	assert.NoError(t, os.Chmod(configDir, 0755))
+	configFile := filepath.Join(configDir, "bloodhound.config.json")
+	assert.NoError(t, os.WriteFile(configFile, []byte("{}"), 0644))
+	assert.NoError(t, os.Chmod(configFile, 0644))
+	setTestConfig(t, map[string]string{"config_directory": configDir, "permissions_mode": permissionsPrivate})
+
+	issues, err := AuditConfigPermissions()
+	assert.NoError(t, err)
+	assert.Equal(t, []PermissionIssue{
+		{Path: configDir, Mode: 0755, Want: 0700},
+		{Path: configFile, Mode: 0644, Want: 0600},
+	}, issues, "Expected the missing secrets file to be skipped")
+
+	assert.NoError(t, FixConfigPermissions(issues))
+	issues, err = AuditConfigPermissions()
+	assert.NoError(t, err)
+	assert.Empty(t, issues, "Expected the fixed permissions to pass the audit")
+
+	assert.NoError(t, os.Chmod(configFile, 0660))
+	bhEnv.Set("permissions_mode", permissionsShared)
+	issues, err = AuditConfigPermissions()
+	assert.NoError(t, err)
+	assert.Empty(t, issues, "Expected group access to be allowed in the shared mode")
+
+	bhEnv.Set("permissions_mode", "everyone")
+	_, err = AuditConfigPermissions()
+	assert.True(t, errors.Is(err, ErrInvalidConfig), "Expected an unknown mode to be rejected")
+	assert.Equal(t, os.FileMode(0700), configDirMode(), "Expected an unknown mode to create private directories")
+}
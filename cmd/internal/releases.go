@@ -0,0 +1,267 @@
+package internal
+
+// Functions for comparing the running BloodHound images against the latest BloodHound CE release
+// Releases come from the image registry's tag listing, and the release notes come from GitHub's API
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HttpClient sends HTTP requests. The *http.Client type implements it.
+type HttpClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Vars for the release and registry APIs, which tests point at a local server
+var (
+	httpClient     HttpClient = &http.Client{Timeout: 10 * time.Second}
+	githubApiUrl              = "https://api.github.com"
+	registryApiUrl            = "https://hub.docker.com"
+	// Repositories for the BloodHound CE image and its releases
+	bloodhoundImageRepo   = "specterops/bloodhound"
+	bloodhoundReleaseRepo = "SpecterOps/BloodHound"
+	// Number of release note lines in the summary
+	releaseNotesLines = 10
+	// Release tags, like "v8.0.0"; release candidates and other tags are ignored
+	releaseTagPattern = regexp.MustCompile(`^v(\d+)\.(\d+)\.(\d+)$`)
+)
+
+// ServerImage describes the image of a BloodHound container.
+type ServerImage struct {
+	// The container's "name" label (e.g., "bhce_bloodhound")
+	Name    string `json:"name"`
+	Image   string `json:"image"`
+	ImageID string `json:"image_id"`
+	// Digest of the image's manifest in the registry, if the image was pulled
+	Digest string `json:"digest,omitempty"`
+	State  string `json:"state"`
+}
+
+// ServerVersion describes the images of a BloodHound deployment and the latest BloodHound CE release.
+type ServerVersion struct {
+	Images []ServerImage `json:"images"`
+	// Release of the running BloodHound image, found by its tag or digest
+	RunningRelease string `json:"running_release,omitempty"`
+	LatestRelease  string `json:"latest_release"`
+	LatestUrl      string `json:"latest_url"`
+	// Whether the running release is older than the latest release; false if the running release is unknown
+	Behind       bool   `json:"behind"`
+	ReleaseNotes string `json:"release_notes,omitempty"`
+}
+
+// registryTag is a tag in the registry's tag listing.
+type registryTag struct {
+	Name   string `json:"name"`
+	Digest string `json:"digest"`
+}
+
+// GetServerVersion returns the images of the BloodHound containers, running or stopped, and compares the BloodHound
+// image against the latest BloodHound CE release in the registry. The release notes are left out if GitHub cannot be
+// reached.
+func GetServerVersion(rt Runtime) (ServerVersion, error) {
+	var version ServerVersion
+	containers, err := rt.ListContainers(context.Background(), true)
+	if err != nil {
+		return version, err
+	}
+	for _, c := range containers {
+		name := c.Labels["name"]
+		if !Contains(devImages, name) && !Contains(prodImages, name) {
+			continue
+		}
+		image := ServerImage{Name: name, Image: c.Image, ImageID: c.ImageID, State: string(c.State)}
+		if inspect, err := rt.InspectImage(context.Background(), c.ImageID); err == nil {
+			image.Digest = repoDigest(c.Image, inspect.RepoDigests)
+		}
+		version.Images = append(version.Images, image)
+	}
+	sort.Slice(version.Images, func(i, j int) bool { return version.Images[i].Name < version.Images[j].Name })
+
+	tags, err := fetchBloodHoundReleases()
+	if err != nil {
+		return version, err
+	}
+	version.LatestRelease = latestRelease(tags)
+	version.LatestUrl = fmt.Sprintf("https://github.com/%s/releases/tag/%s", bloodhoundReleaseRepo, version.LatestRelease)
+
+	for _, image := range version.Images {
+		if image.Name == "bhce_bloodhound" {
+			version.RunningRelease = runningRelease(image, tags)
+		}
+	}
+	if version.RunningRelease != "" {
+		version.Behind = compareReleases(version.RunningRelease, version.LatestRelease) < 0
+	}
+
+	notes, url, err := fetchReleaseNotes(version.LatestRelease)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[!] Could not fetch the release notes for %s: %v\n", version.LatestRelease, err)
+	} else {
+		version.ReleaseNotes = notes
+		if url != "" {
+			version.LatestUrl = url
+		}
+	}
+	return version, nil
+}
+
+// LatestBloodHoundRelease returns the newest BloodHound CE release tag (e.g., "v8.0.0") in the registry.
+func LatestBloodHoundRelease() (string, error) {
+	tags, err := fetchBloodHoundReleases()
+	if err != nil {
+		return "", err
+	}
+	return latestRelease(tags), nil
+}
+
+// fetchBloodHoundReleases returns the BloodHound image's tags from the registry. Returns an error if the tags have no
+// release tag.
+func fetchBloodHoundReleases() ([]registryTag, error) {
+	tags, err := fetchRegistryTags(bloodhoundImageRepo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the BloodHound CE releases: %w", err)
+	}
+	if latestRelease(tags) == "" {
+		return nil, fmt.Errorf("no BloodHound CE releases were found in the %s tags", bloodhoundImageRepo)
+	}
+	return tags, nil
+}
+
+// repoDigest returns the digest from the image's repository digest (e.g., "specterops/bloodhound@sha256:...") that
+// matches the image reference's repository, or the first digest if none match.
+func repoDigest(image string, repoDigests []string) string {
+	repository := image
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository = repository[:i]
+	}
+	repository = strings.TrimPrefix(repository, "docker.io/")
+	digest := ""
+	for _, repoDigest := range repoDigests {
+		name, sum, ok := strings.Cut(repoDigest, "@")
+		if !ok {
+			continue
+		}
+		if digest == "" || strings.TrimPrefix(name, "docker.io/") == repository {
+			digest = sum
+		}
+	}
+	return digest
+}
+
+// runningRelease returns the release of the image from its tag or, for tags like "latest", from the release tag with
+// the same digest.
+func runningRelease(image ServerImage, tags []registryTag) string {
+	if i := strings.LastIndex(image.Image, ":"); i > strings.LastIndex(image.Image, "/") {
+		if tag := image.Image[i+1:]; releaseTagPattern.MatchString(tag) {
+			return tag
+		}
+	}
+	if image.Digest == "" {
+		return ""
+	}
+	release := ""
+	for _, tag := range tags {
+		if tag.Digest == image.Digest && releaseTagPattern.MatchString(tag.Name) && (release == "" || compareReleases(tag.Name, release) > 0) {
+			release = tag.Name
+		}
+	}
+	return release
+}
+
+// latestRelease returns the newest release tag.
+func latestRelease(tags []registryTag) string {
+	latest := ""
+	for _, tag := range tags {
+		if releaseTagPattern.MatchString(tag.Name) && (latest == "" || compareReleases(tag.Name, latest) > 0) {
+			latest = tag.Name
+		}
+	}
+	return latest
+}
+
+// compareReleases compares two release tags like "v8.0.0" and returns -1, 0, or 1.
+func compareReleases(a string, b string) int {
+	partsA := releaseTagPattern.FindStringSubmatch(a)
+	partsB := releaseTagPattern.FindStringSubmatch(b)
+	for i := 1; i < len(partsA) && i < len(partsB); i++ {
+		numberA, _ := strconv.Atoi(partsA[i])
+		numberB, _ := strconv.Atoi(partsB[i])
+		if numberA != numberB {
+			if numberA < numberB {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// fetchRegistryTags returns the most recently updated tags of a repository on Docker Hub.
+func fetchRegistryTags(repository string) ([]registryTag, error) {
+	var listing struct {
+		Results []registryTag `json:"results"`
+	}
+	url := fmt.Sprintf("%s/v2/repositories/%s/tags?page_size=100&ordering=last_updated", registryApiUrl, repository)
+	if err := getJson(url, &listing); err != nil {
+		return nil, err
+	}
+	return listing.Results, nil
+}
+
+// fetchReleaseNotes returns a summary of the GitHub release notes for the BloodHound CE release and the release's URL.
+func fetchReleaseNotes(tag string) (string, string, error) {
+	var release struct {
+		Body    string `json:"body"`
+		HtmlUrl string `json:"html_url"`
+	}
+	url := fmt.Sprintf("%s/repos/%s/releases/tags/%s", githubApiUrl, bloodhoundReleaseRepo, tag)
+	if err := getJson(url, &release); err != nil {
+		return "", "", err
+	}
+	return summarizeReleaseNotes(release.Body, releaseNotesLines), release.HtmlUrl, nil
+}
+
+// summarizeReleaseNotes returns the first non-empty lines of Markdown release notes without heading markers, adding
+// a line with "..." if any lines were left out.
+func summarizeReleaseNotes(body string, limit int) string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#"))
+		if line == "" {
+			continue
+		}
+		if len(lines) == limit {
+			lines = append(lines, "...")
+			break
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// getJson sends a GET request with httpClient and decodes the JSON response into "out".
+func getJson(url string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected HTTP status from %s: %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SpecterOps/BloodHound_CLI/cmd/internal/runtimetest"
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/image"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubReleaseApis points the GitHub and registry API URLs at a local server that answers with the handlers until the
+// test finishes.
+func stubReleaseApis(t *testing.T, handlers map[string]string) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := handlers[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	client, github, registry := httpClient, githubApiUrl, registryApiUrl
+	httpClient, githubApiUrl, registryApiUrl = server.Client(), server.URL, server.URL
+	t.Cleanup(func() { httpClient, githubApiUrl, registryApiUrl = client, github, registry })
+}
+
+// newVersionStack returns a runtimetest.FakeRuntime with BloodHound, Postgres, and Neo4j containers and their images.
+func newVersionStack(bloodhoundImage string, digest string) *runtimetest.FakeRuntime {
+	rt := runtimetest.NewFakeRuntime()
+	images := map[string]string{
+		"bhce_bloodhound": bloodhoundImage,
+		"bhce_postgres":   "docker.io/library/postgres:16",
+		"bhce_neo4j":      "docker.io/library/neo4j:4.4",
+	}
+	for name, ref := range images {
+		rt.Containers = append(rt.Containers, container.Summary{
+			ID: name + "-id", Image: ref, ImageID: "sha256:" + name, State: container.StateRunning,
+			Labels: map[string]string{"name": name},
+		})
+	}
+	rt.Images["sha256:bhce_bloodhound"] = image.InspectResponse{RepoDigests: []string{"specterops/bloodhound@" + digest}}
+	return rt
+}
+
+var registryTags = `{"results": [
+	{"name": "latest", "digest": "sha256:new"},
+	{"name": "v8.1.0", "digest": "sha256:new"},
+	{"name": "v8.1.0-rc1", "digest": "sha256:rc"},
+	{"name": "v8.0.10", "digest": "sha256:old"},
+	{"name": "v8.0.9", "digest": "sha256:older"}
+]}`
+
+func TestGetServerVersion(t *testing.T) {
+	stubReleaseApis(t, map[string]string{
+		"/v2/repositories/specterops/bloodhound/tags": registryTags,
+		"/repos/SpecterOps/BloodHound/releases/tags/v8.1.0": `{
+			"html_url": "https://github.com/SpecterOps/BloodHound/releases/tag/v8.1.0",
+			"body": "## What's Changed\r\n\r\n* Faster ingest\r\n* New edges"
+		}`,
+	})
+
+	version, err := GetServerVersion(newVersionStack("docker.io/specterops/bloodhound:latest", "sha256:old"))
+	assert.NoError(t, err, "`GetServerVersion()` should read the stubbed APIs")
+	assert.Len(t, version.Images, 3)
+	assert.Equal(t, "bhce_bloodhound", version.Images[0].Name, "Images should be sorted by name")
+	assert.Equal(t, "sha256:old", version.Images[0].Digest)
+	assert.Equal(t, "v8.0.10", version.RunningRelease, "A `latest` image should be matched to a release by its digest")
+	assert.Equal(t, "v8.1.0", version.LatestRelease, "Release candidates should be ignored")
+	assert.True(t, version.Behind)
+	assert.Equal(t, "What's Changed\n* Faster ingest\n* New edges", version.ReleaseNotes)
+
+	version, err = GetServerVersion(newVersionStack("docker.io/specterops/bloodhound:v8.1.0", "sha256:new"))
+	assert.NoError(t, err)
+	assert.Equal(t, "v8.1.0", version.RunningRelease)
+	assert.False(t, version.Behind, "The latest release should not be behind")
+}
+
+func TestGetServerVersionWithoutReleaseNotes(t *testing.T) {
+	stubReleaseApis(t, map[string]string{"/v2/repositories/specterops/bloodhound/tags": registryTags})
+
+	version, err := GetServerVersion(newVersionStack("docker.io/specterops/bloodhound:custom", "sha256:unknown"))
+	assert.NoError(t, err, "Missing release notes should not be an error")
+	assert.Empty(t, version.RunningRelease, "An unknown image should not be matched to a release")
+	assert.False(t, version.Behind)
+	assert.Equal(t, "https://github.com/SpecterOps/BloodHound/releases/tag/v8.1.0", version.LatestUrl)
+
+	stubReleaseApis(t, map[string]string{})
+	_, err = GetServerVersion(newVersionStack("docker.io/specterops/bloodhound:latest", "sha256:new"))
+	assert.Error(t, err, "A failed registry request should be an error")
+}
+
+func TestCompareReleases(t *testing.T) {
+	assert.Equal(t, -1, compareReleases("v8.0.9", "v8.0.10"))
+	assert.Equal(t, 1, compareReleases("v9.0.0", "v8.12.3"))
+	assert.Equal(t, 0, compareReleases("v8.1.0", "v8.1.0"))
+	assert.Equal(t, "sha256:b", repoDigest("docker.io/specterops/bloodhound:latest", []string{"mirror.local/bloodhound@sha256:a", "specterops/bloodhound@sha256:b"}))
+	assert.Equal(t, "1\n2\n...", summarizeReleaseNotes("# 1\n\n2\n3", 2))
+}
+
+func TestGetRemoteBloodHoundCliVersionOffline(t *testing.T) {
+	stubReleaseApis(t, map[string]string{"/repos/SpecterOps/bloodhound-cli/releases/latest": `{
+		"tag_name": "v0.3.0",
+		"published_at": "2026-01-15T12:00:00Z",
+		"html_url": "https://github.com/SpecterOps/bloodhound-cli/releases/tag/v0.3.0"
+	}`})
+
+	version, url, err := GetRemoteBloodHoundCliVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, "BloodHound CLI v0.3.0 (15 January 2026)", version)
+	assert.Equal(t, "https://github.com/SpecterOps/bloodhound-cli/releases/tag/v0.3.0", url)
+}
@@ -0,0 +1,108 @@
+package internal
+
+// Functions for changing the database passwords of a running deployment
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Commands run inside the database containers to change the passwords; the new password is sent on stdin, and
+// `cypher-shell` reads the current password from the NEO4J_PASSWORD environment variable
+var (
+	pgPasswordCmd    = `psql --quiet -v ON_ERROR_STOP=1 --username="$POSTGRES_USER" --dbname="$POSTGRES_DB"`
+	neo4jPasswordCmd = []string{"cypher-shell", "--format", "plain", "-d", "system"}
+	neo4jPasswordVar = "NEO4J_PASSWORD"
+)
+
+// RotateSecrets generates new Postgres and Neo4j passwords, changes them inside the running database containers, saves
+// them to the JSON config file, and recreates the BloodHound service so it connects with the new passwords. It then
+// waits up to "timeout" for the services to become healthy.
+//
+// Each password is saved as soon as the database accepts it, so a failure part of the way through leaves the config
+// matching the databases.
+func RotateSecrets(rt Runtime, yaml string, timeout time.Duration) error {
+	if err := CheckYamlExists(yaml); err != nil {
+		return err
+	}
+	for _, key := range composeSecretKeys {
+		if envVar := composeEnvVar(key); os.Getenv(envVar) != "" {
+			return fmt.Errorf("%w: %s is set in your environment and would override the new password; unset it and try again", ErrInvalidConfig, envVar)
+		}
+	}
+
+	running, err := GetRunning(rt)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, c := range running {
+		names = append(names, c.Name)
+	}
+	if !Contains(names, "bhce_postgres") || !Contains(names, "bhce_neo4j") {
+		return fmt.Errorf("the Postgres and Neo4j containers must be running to change their passwords; run `bloodhound-cli up` and try again")
+	}
+
+	// Neo4j goes first because changing its password requires the current one, so a wrong value stops the command
+	// before anything is changed
+	fmt.Println("[+] Changing the Neo4j password...")
+	neo4jSecret := GenerateRandomPassword(32, true)
+	if err := changeNeo4jPassword(rt, yaml, neo4jSecret); err != nil {
+		return fmt.Errorf("error trying to change the Neo4j password: %w", err)
+	}
+	bhEnv.Set("neo4j.secret", neo4jSecret)
+	if err := WriteBloodHoundEnvironmentVariables(); err != nil {
+		return fmt.Errorf("the Neo4j password was changed but could not be saved, so set `neo4j.secret` to %s: %w", neo4jSecret, err)
+	}
+
+	fmt.Println("[+] Changing the Postgres password...")
+	pgSecret := GenerateRandomPassword(32, true)
+	pgQuery := fmt.Sprintf("ALTER ROLE CURRENT_USER WITH PASSWORD %s;\n", sqlQuote(pgSecret))
+	pgErr := rt.Exec(yaml, "app-db", []string{"sh", "-c", pgPasswordCmd}, ExecStreams{Stdin: strings.NewReader(pgQuery), Stderr: os.Stderr})
+	if pgErr != nil {
+		return fmt.Errorf("error trying to change the Postgres password: %w", pgErr)
+	}
+	bhEnv.Set("database.postgres_password", pgSecret)
+	if err := WriteBloodHoundEnvironmentVariables(); err != nil {
+		return fmt.Errorf("the Postgres password was changed but could not be saved, so set `database.postgres_password` to %s: %w", pgSecret, err)
+	}
+
+	fmt.Println("[+] Recreating the BloodHound service with the new passwords...")
+	// The databases already use the new passwords, so only the BloodHound container is recreated
+	if err := rt.Recreate(yaml, "bloodhound"); err != nil {
+		return fmt.Errorf("error trying to recreate the BloodHound service with %s: %w", yaml, err)
+	}
+	if err := WaitForStack(rt, timeout); err != nil {
+		return err
+	}
+	fmt.Println("[+] The database passwords were changed and saved to the JSON config file")
+	return nil
+}
+
+// changeNeo4jPassword logs in to Neo4j with the current password and changes it to "secret". Neither password is
+// passed on the command line.
+func changeNeo4jPassword(rt Runtime, yaml string, secret string) error {
+	current := composeValue("neo4j.secret")
+	query := fmt.Sprintf("ALTER CURRENT USER SET PASSWORD FROM %s TO %s;\n", cypherQuote(current), cypherQuote(secret))
+	command := append(append([]string{}, neo4jPasswordCmd...), "-u", composeValue("neo4j.user"))
+	return rt.Exec(yaml, "graph-db", command, ExecStreams{
+		Stdin:  strings.NewReader(query),
+		Stdout: io.Discard,
+		Stderr: os.Stderr,
+		Env:    []string{neo4jPasswordVar + "=" + current},
+	})
+}
+
+// sqlQuote returns the value as a Postgres string literal.
+func sqlQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// cypherQuote returns the value as a Cypher string literal.
+func cypherQuote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return "'" + strings.ReplaceAll(value, "'", `\'`) + "'"
+}
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/SpecterOps/BloodHound_CLI/cmd/internal/runtimetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteLiterals(t *testing.T) {
+	assert.Equal(t, `'secret'`, sqlQuote("secret"))
+	assert.Equal(t, `'it''s'`, sqlQuote("it's"))
+	assert.Equal(t, `'it\'s'`, cypherQuote("it's"))
+	assert.Equal(t, `'back\\slash'`, cypherQuote(`back\slash`))
+}
+
+func TestRotateSecretsPreconditions(t *testing.T) {
+	yaml := writeTestYaml(t)
+
+	rt := runtimetest.NewFakeRuntime()
+	assert.Error(t, RotateSecrets(rt, yaml, 0), "Expected stopped databases to be an error")
+	assert.Empty(t, rt.Commands, "Expected nothing to run when the databases are stopped")
+
+	t.Setenv("NEO4J_SECRET", "from-the-shell")
+	rt = newFakeStack()
+	assert.True(t, errors.Is(RotateSecrets(rt, yaml, 0), ErrInvalidConfig), "Expected a secret in the environment to be rejected")
+	assert.Empty(t, rt.Commands)
+}
+
+func TestRotateSecrets(t *testing.T) {
+	setTestConfigDirs(t)
+	setTestConfig(t, map[string]string{
+		"neo4j.user":                 "neo4j",
+		"neo4j.secret":               "old-neo4j",
+		"database.postgres_password": "old-pg",
+	})
+	yaml := writeTestYaml(t)
+	neo4jCommand := "exec -T -e NEO4J_PASSWORD graph-db cypher-shell --format plain -d system -u neo4j"
+	pgCommand := "exec -T app-db sh -c " + pgPasswordCmd
+
+	rt := newFakeStack()
+	rt.ExecErrors["app-db"] = errors.New("connection refused")
+	assert.ErrorContains(t, RotateSecrets(rt, yaml, 0), "Postgres password", "A failed Postgres change should stop the command")
+	assert.Equal(t, []string{neo4jCommand, pgCommand}, rt.Commands, "The BloodHound service should not be recreated")
+	assert.Equal(t, []string{"NEO4J_PASSWORD=old-neo4j"}, rt.ExecEnv, "The current Neo4j password should be passed in the environment")
+	for _, command := range rt.Commands {
+		assert.NotContains(t, command, "old-neo4j", "The current Neo4j password should not be on the command line")
+	}
+	stored := readStoredSettings()
+	neo4jSecret, _ := getNestedValue(stored, "neo4j.secret")
+	pgSecret, _ := getNestedValue(stored, "database.postgres_password")
+	assert.NotEqual(t, "old-neo4j", neo4jSecret, "The changed Neo4j password should be saved")
+	assert.Equal(t, bhEnv.GetString("neo4j.secret"), neo4jSecret)
+	assert.Equal(t, "old-pg", pgSecret, "The Postgres password should still match the database")
+
+	rt.Commands, rt.ExecEnv = nil, nil
+	delete(rt.ExecErrors, "app-db")
+	assert.NoError(t, RotateSecrets(rt, yaml, 0))
+	assert.Equal(t, []string{neo4jCommand, pgCommand, "up -d --no-deps bloodhound"}, rt.Commands, "Only the BloodHound service should be recreated, not the databases it depends on")
+	assert.Equal(t, []string{"NEO4J_PASSWORD=" + neo4jSecret.(string)}, rt.ExecEnv, "The saved password should be the current one")
+	pgSecret, _ = getNestedValue(readStoredSettings(), "database.postgres_password")
+	assert.NotEqual(t, "old-pg", pgSecret)
+	assert.Equal(t, bhEnv.GetString("database.postgres_password"), pgSecret)
+}
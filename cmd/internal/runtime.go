@@ -0,0 +1,396 @@
+package internal
+
+// Container runtimes that run the Docker Compose commands and query the BloodHound containers
+// Commands receive a Runtime instead of calling a container engine directly, so tests can run them against a fake
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/SpecterOps/BloodHound_CLI/cmd/internal/engine"
+	"github.com/moby/moby/api/pkg/stdcopy"
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/image"
+	"github.com/moby/moby/client"
+)
+
+// Ensure every runtime implements the Runtime interface
+var (
+	_ Runtime = (*DockerRuntime)(nil)
+	_ Runtime = (*PodmanRuntime)(nil)
+)
+
+// Runtime and the types of its options are defined in the engine package, so the fake in runtimetest can implement
+// Runtime without importing this package
+type (
+	Runtime             = engine.Runtime
+	DownOptions         = engine.DownOptions
+	ExecStreams         = engine.ExecStreams
+	ContainerLogOptions = engine.ContainerLogOptions
+)
+
+// DetectRuntime returns the container runtime installed on this system, connected to the endpoint, after checking
+// that it is usable. Docker is preferred, and Podman is used if Docker is not installed. Status messages are written
+// to stderr so they do not mix with command output on stdout. Returns ErrDockerNotFound, ErrDaemonUnavailable, or
+// ErrComposeMissing if a requirement is not met.
+func DetectRuntime(endpoint Endpoint) (Runtime, error) {
+	fmt.Fprintln(os.Stderr, "[+] Checking the status of Docker and the Compose plugin...")
+	if endpoint.Host != "" {
+		fmt.Fprintf(os.Stderr, "[+] Using the container engine at %s (from the %s)\n", endpoint.Host, endpoint.Source)
+	}
+	var rt Runtime
+	// Check for ``docker`` first because it's the primary supported runtime
+	if CheckPath("docker") {
+		rt = NewDockerRuntime(endpoint)
+	} else if CheckPath("podman") {
+		fmt.Fprintln(os.Stderr, "[+] Docker is not installed, but Podman is installed. Using Podman as a Docker alternative.")
+		if endpoint.Context != "" && endpoint.Context != defaultContextName {
+			return nil, fmt.Errorf("%w: Docker contexts require Docker, so use `--host` to select a Podman endpoint", ErrInvalidConfig)
+		}
+		rt = NewPodmanRuntime(endpoint)
+	} else {
+		return nil, fmt.Errorf("%w, so please install Docker or Podman (in Docker compatibility mode) and try again", ErrDockerNotFound)
+	}
+
+	if err := rt.Check(); err != nil {
+		return nil, err
+	}
+	fmt.Fprintln(os.Stderr, "[+] Docker and the Compose plugin checks have passed")
+	return rt, nil
+}
+
+// cliRuntime runs Compose commands with a container engine's CLI and queries containers through the Docker-compatible
+// API selected by the DOCKER_HOST environment variable (or the default socket). DockerRuntime and PodmanRuntime only
+// differ in how they run Compose and check their installation.
+type cliRuntime struct {
+	// Name of the engine's CLI
+	name string
+	// Command and leading arguments for running Compose (e.g., "docker compose" or "podman-compose")
+	compose []string
+	// Engine endpoint shared by the Compose subprocesses and the API client
+	endpoint Endpoint
+	// Output of the Compose provider's "version" command, read the first time an override file is needed
+	composeVersion string
+}
+
+// Name returns the name of the engine's CLI.
+func (r *cliRuntime) Name() string {
+	return r.name
+}
+
+// composeArgs returns the command and arguments for running a Compose subcommand against the YAML file merged with
+// the override files.
+func (r *cliRuntime) composeArgs(yaml string, overrides []string, args []string) (string, []string) {
+	full := append([]string{}, r.compose[1:]...)
+	full = append(full, "-f", yaml)
+	for _, override := range overrides {
+		full = append(full, "-f", override)
+	}
+	return r.compose[0], append(full, args...)
+}
+
+// composeOverrides writes the override files for the current configuration and returns their paths. Returns an error
+// wrapping ErrInvalidConfig if an override file is needed but the Compose provider cannot read it.
+func (r *cliRuntime) composeOverrides() ([]string, error) {
+	overrides, err := writeComposeOverrides()
+	if err != nil || len(overrides) == 0 {
+		return overrides, err
+	}
+	if r.composeVersion == "" {
+		output, err := RunBasicCmdWithEnv(r.compose[0], append(append([]string{}, r.compose[1:]...), "version"), r.env())
+		if err != nil {
+			return nil, fmt.Errorf("failed to check the version of %s: %w", strings.Join(r.compose, " "), err)
+		}
+		r.composeVersion = output
+	}
+	if err := checkOverrideSupport(r.composeVersion); err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// runCompose runs a Compose subcommand against the YAML file and prints its output.
+func (r *cliRuntime) runCompose(yaml string, args ...string) error {
+	overrides, err := r.composeOverrides()
+	if err != nil {
+		return err
+	}
+	name, full := r.composeArgs(yaml, overrides, args)
+	return RunCmdWithEnv(name, full, r.composeEnv())
+}
+
+// Up creates and starts the services in detached mode.
+func (r *cliRuntime) Up(yaml string, services ...string) error {
+	return r.runCompose(yaml, append([]string{"up", "-d"}, services...)...)
+}
+
+// Recreate creates or recreates the services in detached mode without touching their dependencies.
+func (r *cliRuntime) Recreate(yaml string, services ...string) error {
+	return r.runCompose(yaml, append([]string{"up", "-d", "--no-deps"}, services...)...)
+}
+
+// Down stops and removes the containers and anything else selected by the options.
+func (r *cliRuntime) Down(yaml string, options DownOptions) error {
+	args := []string{"down"}
+	if options.Images {
+		args = append(args, "--rmi", "all")
+	}
+	if options.Volumes {
+		args = append(args, "--volumes")
+	}
+	if options.RemoveOrphans {
+		args = append(args, "--remove-orphans")
+	}
+	return r.runCompose(yaml, args...)
+}
+
+// Pull pulls the images for the services.
+func (r *cliRuntime) Pull(yaml string) error {
+	return r.runCompose(yaml, "pull")
+}
+
+// Build builds the images for the services.
+func (r *cliRuntime) Build(yaml string) error {
+	return r.runCompose(yaml, "build")
+}
+
+// Create creates the containers for the services without starting them, pulling images as needed.
+func (r *cliRuntime) Create(yaml string) error {
+	return r.runCompose(yaml, "create")
+}
+
+// Start starts the existing containers for the services.
+func (r *cliRuntime) Start(yaml string, services ...string) error {
+	return r.runCompose(yaml, append([]string{"start"}, services...)...)
+}
+
+// Stop stops the running containers for the services without removing them.
+func (r *cliRuntime) Stop(yaml string, services ...string) error {
+	return r.runCompose(yaml, append([]string{"stop"}, services...)...)
+}
+
+// Restart restarts the containers for all services.
+func (r *cliRuntime) Restart(yaml string) error {
+	return r.runCompose(yaml, "restart")
+}
+
+// Exec runs a command inside a running service without a TTY. The extra environment variables are given to Compose by
+// name, and Compose reads their values from its own environment.
+func (r *cliRuntime) Exec(yaml string, service string, command []string, streams ExecStreams) error {
+	args := []string{"exec", "-T"}
+	for _, variable := range streams.Env {
+		name, _, _ := strings.Cut(variable, "=")
+		args = append(args, "-e", name)
+	}
+	args = append(append(args, service), command...)
+	overrides, err := r.composeOverrides()
+	if err != nil {
+		return err
+	}
+	name, full := r.composeArgs(yaml, overrides, args)
+	return RunCmdWithIO(name, full, append(r.composeEnv(), streams.Env...), streams.Stdin, streams.Stdout, streams.Stderr)
+}
+
+// env returns the environment variables that point the engine's CLI at the endpoint.
+func (r *cliRuntime) env() []string {
+	return r.endpoint.CommandEnv(r.name)
+}
+
+// composeEnv returns the environment variables for Compose commands: the endpoint's variables plus the config values
+// the Docker YAML files read.
+func (r *cliRuntime) composeEnv() []string {
+	return append(r.env(), ComposeEnvironment()...)
+}
+
+// withClient connects to the engine's API and calls the function with the client.
+func (r *cliRuntime) withClient(fn func(cli *client.Client) error) error {
+	options, err := r.endpoint.ClientOptions()
+	if err != nil {
+		return err
+	}
+	cli, err := client.New(options...)
+	if err != nil {
+		return fmt.Errorf("failed to get client connection to %s: %w", r.name, err)
+	}
+	defer cli.Close()
+	return fn(cli)
+}
+
+// ListContainers returns the running containers, or every container if "all" is true.
+func (r *cliRuntime) ListContainers(ctx context.Context, all bool) ([]container.Summary, error) {
+	var containers []container.Summary
+	err := r.withClient(func(cli *client.Client) error {
+		result, err := cli.ContainerList(ctx, client.ContainerListOptions{All: all})
+		if err != nil {
+			return fmt.Errorf("failed to get container list from %s: %w", r.name, err)
+		}
+		containers = result.Items
+		return nil
+	})
+	return containers, err
+}
+
+// InspectContainer returns the full details of a container.
+func (r *cliRuntime) InspectContainer(ctx context.Context, id string) (container.InspectResponse, error) {
+	var inspect container.InspectResponse
+	err := r.withClient(func(cli *client.Client) error {
+		result, err := cli.ContainerInspect(ctx, id, client.ContainerInspectOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to inspect container: %w", err)
+		}
+		inspect = result.Container
+		return nil
+	})
+	return inspect, err
+}
+
+// ContainerLogs writes a container's logs to the writer, demultiplexing stdout and stderr unless the container uses
+// a TTY.
+func (r *cliRuntime) ContainerLogs(ctx context.Context, id string, options ContainerLogOptions, out io.Writer) error {
+	return r.withClient(func(cli *client.Client) error {
+		inspect, err := cli.ContainerInspect(ctx, id, client.ContainerInspectOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to inspect container: %w", err)
+		}
+
+		reader, err := cli.ContainerLogs(ctx, id, client.ContainerLogsOptions{
+			ShowStdout: true,
+			ShowStderr: true,
+			Tail:       options.Tail,
+			Since:      options.Since,
+			Until:      options.Until,
+			Timestamps: options.Timestamps,
+			Follow:     options.Follow,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get container logs: %w", err)
+		}
+		defer reader.Close()
+
+		if inspect.Container.Config != nil && inspect.Container.Config.Tty {
+			_, err = io.Copy(out, reader)
+		} else {
+			_, err = stdcopy.StdCopy(out, out, reader)
+		}
+		if err != nil && ctx.Err() == nil {
+			return fmt.Errorf("failed to read container logs: %w", err)
+		}
+		return nil
+	})
+}
+
+// CopyFromContainer writes a tar archive of a path inside a container to the writer.
+func (r *cliRuntime) CopyFromContainer(ctx context.Context, id string, path string, out io.Writer) error {
+	return r.withClient(func(cli *client.Client) error {
+		result, err := cli.CopyFromContainer(ctx, id, client.CopyFromContainerOptions{
+			SourcePath: path,
+		})
+		if err != nil {
+			return err
+		}
+		defer result.Content.Close()
+		_, err = io.Copy(out, result.Content)
+		return err
+	})
+}
+
+// CopyToContainer extracts a tar archive into a directory inside a container, keeping the archive's file ownership.
+func (r *cliRuntime) CopyToContainer(ctx context.Context, id string, dir string, content io.Reader) error {
+	return r.withClient(func(cli *client.Client) error {
+		_, err := cli.CopyToContainer(ctx, id, client.CopyToContainerOptions{
+			DestinationPath: dir,
+			Content:         content,
+			CopyUIDGID:      true,
+		})
+		return err
+	})
+}
+
+// InspectImage returns the full details of a local image.
+func (r *cliRuntime) InspectImage(ctx context.Context, id string) (image.InspectResponse, error) {
+	var inspect image.InspectResponse
+	err := r.withClient(func(cli *client.Client) error {
+		result, err := cli.ImageInspect(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to inspect image: %w", err)
+		}
+		inspect = result.InspectResponse
+		return nil
+	})
+	return inspect, err
+}
+
+// TagImage adds a reference to a local image.
+func (r *cliRuntime) TagImage(ctx context.Context, image string, reference string) error {
+	return r.withClient(func(cli *client.Client) error {
+		if _, err := cli.ImageTag(ctx, client.ImageTagOptions{Source: image, Target: reference}); err != nil {
+			return fmt.Errorf("failed to tag image %s as %s: %w", image, reference, err)
+		}
+		return nil
+	})
+}
+
+// DockerRuntime runs BloodHound with Docker and the Docker Compose v2 plugin.
+type DockerRuntime struct {
+	cliRuntime
+}
+
+// NewDockerRuntime returns a DockerRuntime that runs Compose with `docker compose` against the endpoint.
+func NewDockerRuntime(endpoint Endpoint) *DockerRuntime {
+	return &DockerRuntime{cliRuntime{name: "docker", compose: []string{"docker", "compose"}, endpoint: endpoint}}
+}
+
+// Check verifies that the Docker daemon is running and the Compose v2 plugin is installed.
+func (r *DockerRuntime) Check() error {
+	// Check if the Docker Engine is running
+	_, engineErr := RunBasicCmdWithEnv("docker", []string{"info"}, r.env())
+	if engineErr != nil {
+		return fmt.Errorf("docker is installed on this system, but %w", ErrDaemonUnavailable)
+	}
+
+	// Check for the ``compose`` plugin as our first choice
+	_, composeErr := RunBasicCmdWithEnv("docker", []string{"compose", "version"}, r.env())
+	if composeErr != nil {
+		// Check if the deprecated v1 script is installed
+		if CheckPath("docker-compose") {
+			fmt.Fprintln(os.Stderr, "[!] The deprecated `docker-compose` v1 script was detected on your system")
+			fmt.Fprintln(os.Stderr, "[!] Docker has deprecated v1 and this CLI tool no longer supports it")
+			return fmt.Errorf("%w (v2), so please upgrade to Docker Compose v2 and try again: https://docs.docker.com/compose/install/", ErrComposeMissing)
+		}
+		return fmt.Errorf("%w, so please install it and try again: https://docs.docker.com/compose/install/", ErrComposeMissing)
+	}
+	return nil
+}
+
+// PodmanRuntime runs BloodHound with Podman, using either the native `podman compose` command or the standalone
+// `podman-compose` script. Container queries require Podman's Docker-compatible API socket.
+type PodmanRuntime struct {
+	cliRuntime
+}
+
+// NewPodmanRuntime returns a PodmanRuntime that runs Compose with `podman compose` if it works, or with
+// `podman-compose` if that is installed instead. Both run against the endpoint.
+func NewPodmanRuntime(endpoint Endpoint) *PodmanRuntime {
+	rt := &PodmanRuntime{cliRuntime{name: "podman", endpoint: endpoint}}
+	if _, err := RunBasicCmdWithEnv("podman", []string{"compose", "version"}, rt.env()); err == nil {
+		rt.compose = []string{"podman", "compose"}
+	} else if CheckPath("podman-compose") {
+		rt.compose = []string{"podman-compose"}
+	}
+	return rt
+}
+
+// Check verifies that Podman is usable and that a Compose provider was found.
+func (r *PodmanRuntime) Check() error {
+	_, engineErr := RunBasicCmdWithEnv("podman", []string{"info"}, r.env())
+	if engineErr != nil {
+		return fmt.Errorf("podman is installed on this system, but %w", ErrDaemonUnavailable)
+	}
+	if len(r.compose) == 0 {
+		return fmt.Errorf("%w, so please install `podman-compose` or a provider for `podman compose` and try again", ErrComposeMissing)
+	}
+	return nil
+}
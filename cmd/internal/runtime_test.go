@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SpecterOps/BloodHound_CLI/cmd/internal/runtimetest"
+	"github.com/moby/moby/api/types/container"
+	"github.com/stretchr/testify/assert"
+)
+
+// newFakeStack returns a runtimetest.FakeRuntime with running and healthy BloodHound containers plus an unrelated container.
+func newFakeStack() *runtimetest.FakeRuntime {
+	rt := runtimetest.NewFakeRuntime()
+	for _, name := range prodImages {
+		rt.Containers = append(rt.Containers, container.Summary{
+			ID: name + "-id", Image: name + ":latest", State: container.StateRunning, Status: "Up 1 minute",
+			Labels: map[string]string{"name": name},
+		})
+		rt.Inspects[name+"-id"] = container.InspectResponse{
+			ID:    name + "-id",
+			State: &container.State{Running: true, Health: &container.Health{Status: container.Healthy}},
+		}
+	}
+	rt.Containers = append(rt.Containers, container.Summary{ID: "other-id", Image: "nginx", State: container.StateRunning})
+	return rt
+}
+
+// writeTestYaml writes an empty Docker YAML file to a temporary directory and returns its path.
+func writeTestYaml(t *testing.T) string {
+	yaml := filepath.Join(t.TempDir(), "docker-compose.yml")
+	assert.NoError(t, os.WriteFile(yaml, []byte("services: {}\n"), 0600))
+	return yaml
+}
+
+func TestComposeArgs(t *testing.T) {
+	docker := NewDockerRuntime(Endpoint{})
+	name, args := docker.composeArgs("docker-compose.yml", nil, []string{"up", "-d"})
+	assert.Equal(t, "docker", name)
+	assert.Equal(t, []string{"compose", "-f", "docker-compose.yml", "up", "-d"}, args)
+	_, args = docker.composeArgs("docker-compose.yml", []string{"override.yml"}, []string{"up", "-d"})
+	assert.Equal(t, []string{"compose", "-f", "docker-compose.yml", "-f", "override.yml", "up", "-d"}, args, "Expected the override files after the YAML file")
+
+	podman := &PodmanRuntime{cliRuntime{name: "podman", compose: []string{"podman-compose"}}}
+	name, args = podman.composeArgs("docker-compose.yml", nil, []string{"exec", "-T", "app-db"})
+	assert.Equal(t, "podman-compose", name)
+	assert.Equal(t, []string{"-f", "docker-compose.yml", "exec", "-T", "app-db"}, args)
+
+	missing := &PodmanRuntime{cliRuntime{name: "podman"}}
+	if CheckPath("podman") {
+		assert.True(t, errors.Is(missing.Check(), ErrComposeMissing), "Expected `Check()` to require a Compose provider")
+	}
+}
+
+func TestFakeRuntimeListContainers(t *testing.T) {
+	rt := newFakeStack()
+	rt.Containers[0].State = container.StateExited
+
+	running, err := rt.ListContainers(context.Background(), false)
+	assert.NoError(t, err)
+	assert.Len(t, running, 3, "Expected stopped containers to be left out")
+
+	all, err := rt.ListContainers(context.Background(), true)
+	assert.NoError(t, err)
+	assert.Len(t, all, 4)
+}
+
+func TestGetRunning(t *testing.T) {
+	rt := newFakeStack()
+	running, err := GetRunning(rt)
+	assert.NoError(t, err)
+	assert.Len(t, running, 3, "Expected only the BloodHound containers")
+	assert.Equal(t, "bhce_bloodhound", running[0].Name)
+
+	rt.Errors["ListContainers"] = errors.New("daemon went away")
+	_, err = GetRunning(rt)
+	assert.Error(t, err)
+}
+
+func TestComposeCommands(t *testing.T) {
+	defer quietTests()()
+	yaml := writeTestYaml(t)
+	rt := runtimetest.NewFakeRuntime()
+
+	assert.NoError(t, RunDockerComposeUp(rt, yaml))
+	assert.NoError(t, RunDockerComposeDown(rt, yaml, true))
+	assert.NoError(t, RunDockerComposeUpgrade(rt, yaml))
+	assert.Equal(t, []string{"up -d", "down --volumes", "down", "build", "up -d"}, rt.Commands)
+
+	rt.Errors["Pull"] = errors.New("registry unavailable")
+	assert.Error(t, RunDockerComposePull(rt, yaml))
+
+	missing := filepath.Join(t.TempDir(), "missing.yml")
+	assert.True(t, errors.Is(RunDockerComposeStart(rt, missing), ErrYamlMissing), "Expected a missing YAML file to be reported")
+}
+
+func TestCheckHealthWithFakeRuntime(t *testing.T) {
+	defer quietTests()()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	original := bhEnv.GetString("root_url")
+	bhEnv.Set("root_url", server.URL)
+	defer bhEnv.Set("root_url", original)
+
+	yaml := writeTestYaml(t)
+	rt := newFakeStack()
+	issues, err := CheckHealth(rt, yaml)
+	assert.NoError(t, err)
+	assert.Empty(t, issues, "Expected a healthy stack to have no issues")
+	assert.Contains(t, rt.Commands, "exec -T app-db sh -c "+pgReadyCmd)
+	assert.Contains(t, rt.Commands, "exec -T graph-db "+strings.Join(neo4jBoltCmd, " "), "The unpublished bolt port should be probed inside the Neo4j container")
+
+	rt.ExecErrors["graph-db"] = errors.New("exit status 1")
+	issues, err = CheckHealth(rt, yaml)
+	assert.NoError(t, err)
+	assert.True(t, issues.HasErrors(), "Expected a closed bolt port to be an error")
+	delete(rt.ExecErrors, "graph-db")
+
+	rt.Errors["Exec"] = errors.New("exit status 2")
+	issues, err = CheckHealth(rt, yaml)
+	assert.NoError(t, err)
+	assert.True(t, issues.HasErrors(), "Expected a failed `pg_isready` to be an error")
+}
+
+func TestFetchLogsWithFakeRuntime(t *testing.T) {
+	rt := newFakeStack()
+	rt.Logs["bhce_neo4j-id"] = "Started.\n"
+	rt.Logs["bhce_postgres-id"] = "ready\n"
+
+	var out bytes.Buffer
+	assert.NoError(t, FetchLogs(context.Background(), rt, "neo4j", LogOptions{Tail: "10"}, &out))
+	assert.Equal(t, "bhce_neo4j | Started.\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, FetchLogs(context.Background(), rt, "all", LogOptions{Tail: "10"}, &out))
+	assert.Equal(t, "bhce_neo4j      | Started.\nbhce_postgres   | ready\n", out.String())
+
+	assert.Error(t, FetchLogs(context.Background(), rt, "nginx", LogOptions{}, &out), "Expected non-BloodHound containers to be ignored")
+}
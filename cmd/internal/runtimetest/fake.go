@@ -0,0 +1,274 @@
+package runtimetest
+
+// An in-memory container runtime for running commands in tests without a container engine
+// Only tests import this package, so the fake is not built into the BloodHound CLI binary
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/SpecterOps/BloodHound_CLI/cmd/internal/engine"
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/image"
+)
+
+// Ensure the fake implements the Runtime interface
+var _ engine.Runtime = (*FakeRuntime)(nil)
+
+// FakeRuntime is an in-memory engine.Runtime. It records every Compose command it receives and answers container queries
+// from its fields. Set an entry in Errors to make the method with that name (e.g., "Up") fail.
+type FakeRuntime struct {
+	mu sync.Mutex
+
+	// Containers returned by ListContainers; only containers in the "running" state are returned unless all are requested
+	Containers []container.Summary
+	// Inspect data returned by InspectContainer, keyed by container ID
+	Inspects map[string]container.InspectResponse
+	// Log output written by ContainerLogs, keyed by container ID
+	Logs map[string]string
+	// Output written by Exec, keyed by service name
+	ExecOutput map[string]string
+	// Errors returned by Exec, keyed by service name
+	ExecErrors map[string]error
+	// Extra environment variables given to Exec, in order
+	ExecEnv []string
+	// Tar archives returned by CopyFromContainer and stored by CopyToContainer, keyed by container ID
+	Archives map[string][]byte
+	// Image details returned by InspectImage, keyed by image ID
+	Images map[string]image.InspectResponse
+	// Image IDs tagged by TagImage, keyed by reference
+	Tags map[string]string
+	// Errors returned by the method with the matching name
+	Errors map[string]error
+	// Every Compose command received, formatted like the Compose CLI's arguments (e.g., "up -d app-db")
+	Commands []string
+}
+
+// NewFakeRuntime returns an empty FakeRuntime.
+func NewFakeRuntime() *FakeRuntime {
+	return &FakeRuntime{
+		Inspects:   map[string]container.InspectResponse{},
+		Logs:       map[string]string{},
+		ExecOutput: map[string]string{},
+		ExecErrors: map[string]error{},
+		Archives:   map[string][]byte{},
+		Images:     map[string]image.InspectResponse{},
+		Tags:       map[string]string{},
+		Errors:     map[string]error{},
+	}
+}
+
+// record saves a Compose command and returns the error configured for the method.
+func (f *FakeRuntime) record(method string, args ...string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Commands = append(f.Commands, strings.Join(args, " "))
+	return f.Errors[method]
+}
+
+// failure returns the error configured for the method.
+func (f *FakeRuntime) failure(method string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.Errors[method]
+}
+
+// Name returns "fake".
+func (f *FakeRuntime) Name() string {
+	return "fake"
+}
+
+// Check returns the error configured for "Check".
+func (f *FakeRuntime) Check() error {
+	return f.failure("Check")
+}
+
+// Up records an "up -d" command.
+func (f *FakeRuntime) Up(yaml string, services ...string) error {
+	return f.record("Up", append([]string{"up", "-d"}, services...)...)
+}
+
+// Recreate records an "up" command that leaves the dependencies alone.
+func (f *FakeRuntime) Recreate(yaml string, services ...string) error {
+	return f.record("Recreate", append([]string{"up", "-d", "--no-deps"}, services...)...)
+}
+
+// Down records a "down" command with the flags for the options.
+func (f *FakeRuntime) Down(yaml string, options engine.DownOptions) error {
+	args := []string{"down"}
+	if options.Images {
+		args = append(args, "--rmi", "all")
+	}
+	if options.Volumes {
+		args = append(args, "--volumes")
+	}
+	if options.RemoveOrphans {
+		args = append(args, "--remove-orphans")
+	}
+	return f.record("Down", args...)
+}
+
+// Pull records a "pull" command.
+func (f *FakeRuntime) Pull(yaml string) error {
+	return f.record("Pull", "pull")
+}
+
+// Build records a "build" command.
+func (f *FakeRuntime) Build(yaml string) error {
+	return f.record("Build", "build")
+}
+
+// Create records a "create" command.
+func (f *FakeRuntime) Create(yaml string) error {
+	return f.record("Create", "create")
+}
+
+// Start records a "start" command.
+func (f *FakeRuntime) Start(yaml string, services ...string) error {
+	return f.record("Start", append([]string{"start"}, services...)...)
+}
+
+// Stop records a "stop" command.
+func (f *FakeRuntime) Stop(yaml string, services ...string) error {
+	return f.record("Stop", append([]string{"stop"}, services...)...)
+}
+
+// Restart records a "restart" command.
+func (f *FakeRuntime) Restart(yaml string) error {
+	return f.record("Restart", "restart")
+}
+
+// Exec records an "exec" command with the names of the extra environment variables, drains stdin, and writes the
+// service's configured output to stdout.
+func (f *FakeRuntime) Exec(yaml string, service string, command []string, streams engine.ExecStreams) error {
+	args := []string{"exec", "-T"}
+	for _, variable := range streams.Env {
+		name, _, _ := strings.Cut(variable, "=")
+		args = append(args, "-e", name)
+	}
+	if err := f.record("Exec", append(append(args, service), command...)...); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	f.ExecEnv = append(f.ExecEnv, streams.Env...)
+	err := f.ExecErrors[service]
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if streams.Stdin != nil {
+		if _, err := io.Copy(io.Discard, streams.Stdin); err != nil {
+			return err
+		}
+	}
+	if streams.Stdout != nil {
+		f.mu.Lock()
+		output := f.ExecOutput[service]
+		f.mu.Unlock()
+		if _, err := io.WriteString(streams.Stdout, output); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListContainers returns the configured containers, leaving out stopped containers unless "all" is true.
+func (f *FakeRuntime) ListContainers(ctx context.Context, all bool) ([]container.Summary, error) {
+	if err := f.failure("ListContainers"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var containers []container.Summary
+	for _, c := range f.Containers {
+		if all || c.State == container.StateRunning {
+			containers = append(containers, c)
+		}
+	}
+	return containers, nil
+}
+
+// InspectContainer returns the configured inspect data for the container.
+func (f *FakeRuntime) InspectContainer(ctx context.Context, id string) (container.InspectResponse, error) {
+	if err := f.failure("InspectContainer"); err != nil {
+		return container.InspectResponse{}, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	inspect, ok := f.Inspects[id]
+	if !ok {
+		return container.InspectResponse{}, fmt.Errorf("no such container: %s", id)
+	}
+	return inspect, nil
+}
+
+// ContainerLogs writes the configured logs for the container. The options are ignored.
+func (f *FakeRuntime) ContainerLogs(ctx context.Context, id string, options engine.ContainerLogOptions, out io.Writer) error {
+	if err := f.failure("ContainerLogs"); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	logs := f.Logs[id]
+	f.mu.Unlock()
+	_, err := io.WriteString(out, logs)
+	return err
+}
+
+// CopyFromContainer writes the configured archive for the container. The path is ignored.
+func (f *FakeRuntime) CopyFromContainer(ctx context.Context, id string, path string, out io.Writer) error {
+	if err := f.failure("CopyFromContainer"); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	archive, ok := f.Archives[id]
+	f.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no such container: %s", id)
+	}
+	_, err := io.Copy(out, bytes.NewReader(archive))
+	return err
+}
+
+// CopyToContainer stores the archive as the container's archive. The directory is ignored.
+func (f *FakeRuntime) CopyToContainer(ctx context.Context, id string, dir string, content io.Reader) error {
+	if err := f.failure("CopyToContainer"); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Archives[id] = data
+	return nil
+}
+
+// InspectImage returns the configured details for the image.
+func (f *FakeRuntime) InspectImage(ctx context.Context, id string) (image.InspectResponse, error) {
+	if err := f.failure("InspectImage"); err != nil {
+		return image.InspectResponse{}, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	inspect, ok := f.Images[id]
+	if !ok {
+		return image.InspectResponse{}, fmt.Errorf("no such image: %s", id)
+	}
+	return inspect, nil
+}
+
+// TagImage stores the image as the reference's image.
+func (f *FakeRuntime) TagImage(ctx context.Context, image string, reference string) error {
+	if err := f.failure("TagImage"); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Tags[reference] = image
+	return nil
+}
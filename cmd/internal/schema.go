@@ -0,0 +1,193 @@
+package internal
+
+// The schema of the JSON config file
+// Every supported key is listed here with its type, default, and documentation, and `config set` validates against it
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/client"
+)
+
+// Types of config values
+var (
+	typeString   = "string"
+	typeBool     = "bool"
+	typeInt      = "int"
+	typeDuration = "duration"
+	// A "host:port" listen address; the host may be empty
+	typeAddress = "address"
+	// An absolute http or https URL
+	typeUrl = "url"
+	// A container engine URL like DOCKER_HOST (e.g., "unix:///var/run/docker.sock" or "ssh://user@host")
+	typeEngineHost = "engine-host"
+	// A database credential, which the Docker YAML files put into connection URLs and strings as it is
+	typeCredential = "credential"
+)
+
+// Characters that would break the database connection URLs and strings if they were in a credential
+var unsafeCredentialChars = "@/:?# \t\r\n"
+
+// ConfigKey describes a key in the JSON config file.
+type ConfigKey struct {
+	Key  string `json:"key"`
+	Type string `json:"type"`
+	// Allowed values, if the key only accepts a fixed set; matched without regard to case
+	Allowed []string `json:"allowed,omitempty"`
+	// Default value, or nil if the key has no default
+	Default interface{} `json:"default,omitempty"`
+	// Description of the default for keys whose default is not a fixed value
+	DefaultDescription string `json:"default_description,omitempty"`
+	Description        string `json:"description"`
+	// Whether the value is a secret that is redacted and can be kept in a secret store
+	Secret bool `json:"secret"`
+	// Whether the value is managed by the CLI and cannot be changed with `config set`
+	ReadOnly bool `json:"read_only"`
+}
+
+// Aliases for config keys, mapped to the key they refer to
+var configAliases = map[string]string{
+	"default_password": "default_admin.password",
+}
+
+// Keys of the JSON config file
+var configSchema = []ConfigKey{
+	{Key: "version", Type: typeInt, Default: currentConfigVersion, ReadOnly: true, Description: "Version of the config file's layout, used to migrate files written by older versions of BloodHound CLI"},
+	{Key: "config_directory", Type: typeString, DefaultDescription: "the OS's user config directory plus \"bloodhound\"", ReadOnly: true, Description: "Directory with the JSON config file and the Docker YAML files"},
+	{Key: "compose_source", Type: typeString, Allowed: []string{composeSourceEmbedded, composeSourceDownloaded}, DefaultDescription: "set when the CLI writes the Docker YAML files", ReadOnly: true, Description: "Where the Docker YAML files came from: the copies built into the binary (embedded) or the release on GitHub (downloaded)"},
+	{Key: "compose_project", Type: typeString, DefaultDescription: "the name of the config directory, which Compose uses by default", ReadOnly: true, Description: "Compose project name of the BloodHound containers and volumes, recorded by `config move-dir` so the moved YAML files keep using them"},
+	{Key: "default_admin.principal_name", Type: typeString, Default: "admin", Description: "Name of the default admin user created by the first start"},
+	{Key: "default_admin.password", Type: typeString, DefaultDescription: "a random 32-character password", Secret: true, Description: "Password of the default admin user created by the first start or `resetpwd`"},
+	{Key: "bind_addr", Type: typeAddress, Default: "0.0.0.0:8080", Description: "Address the BloodHound server listens on inside its container"},
+	{Key: "metrics_port", Type: typeAddress, Default: ":2112", Description: "Address the BloodHound metrics endpoint listens on inside its container"},
+	{Key: "root_url", Type: typeUrl, Default: "http://127.0.0.1:8080", Description: "URL users open to reach BloodHound; the CLI also uses it for health checks"},
+	{Key: "work_dir", Type: typeString, Default: "/opt/bloodhound/work", Description: "Working directory of the BloodHound server inside its container"},
+	{Key: "log_level", Type: typeString, Allowed: []string{"DEBUG", "INFO", "WARN", "ERROR"}, Default: "INFO", Description: "Minimum level of the BloodHound server's log entries"},
+	{Key: "log_path", Type: typeString, Default: "bloodhound.log", Description: "Log file of the BloodHound server inside its container"},
+	{Key: "collectors_base_path", Type: typeString, Default: "/etc/bloodhound/collectors", Description: "Directory with the collector downloads inside the BloodHound container"},
+	{Key: "tls.cert_file", Type: typeString, Default: "", Description: "Certificate file for serving BloodHound over HTTPS, inside the BloodHound container"},
+	{Key: "tls.key_file", Type: typeString, Default: "", Description: "Private key file for serving BloodHound over HTTPS, inside the BloodHound container"},
+	{Key: "docker_host", Type: typeEngineHost, Default: "", Description: "Container engine to manage; empty uses DOCKER_HOST, DOCKER_CONTEXT, or Docker's current context"},
+	{Key: "permissions_mode", Type: typeString, Allowed: []string{permissionsPrivate, permissionsShared}, Default: permissionsPrivate, Description: "Permissions for the config directory and files: only your user (private) or also your group (shared)"},
+	{Key: "secret_store", Type: typeString, Allowed: []string{secretStoreConfig, secretStoreKeyring, secretStoreFile}, Default: secretStoreConfig, Description: "Where secrets are kept: this file (config), the OS keyring (keyring), or an encrypted file (file)"},
+	{Key: "wait_timeout", Type: typeDuration, Default: "5m", Description: "Default for the `--timeout` flag, which controls how long commands wait for the services to become healthy"},
+	{Key: "database.postgres_user", Type: typeCredential, DefaultDescription: "the YAML file's default (bloodhound)", Description: "Postgres user for the BloodHound database"},
+	{Key: "database.postgres_password", Type: typeCredential, DefaultDescription: "generated by `install` for new deployments", Secret: true, Description: "Postgres password; use `rotate-secrets` to change it for a running deployment"},
+	{Key: "database.postgres_db", Type: typeCredential, DefaultDescription: "the YAML file's default (bloodhound)", Description: "Name of the Postgres database"},
+	{Key: "neo4j.user", Type: typeCredential, DefaultDescription: "the YAML file's default (neo4j)", Description: "Neo4j user"},
+	{Key: "neo4j.secret", Type: typeCredential, DefaultDescription: "generated by `install` for new deployments", Secret: true, Description: "Neo4j password; use `rotate-secrets` to change it for a running deployment"},
+	{Key: "neo4j.publish_ports", Type: typeBool, Default: true, Description: "Publish the Neo4j bolt and web ports on 127.0.0.1; `install --secure` turns this off"},
+	{Key: "bloodhound.host", Type: typeString, DefaultDescription: "the YAML file's default (127.0.0.1)", Description: "Host address the BloodHound port is published on"},
+	{Key: "bloodhound.port", Type: typeInt, DefaultDescription: "the YAML file's default (8080)", Description: "Host port BloodHound is published on"},
+	{Key: "bloodhound.tag", Type: typeString, DefaultDescription: "the YAML file's default (latest)", Description: "Tag of the BloodHound image"},
+}
+
+// LookupConfigKey returns the schema for a key or alias, matched without regard to case.
+func LookupConfigKey(key string) (ConfigKey, bool) {
+	key = strings.ToLower(key)
+	if target, ok := configAliases[key]; ok {
+		key = target
+	}
+	for _, entry := range configSchema {
+		if entry.Key == key {
+			return entry, true
+		}
+	}
+	return ConfigKey{}, false
+}
+
+// ConfigSchema returns the schema of every key, sorted by key.
+func ConfigSchema() []ConfigKey {
+	schema := append([]ConfigKey{}, configSchema...)
+	sort.Slice(schema, func(i, j int) bool { return schema[i].Key < schema[j].Key })
+	return schema
+}
+
+// Parse converts a value from the command line to the key's type. Returns an error wrapping ErrInvalidConfig if the
+// value is not valid for the key.
+func (c ConfigKey) Parse(value string) (interface{}, error) {
+	if len(c.Allowed) > 0 {
+		for _, allowed := range c.Allowed {
+			if strings.EqualFold(value, allowed) {
+				return allowed, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: `%s` must be one of %s, not `%s`", ErrInvalidConfig, c.Key, strings.Join(c.Allowed, ", "), value)
+	}
+
+	switch c.Type {
+	case typeBool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: `%s` must be true or false, not `%s`", ErrInvalidConfig, c.Key, value)
+		}
+		return parsed, nil
+	case typeInt:
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: `%s` must be a whole number, not `%s`", ErrInvalidConfig, c.Key, value)
+		}
+		return parsed, nil
+	case typeDuration:
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("%w: `%s` must be a duration like 90s or 5m, not `%s`", ErrInvalidConfig, c.Key, value)
+		}
+		return parsed.String(), nil
+	case typeAddress:
+		host, port, err := net.SplitHostPort(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: `%s` must be an address like 0.0.0.0:8080 or :8080, not `%s`", ErrInvalidConfig, c.Key, value)
+		}
+		if number, err := strconv.Atoi(port); err != nil || number < 1 || number > 65535 {
+			return nil, fmt.Errorf("%w: `%s` has an invalid port: `%s`", ErrInvalidConfig, c.Key, port)
+		}
+		// The host can be empty, an IP address, or a hostname
+		if strings.ContainsAny(host, " /") {
+			return nil, fmt.Errorf("%w: `%s` has an invalid host: `%s`", ErrInvalidConfig, c.Key, host)
+		}
+		return value, nil
+	case typeUrl:
+		parsed, err := url.Parse(value)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("%w: `%s` must be an http or https URL like http://127.0.0.1:8080, not `%s`", ErrInvalidConfig, c.Key, value)
+		}
+		return strings.TrimSuffix(value, "/"), nil
+	case typeCredential:
+		if strings.ContainsAny(value, unsafeCredentialChars) {
+			return nil, fmt.Errorf("%w: `%s` cannot contain spaces or any of @ / : ? # because it is used in the database connection URLs", ErrInvalidConfig, c.Key)
+		}
+		return value, nil
+	case typeEngineHost:
+		if value == "" {
+			return value, nil
+		}
+		if _, err := client.ParseHostURL(value); err != nil {
+			return nil, fmt.Errorf("%w: `%s` must be a container engine URL like ssh://user@host: %w", ErrInvalidConfig, c.Key, err)
+		}
+		return value, nil
+	}
+	return value, nil
+}
+
+// DefaultString describes the key's default for documentation.
+func (c ConfigKey) DefaultString() string {
+	if c.DefaultDescription != "" {
+		return c.DefaultDescription
+	}
+	if c.Default == nil || fmt.Sprint(c.Default) == "" {
+		return "none"
+	}
+	return fmt.Sprint(c.Default)
+}
+
+// EnvVar returns the environment variable the Docker YAML files read the key's value from, if any.
+func (c ConfigKey) EnvVar() string {
+	return composeEnvVar(c.Key)
+}
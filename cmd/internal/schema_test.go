@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"errors"
+	"github.com/SpecterOps/BloodHound_CLI/cmd/internal/runtimetest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestConfigKeyParse(t *testing.T) {
+	valid := []struct {
+		key   string
+		value string
+		want  interface{}
+	}{
+		{"bloodhound.port", "8443", 8443},
+		{"wait_timeout", "90s", "1m30s"},
+		{"bind_addr", "0.0.0.0:8080", "0.0.0.0:8080"},
+		{"metrics_port", ":2112", ":2112"},
+		{"root_url", "https://bloodhound.example.com/", "https://bloodhound.example.com"},
+		{"log_level", "debug", "DEBUG"},
+		{"docker_host", "ssh://user@host", "ssh://user@host"},
+		{"docker_host", "", ""},
+		{"log_path", "bhce.log", "bhce.log"},
+		{"neo4j.secret", "Sup3r-Secret_", "Sup3r-Secret_"},
+	}
+	for _, test := range valid {
+		entry, ok := LookupConfigKey(test.key)
+		assert.True(t, ok, "`%s` should be in the schema", test.key)
+		parsed, err := entry.Parse(test.value)
+		assert.NoError(t, err, "`Parse()` should accept `%s` for `%s`", test.value, test.key)
+		assert.Equal(t, test.want, parsed, "`Parse()` should convert `%s` for `%s`", test.value, test.key)
+	}
+
+	invalid := []struct {
+		key   string
+		value string
+	}{
+		{"bloodhound.port", "eighty"},
+		{"wait_timeout", "5"},
+		{"wait_timeout", "-1m"},
+		{"bind_addr", "8080"},
+		{"bind_addr", "0.0.0.0:99999"},
+		{"root_url", "127.0.0.1:8080"},
+		{"root_url", "ftp://127.0.0.1"},
+		{"log_level", "TRACE"},
+		{"docker_host", "not a url"},
+		{"neo4j.secret", "p@ssword"},
+		{"neo4j.user", "neo4j/admin"},
+		{"database.postgres_password", "pass word"},
+		{"database.postgres_password", "what?#"},
+	}
+	for _, test := range invalid {
+		entry, _ := LookupConfigKey(test.key)
+		_, err := entry.Parse(test.value)
+		assert.True(t, errors.Is(err, ErrInvalidConfig), "`Parse()` should reject `%s` for `%s`", test.value, test.key)
+	}
+}
+
+func TestConfigKeyParseBool(t *testing.T) {
+	entry := ConfigKey{Key: "example", Type: typeBool}
+	parsed, err := entry.Parse("TRUE")
+	assert.NoError(t, err, "`Parse()` should accept a boolean")
+	assert.Equal(t, true, parsed, "`Parse()` should convert a boolean")
+	_, err = entry.Parse("maybe")
+	assert.True(t, errors.Is(err, ErrInvalidConfig), "`Parse()` should reject a value that is not a boolean")
+}
+
+func TestLookupConfigKey(t *testing.T) {
+	entry, ok := LookupConfigKey("DEFAULT_PASSWORD")
+	assert.True(t, ok, "`LookupConfigKey()` should resolve aliases without regard to case")
+	assert.Equal(t, "default_admin.password", entry.Key, "`default_password` should be an alias for `default_admin.password`")
+	assert.True(t, entry.Secret, "`default_admin.password` should be a secret")
+
+	_, ok = LookupConfigKey("not_a_real_setting")
+	assert.False(t, ok, "`LookupConfigKey()` should not find an unknown key")
+
+	for _, entry := range ConfigSchema() {
+		assert.NotEmpty(t, entry.Description, "`%s` should have a description", entry.Key)
+		assert.NotEmpty(t, entry.DefaultString(), "`%s` should describe its default", entry.Key)
+	}
+}
+
+func TestSetConfigValidation(t *testing.T) {
+	err := SetConfig("bind_addr", "localhost", false)
+	assert.True(t, errors.Is(err, ErrInvalidConfig), "`SetConfig()` should refuse an invalid address")
+
+	err = SetConfig("not_a_real_setting", "value", false)
+	assert.True(t, errors.Is(err, ErrInvalidConfig), "`SetConfig()` should refuse an unknown key without `force`")
+	assert.False(t, bhEnv.IsSet("not_a_real_setting"), "`SetConfig()` should not set a refused key")
+}
+
+func TestCheckCredentialChange(t *testing.T) {
+	rt := newFakeStack()
+	err := CheckCredentialChange(rt, "neo4j.secret")
+	assert.ErrorIs(t, err, ErrInvalidConfig, "A database credential should not be changed while BloodHound containers exist")
+	assert.ErrorContains(t, err, "rotate-secrets")
+	assert.NoError(t, CheckCredentialChange(rt, "log_level"), "Other keys should be changeable while containers exist")
+	assert.NoError(t, CheckCredentialChange(runtimetest.NewFakeRuntime(), "database.postgres_password"), "A database credential should be changeable before the first install")
+}
@@ -0,0 +1,416 @@
+package internal
+
+// Functions for keeping secret config values out of the JSON config file
+// Secrets are stored in the OS keyring or a passphrase-encrypted file based on the `secret_store` config value
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Vars for the secret stores
+var (
+	// Config keys with secret values and their aliases, which viper includes in the settings with the secret's value
+	secretKeys, secretAliases = schemaSecretKeys()
+	// Values of the `secret_store` config key
+	secretStoreConfig  = "config"
+	secretStoreKeyring = "keyring"
+	secretStoreFile    = "file"
+	// Service name used for the keyring entries
+	keyringService = "bloodhound-cli"
+	// Name of the encrypted file in the config directory and the environment variable with its passphrase
+	secretFileName       = "secrets.enc"
+	secretPassphraseVar  = "BLOODHOUND_SECRETS_PASSPHRASE"
+	secretFileIterations = 600000
+	// Placeholder shown instead of secret values
+	redactedValue = "********"
+)
+
+// Values known to be in the secret store, so unchanged secrets are not written again
+var storedSecrets = map[string]string{}
+
+// errSecretNotFound means the secret store has no value for a key
+var errSecretNotFound = errors.New("secret not found")
+
+// SecretStore saves secret config values outside the JSON config file.
+type SecretStore interface {
+	// Name returns the `secret_store` value for the store
+	Name() string
+	// Get returns the value for the key or an error wrapping errSecretNotFound
+	Get(key string) (string, error)
+	// Set saves the value for the key
+	Set(key string, value string) error
+}
+
+// schemaSecretKeys returns the keys the schema marks as secrets and the aliases for them.
+func schemaSecretKeys() ([]string, []string) {
+	var keys, aliases []string
+	for _, entry := range configSchema {
+		if entry.Secret {
+			keys = append(keys, entry.Key)
+		}
+	}
+	for alias, key := range configAliases {
+		if Contains(keys, key) {
+			aliases = append(aliases, alias)
+		}
+	}
+	return keys, aliases
+}
+
+// IsSecretKey reports whether the config key holds a secret value.
+func IsSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	return Contains(secretKeys, key) || Contains(secretAliases, key)
+}
+
+// openSecretStore returns the store selected by the `secret_store` config value, or nil if secrets are kept in the
+// JSON config file. Returns an error wrapping ErrInvalidConfig for an unknown store.
+func openSecretStore() (SecretStore, error) {
+	switch kind := strings.ToLower(bhEnv.GetString("secret_store")); kind {
+	case "", secretStoreConfig:
+		return nil, nil
+	case secretStoreKeyring:
+		return newKeyringStore()
+	case secretStoreFile:
+		return &fileStore{path: filepath.Join(GetBloodHoundDir(), secretFileName), passphrase: os.Getenv(secretPassphraseVar)}, nil
+	default:
+		return nil, fmt.Errorf("%w: `secret_store` must be %s, %s, or %s, not `%s`", ErrInvalidConfig, secretStoreConfig, secretStoreKeyring, secretStoreFile, kind)
+	}
+}
+
+// loadSecrets adds the values from the secret store to the configuration. Secrets that are also in the JSON config file
+// are left alone, so a value added to the file (e.g., by a restore) replaces the stored value on the next write.
+func loadSecrets() error {
+	store, err := openSecretStore()
+	if err != nil || store == nil {
+		return err
+	}
+	values := map[string]interface{}{}
+	for _, key := range secretKeys {
+		if bhEnv.InConfig(key) {
+			continue
+		}
+		value, err := store.Get(key)
+		if errors.Is(err, errSecretNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read `%s` from the %s secret store: %w", key, store.Name(), err)
+		}
+		storedSecrets[key] = value
+		setNestedValue(values, key, value)
+	}
+	return bhEnv.MergeConfigMap(values)
+}
+
+// saveSecrets moves the secret values in the settings to the secret store, leaving out the keys in "keep". The settings
+// are changed in place. Nothing happens if secrets are kept in the JSON config file.
+func saveSecrets(settings map[string]interface{}, keep ...string) error {
+	store, err := openSecretStore()
+	if err != nil || store == nil {
+		return err
+	}
+	for _, key := range secretKeys {
+		if Contains(keep, key) {
+			continue
+		}
+		value := bhEnv.GetString(key)
+		if value != "" && storedSecrets[key] != value {
+			if err := store.Set(key, value); err != nil {
+				return fmt.Errorf("failed to save `%s` to the %s secret store: %w", key, store.Name(), err)
+			}
+			storedSecrets[key] = value
+		}
+		deleteNestedValue(settings, key)
+	}
+	for _, alias := range secretAliases {
+		delete(settings, alias)
+	}
+	return nil
+}
+
+// RedactSecrets returns a copy of the nested settings with the secret values replaced by a placeholder.
+func RedactSecrets(settings map[string]interface{}) map[string]interface{} {
+	redacted := map[string]interface{}{}
+	for key, value := range settings {
+		if nested, ok := value.(map[string]interface{}); ok {
+			redacted[key] = redactNested(key, nested)
+			continue
+		}
+		redacted[key] = redactValue(key, value)
+	}
+	return redacted
+}
+
+// redactNested redacts the secrets in a nested section of the settings.
+func redactNested(prefix string, section map[string]interface{}) map[string]interface{} {
+	redacted := map[string]interface{}{}
+	for key, value := range section {
+		path := prefix + "." + key
+		if nested, ok := value.(map[string]interface{}); ok {
+			redacted[key] = redactNested(path, nested)
+			continue
+		}
+		redacted[key] = redactValue(path, value)
+	}
+	return redacted
+}
+
+// redactValue returns the placeholder if the key is a secret with a value.
+func redactValue(key string, value interface{}) interface{} {
+	if IsSecretKey(key) && fmt.Sprint(value) != "" {
+		return redactedValue
+	}
+	return value
+}
+
+// setNestedValue sets a dotted key (e.g., "neo4j.secret") in nested settings.
+func setNestedValue(settings map[string]interface{}, key string, value interface{}) {
+	parts := strings.Split(key, ".")
+	section := settings
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := section[part].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			section[part] = next
+		}
+		section = next
+	}
+	section[parts[len(parts)-1]] = value
+}
+
+// deleteNestedValue removes a dotted key from nested settings along with any sections it leaves empty.
+func deleteNestedValue(settings map[string]interface{}, key string) {
+	parts := strings.SplitN(key, ".", 2)
+	if len(parts) == 1 {
+		delete(settings, key)
+		return
+	}
+	section, ok := settings[parts[0]].(map[string]interface{})
+	if !ok {
+		return
+	}
+	deleteNestedValue(section, parts[1])
+	if len(section) == 0 {
+		delete(settings, parts[0])
+	}
+}
+
+// keyringStore keeps secrets in the OS keyring with `secret-tool` (the Secret Service on Linux) or `security` (the
+// Keychain on macOS).
+type keyringStore struct {
+	tool string
+	// Runs a command with the input on stdin and returns its stdout; replaced in tests
+	run func(name string, args []string, input string) (string, error)
+}
+
+// newKeyringStore returns a keyringStore for the current OS. Returns an error if the OS's keyring tool is missing.
+func newKeyringStore() (*keyringStore, error) {
+	tool := "secret-tool"
+	if runtime.GOOS == "darwin" {
+		tool = "security"
+	} else if runtime.GOOS == "windows" {
+		return nil, fmt.Errorf("%w: the keyring secret store is not supported on Windows, so use the file store", ErrInvalidConfig)
+	}
+	if !CheckPath(tool) {
+		return nil, fmt.Errorf("%w: the keyring secret store requires `%s`, which is not installed", ErrInvalidConfig, tool)
+	}
+	return &keyringStore{tool: tool, run: runWithInput}, nil
+}
+
+// runWithInput runs a command with the input on stdin and returns its stdout. The error includes the command's stderr.
+func runWithInput(name string, args []string, input string) (string, error) {
+	command := exec.Command(name, args...)
+	command.Stdin = strings.NewReader(input)
+	var stderr bytes.Buffer
+	command.Stderr = &stderr
+	out, err := command.Output()
+	if err != nil && stderr.Len() > 0 {
+		return string(out), fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), err
+}
+
+// Name returns "keyring".
+func (k *keyringStore) Name() string {
+	return secretStoreKeyring
+}
+
+// Get looks up the key's keyring entry.
+func (k *keyringStore) Get(key string) (string, error) {
+	var args []string
+	if k.tool == "security" {
+		args = []string{"find-generic-password", "-s", keyringService, "-a", key, "-w"}
+	} else {
+		args = []string{"lookup", "service", keyringService, "key", key}
+	}
+	out, err := k.run(k.tool, args, "")
+	if err != nil {
+		var exitErr *exec.ExitError
+		// `security` exits with 44 and `secret-tool` exits with 1 without any output when there is no entry
+		if errors.As(err, &exitErr) && (exitErr.ExitCode() == 44 || (k.tool == "secret-tool" && exitErr.ExitCode() == 1 && out == "")) {
+			return "", errSecretNotFound
+		}
+		return "", err
+	}
+	return strings.TrimSuffix(out, "\n"), nil
+}
+
+// Set creates or replaces the key's keyring entry. The value is always sent on stdin, so it never shows up in the
+// process list: `secret-tool` reads it directly, and `security` reads the whole command in its interactive mode.
+func (k *keyringStore) Set(key string, value string) error {
+	if k.tool == "security" {
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("%w: the Keychain cannot store `%s` because the value has a line break", ErrInvalidConfig, key)
+		}
+		command := fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", securityQuote(keyringService), securityQuote(key), securityQuote(value))
+		_, err := k.run(k.tool, []string{"-i"}, command)
+		return err
+	}
+	_, err := k.run(k.tool, []string{"store", "--label", "BloodHound CLI " + key, "service", keyringService, "key", key}, value)
+	return err
+}
+
+// securityQuote quotes an argument for a command read by `security -i`, which splits its input on spaces outside of
+// double quotes and removes the backslash from escaped characters.
+func securityQuote(arg string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg)
+	return `"` + escaped + `"`
+}
+
+// fileStore keeps secrets in a JSON object encrypted with AES-256-GCM and a key derived from a passphrase.
+type fileStore struct {
+	path       string
+	passphrase string
+	// Decrypted secrets, loaded on first use
+	values map[string]string
+}
+
+// encryptedSecrets is the format of the encrypted secrets file.
+type encryptedSecrets struct {
+	Version    int    `json:"version"`
+	Kdf        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Name returns "file".
+func (f *fileStore) Name() string {
+	return secretStoreFile
+}
+
+// Get returns the key's value from the encrypted file.
+func (f *fileStore) Get(key string) (string, error) {
+	if err := f.load(); err != nil {
+		return "", err
+	}
+	value, ok := f.values[key]
+	if !ok {
+		return "", errSecretNotFound
+	}
+	return value, nil
+}
+
+// Set saves the key's value and rewrites the encrypted file.
+func (f *fileStore) Set(key string, value string) error {
+	if err := f.load(); err != nil {
+		return err
+	}
+	f.values[key] = value
+	return f.save()
+}
+
+// load decrypts the file into values. A missing file is an empty store.
+func (f *fileStore) load() error {
+	if f.values != nil {
+		return nil
+	}
+	if f.passphrase == "" {
+		return fmt.Errorf("%w: set the %s environment variable to use the file secret store", ErrInvalidConfig, secretPassphraseVar)
+	}
+	data, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		f.values = map[string]string{}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var encrypted encryptedSecrets
+	if err := json.Unmarshal(data, &encrypted); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", f.path, err)
+	}
+	gcm, err := secretCipher(f.passphrase, encrypted.Salt, encrypted.Iterations)
+	if err != nil {
+		return err
+	}
+	plaintext, err := gcm.Open(nil, encrypted.Nonce, encrypted.Ciphertext, nil)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt %s; check the %s environment variable", f.path, secretPassphraseVar)
+	}
+	values := map[string]string{}
+	if err := json.Unmarshal(plaintext, &values); err != nil {
+		return fmt.Errorf("failed to parse the decrypted secrets: %w", err)
+	}
+	f.values = values
+	return nil
+}
+
+// save encrypts values with a new salt and nonce and replaces the file.
+func (f *fileStore) save() error {
+	plaintext, err := json.Marshal(f.values)
+	if err != nil {
+		return err
+	}
+	encrypted := encryptedSecrets{Version: 1, Kdf: "pbkdf2-sha256", Iterations: secretFileIterations, Salt: make([]byte, 16)}
+	if _, err := rand.Read(encrypted.Salt); err != nil {
+		return err
+	}
+	gcm, err := secretCipher(f.passphrase, encrypted.Salt, encrypted.Iterations)
+	if err != nil {
+		return err
+	}
+	encrypted.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(encrypted.Nonce); err != nil {
+		return err
+	}
+	encrypted.Ciphertext = gcm.Seal(nil, encrypted.Nonce, plaintext, nil)
+	data, err := json.MarshalIndent(encrypted, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, configFileMode()); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
+
+// secretCipher derives the file's key from the passphrase and returns an AES-GCM cipher.
+func secretCipher(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// lowerSecretFileIterations speeds up the key derivation for the file store tests.
+func lowerSecretFileIterations(t *testing.T) {
+	original := secretFileIterations
+	secretFileIterations = 1000
+	t.Cleanup(func() { secretFileIterations = original })
+}
+
+func TestFileStore(t *testing.T) {
+	lowerSecretFileIterations(t)
+	path := filepath.Join(t.TempDir(), secretFileName)
+
+	store := &fileStore{path: path, passphrase: "correct horse"}
+	_, err := store.Get("neo4j.secret")
+	assert.True(t, errors.Is(err, errSecretNotFound), "Expected a missing file to be an empty store")
+	assert.NoError(t, store.Set("neo4j.secret", "s3cret"))
+	assert.True(t, FileExists(path))
+
+	reopened := &fileStore{path: path, passphrase: "correct horse"}
+	value, err := reopened.Get("neo4j.secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret", value)
+
+	wrong := &fileStore{path: path, passphrase: "battery staple"}
+	_, err = wrong.Get("neo4j.secret")
+	assert.Error(t, err, "Expected the wrong passphrase to fail")
+
+	missing := &fileStore{path: path}
+	_, err = missing.Get("neo4j.secret")
+	assert.True(t, errors.Is(err, ErrInvalidConfig), "Expected a missing passphrase to be a config error")
+}
+
+func TestKeyringStore(t *testing.T) {
+	var calls []string
+	store := &keyringStore{tool: "secret-tool", run: func(name string, args []string, input string) (string, error) {
+		calls = append(calls, name+" "+args[0]+" "+input)
+		return "s3cret\n", nil
+	}}
+
+	assert.NoError(t, store.Set("neo4j.secret", "s3cret"))
+	value, err := store.Get("neo4j.secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret", value, "Expected the trailing newline to be removed")
+	assert.Equal(t, []string{"secret-tool store s3cret", "secret-tool lookup "}, calls, "Expected the secret to be sent on stdin")
+
+	calls = nil
+	store.tool = "security"
+	assert.NoError(t, store.Set("neo4j.secret", `s3 "c\ret`))
+	assert.Equal(t, []string{"security -i add-generic-password -U -s \"bloodhound-cli\" -a \"neo4j.secret\" -w \"s3 \\\"c\\\\ret\"\n"}, calls, "Expected the secret to be sent on stdin and quoted for `security -i`")
+	assert.ErrorIs(t, store.Set("neo4j.secret", "two\nlines"), ErrInvalidConfig)
+}
+
+func TestSaveSecrets(t *testing.T) {
+	lowerSecretFileIterations(t)
+	t.Setenv(secretPassphraseVar, "correct horse")
+	setTestConfig(t, map[string]string{
+		"config_directory": t.TempDir(),
+		"secret_store":     secretStoreFile,
+		"neo4j.secret":     "s3cret",
+	})
+	t.Cleanup(func() { delete(storedSecrets, "neo4j.secret") })
+
+	settings := map[string]interface{}{
+		"neo4j":            map[string]interface{}{"secret": "s3cret"},
+		"log_path":         "bloodhound.log",
+		"default_password": "hunter2",
+	}
+	assert.NoError(t, saveSecrets(settings, "default_admin.password"))
+	assert.Equal(t, map[string]interface{}{"log_path": "bloodhound.log"}, settings, "Expected the secret and its empty section to be removed")
+
+	store := &fileStore{path: filepath.Join(GetBloodHoundDir(), secretFileName), passphrase: "correct horse"}
+	value, err := store.Get("neo4j.secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret", value)
+}
+
+func TestRedactSecrets(t *testing.T) {
+	settings := map[string]interface{}{
+		"default_admin": map[string]interface{}{"password": "hunter2", "principal_name": "admin"},
+		"neo4j":         map[string]interface{}{"secret": ""},
+		"log_level":     "INFO",
+	}
+	redacted := RedactSecrets(settings)
+	assert.Equal(t, map[string]interface{}{
+		"default_admin": map[string]interface{}{"password": redactedValue, "principal_name": "admin"},
+		"neo4j":         map[string]interface{}{"secret": ""},
+		"log_level":     "INFO",
+	}, redacted)
+	assert.Equal(t, "hunter2", settings["default_admin"].(map[string]interface{})["password"], "Expected the original settings to be unchanged")
+	assert.True(t, IsSecretKey("DEFAULT_ADMIN.PASSWORD"))
+}
@@ -0,0 +1,412 @@
+package internal
+
+// Functions for replacing the BloodHound CLI binary with the latest release
+// Release assets are verified against the published SHA-256 checksums (and signatures, when present) before the
+// running executable is replaced, and the previous binary is kept next to it for a rollback
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/SpecterOps/BloodHound_CLI/cmd/config"
+)
+
+// Vars for updating the BloodHound CLI binary
+var (
+	// Repository of the BloodHound CLI releases
+	cliReleaseRepo = "SpecterOps/bloodhound-cli"
+	// Suffix of the copy of the previous binary kept for `self-update --rollback`
+	previousBinarySuffix = ".previous"
+	// Assets with the SHA-256 checksums of every asset, checked after the asset's own ".sha256" file
+	checksumAssets = []string{"checksums.txt", "SHA256SUMS", "sha256sums.txt"}
+	// executablePath returns the path of the running binary; tests replace it
+	executablePath = os.Executable
+	// Public key for release signatures; tests replace it
+	releaseSigningKey = config.ReleaseSigningKey
+)
+
+// githubRelease is a release from GitHub's API.
+type githubRelease struct {
+	TagName string        `json:"tag_name"`
+	HtmlUrl string        `json:"html_url"`
+	Assets  []githubAsset `json:"assets"`
+}
+
+// githubAsset is a file attached to a GitHub release.
+type githubAsset struct {
+	Name string `json:"name"`
+	Url  string `json:"browser_download_url"`
+}
+
+// SelfUpdate describes an update or rollback of the BloodHound CLI binary.
+type SelfUpdate struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Path of the replaced executable
+	Path string `json:"path"`
+	// Path of the copy of the previous binary
+	Previous string `json:"previous,omitempty"`
+	// Release asset that was installed
+	Asset string `json:"asset,omitempty"`
+	// Whether the asset's signature was verified
+	Signed bool `json:"signed"`
+	// Whether the binary was replaced; false if it was already the latest release
+	Updated bool `json:"updated"`
+}
+
+// RunSelfUpdate replaces the running BloodHound CLI binary with the latest release for this OS and architecture. The
+// release asset must match its published SHA-256 checksum, and its signature is verified if the release has one or
+// this build has a release signing key. The previous binary is kept with the ".previous" suffix for
+// RollbackSelfUpdate. Unless "force" is true, nothing is replaced if this binary is already the latest release, and a
+// release older than this binary is refused. Returns an error wrapping ErrVerificationFailed if the asset cannot be
+// verified.
+func RunSelfUpdate(force bool) (SelfUpdate, error) {
+	update := SelfUpdate{From: config.Version}
+	exe, err := currentExecutable()
+	if err != nil {
+		return update, err
+	}
+	update.Path = exe
+
+	var release githubRelease
+	if err := getJson(fmt.Sprintf("%s/repos/%s/releases/latest", githubApiUrl, cliReleaseRepo), &release); err != nil {
+		return update, fmt.Errorf("failed to fetch the latest release: %w", err)
+	}
+	update.To = release.TagName
+	if !force {
+		if release.TagName == config.Version {
+			return update, nil
+		}
+		// Builds without a release tag (e.g., local builds) can always be updated
+		if releaseTagPattern.MatchString(release.TagName) && releaseTagPattern.MatchString(config.Version) &&
+			compareReleases(release.TagName, config.Version) < 0 {
+			return update, fmt.Errorf("the latest release, %s, is older than this binary (%s); use `--force` to downgrade", release.TagName, config.Version)
+		}
+	}
+
+	asset, ok := selectReleaseAsset(release.Assets, runtime.GOOS, runtime.GOARCH)
+	if !ok {
+		return update, fmt.Errorf("the %s release has no binary for %s/%s; download one from %s", release.TagName, runtime.GOOS, runtime.GOARCH, release.HtmlUrl)
+	}
+	update.Asset = asset.Name
+	fmt.Printf("[+] Downloading %s from the %s release...\n", asset.Name, release.TagName)
+	content, err := downloadBytes(asset.Url)
+	if err != nil {
+		return update, fmt.Errorf("failed to download %s: %w", asset.Name, err)
+	}
+
+	if err := verifyReleaseChecksum(release.Assets, asset.Name, content); err != nil {
+		return update, err
+	}
+	fmt.Printf("[+] Verified the SHA-256 checksum of %s\n", asset.Name)
+	update.Signed, err = verifyReleaseSignature(release.Assets, asset.Name, content)
+	if err != nil {
+		return update, err
+	}
+
+	binary, err := extractReleaseBinary(asset.Name, content)
+	if err != nil {
+		return update, fmt.Errorf("failed to extract the binary from %s: %w", asset.Name, err)
+	}
+	update.Previous = exe + previousBinarySuffix
+	if err := copyExecutable(exe, update.Previous); err != nil {
+		return update, fmt.Errorf("failed to keep a copy of the current binary: %w", err)
+	}
+	staged, err := stageExecutable(filepath.Dir(exe), bytes.NewReader(binary))
+	if err != nil {
+		return update, err
+	}
+	if err := replaceExecutable(staged, exe); err != nil {
+		os.Remove(staged)
+		return update, err
+	}
+	update.Updated = true
+	return update, nil
+}
+
+// RollbackSelfUpdate swaps the running BloodHound CLI binary with the copy kept by RunSelfUpdate, so running it again
+// goes back to the newer binary.
+func RollbackSelfUpdate() (SelfUpdate, error) {
+	exe, err := currentExecutable()
+	if err != nil {
+		return SelfUpdate{}, err
+	}
+	update := SelfUpdate{From: config.Version, Path: exe, Previous: exe + previousBinarySuffix}
+	previous, err := os.Open(update.Previous)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return update, fmt.Errorf("there is no previous binary at %s to roll back to", update.Previous)
+		}
+		return update, fmt.Errorf("failed to open the previous binary: %w", err)
+	}
+	defer previous.Close()
+
+	staged, err := stageExecutable(filepath.Dir(exe), previous)
+	if err != nil {
+		return update, err
+	}
+	current, err := os.Open(exe)
+	if err != nil {
+		os.Remove(staged)
+		return update, fmt.Errorf("failed to read the current binary: %w", err)
+	}
+	defer current.Close()
+	kept, err := stageExecutable(filepath.Dir(exe), current)
+	if err != nil {
+		os.Remove(staged)
+		return update, err
+	}
+	if err := replaceExecutable(staged, exe); err != nil {
+		os.Remove(staged)
+		os.Remove(kept)
+		return update, err
+	}
+	if err := os.Rename(kept, update.Previous); err != nil {
+		return update, fmt.Errorf("failed to keep a copy of the replaced binary: %w", err)
+	}
+	update.Updated = true
+	return update, nil
+}
+
+// currentExecutable returns the path of the running binary with any symlinks resolved.
+func currentExecutable() (string, error) {
+	exe, err := executablePath()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the running binary: %w", err)
+	}
+	resolved, err := filepath.EvalSymlinks(exe)
+	if err != nil {
+		return "", fmt.Errorf("failed to find the running binary: %w", err)
+	}
+	return resolved, nil
+}
+
+// selectReleaseAsset returns the release asset for the OS and architecture (e.g., "bloodhound-cli-linux-amd64.tar.gz").
+func selectReleaseAsset(assets []githubAsset, goos string, goarch string) (githubAsset, bool) {
+	base := fmt.Sprintf("bloodhound-cli-%s-%s", goos, goarch)
+	for _, name := range []string{base + ".tar.gz", base + ".zip", base, base + ".exe"} {
+		for _, asset := range assets {
+			if asset.Name == name {
+				return asset, true
+			}
+		}
+	}
+	return githubAsset{}, false
+}
+
+// findAsset returns the release asset with the name.
+func findAsset(assets []githubAsset, name string) (githubAsset, bool) {
+	for _, asset := range assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+	return githubAsset{}, false
+}
+
+// verifyReleaseChecksum compares the SHA-256 checksum of the asset's content with the checksum published in the
+// asset's ".sha256" file or in a checksums file for the whole release. Returns an error wrapping ErrVerificationFailed
+// if the checksums differ or the release has no checksum for the asset.
+func verifyReleaseChecksum(assets []githubAsset, name string, content []byte) error {
+	sum := sha256.Sum256(content)
+	actual := hex.EncodeToString(sum[:])
+	for _, sumsName := range append([]string{name + ".sha256"}, checksumAssets...) {
+		sumsAsset, ok := findAsset(assets, sumsName)
+		if !ok {
+			continue
+		}
+		sums, err := downloadBytes(sumsAsset.Url)
+		if err != nil {
+			return fmt.Errorf("failed to download %s: %w", sumsName, err)
+		}
+		expected, ok := parseChecksum(sums, name, sumsName == name+".sha256")
+		if !ok {
+			continue
+		}
+		if !strings.EqualFold(expected, actual) {
+			return fmt.Errorf("%w: the SHA-256 checksum of %s is %s, but %s lists %s", ErrVerificationFailed, name, actual, sumsName, expected)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: the release has no published SHA-256 checksum for %s, so it was not installed", ErrVerificationFailed, name)
+}
+
+// parseChecksum returns the checksum for the file name from the output of `sha256sum` (lines of "<checksum>  <name>",
+// with a "*" before the name in binary mode). If "single" is true, a checksum without a name is also accepted.
+func parseChecksum(sums []byte, name string, single bool) (string, bool) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		switch {
+		case len(fields) == 1 && single:
+			return fields[0], true
+		case len(fields) >= 2 && filepath.Base(strings.TrimPrefix(fields[1], "*")) == name:
+			return fields[0], true
+		}
+	}
+	return "", false
+}
+
+// verifyReleaseSignature checks the asset's Ed25519 signature in its ".sig" file against the release signing key and
+// reports whether the signature was verified. Builds without a signing key only check the signature if the release
+// has one. Returns an error wrapping ErrVerificationFailed if the signature does not match or, when this build has a
+// signing key, if the release has no signature for the asset.
+func verifyReleaseSignature(assets []githubAsset, name string, content []byte) (bool, error) {
+	sigAsset, ok := findAsset(assets, name+".sig")
+	if !ok {
+		if releaseSigningKey != "" {
+			return false, fmt.Errorf("%w: the release has no signature for %s", ErrVerificationFailed, name)
+		}
+		return false, nil
+	}
+	if releaseSigningKey == "" {
+		fmt.Printf("[!] %s is signed, but this build has no release signing key, so only the checksum was verified\n", name)
+		return false, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(releaseSigningKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return false, fmt.Errorf("%w: the release signing key in this build is not a valid Ed25519 public key", ErrVerificationFailed)
+	}
+	encoded, err := downloadBytes(sigAsset.Url)
+	if err != nil {
+		return false, fmt.Errorf("failed to download %s: %w", sigAsset.Name, err)
+	}
+	// Signatures are published as raw bytes or in base64
+	signature := encoded
+	if len(signature) != ed25519.SignatureSize {
+		signature, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+		if err != nil {
+			return false, fmt.Errorf("%w: %s is not a valid signature", ErrVerificationFailed, sigAsset.Name)
+		}
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), content, signature) {
+		return false, fmt.Errorf("%w: the signature in %s does not match %s", ErrVerificationFailed, sigAsset.Name, name)
+	}
+	fmt.Printf("[+] Verified the signature of %s\n", name)
+	return true, nil
+}
+
+// extractReleaseBinary returns the BloodHound CLI binary from a release asset, which is a tar.gz or zip archive or the
+// binary itself.
+func extractReleaseBinary(name string, content []byte) ([]byte, error) {
+	isBinary := func(path string) bool {
+		base := filepath.Base(path)
+		return base == "bloodhound-cli" || base == "bloodhound-cli.exe"
+	}
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"):
+		gz, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		tr := tar.NewReader(gz)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if header.Typeflag == tar.TypeReg && isBinary(header.Name) {
+				return io.ReadAll(tr)
+			}
+		}
+	case strings.HasSuffix(name, ".zip"):
+		zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range zr.File {
+			if file.FileInfo().IsDir() || !isBinary(file.Name) {
+				continue
+			}
+			rc, err := file.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			return io.ReadAll(rc)
+		}
+	default:
+		return content, nil
+	}
+	return nil, fmt.Errorf("%s does not contain a bloodhound-cli binary", name)
+}
+
+// downloadBytes downloads a release asset with httpClient.
+func downloadBytes(url string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status: %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// stageExecutable writes an executable to a temporary file in the directory, so it can be renamed over the binary.
+func stageExecutable(dir string, content io.Reader) (string, error) {
+	staged, err := os.CreateTemp(dir, ".bloodhound-cli-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to write the new binary next to the current one: %w", err)
+	}
+	_, err = io.Copy(staged, content)
+	if closeErr := staged.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(staged.Name(), 0755)
+	}
+	if err != nil {
+		os.Remove(staged.Name())
+		return "", fmt.Errorf("failed to write the new binary next to the current one: %w", err)
+	}
+	return staged.Name(), nil
+}
+
+// copyExecutable copies a binary and makes the copy executable.
+func copyExecutable(src string, dst string) error {
+	if err := CopyFile(src, dst); err != nil {
+		return err
+	}
+	return os.Chmod(dst, 0755)
+}
+
+// replaceExecutable renames the staged binary over the executable. The rename is atomic, so the executable is either
+// the old or the new binary.
+func replaceExecutable(staged string, exe string) error {
+	if runtime.GOOS == "windows" {
+		// Windows cannot replace a running executable, but it can rename it
+		old := exe + ".old"
+		os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return fmt.Errorf("failed to move the current binary aside: %w", err)
+		}
+	}
+	if err := os.Rename(staged, exe); err != nil {
+		return fmt.Errorf("failed to replace the current binary: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package internal
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/SpecterOps/BloodHound_CLI/cmd/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubSelfUpdate points the release API at a local server with a release whose asset for this OS and architecture
+// contains the binary, and points the running executable at a temporary file. Extra assets are added with their
+// contents. Returns the path of the temporary executable and the handlers, so tests can change the assets.
+func stubSelfUpdate(t *testing.T, binary string, extra map[string]string) (string, map[string]string) {
+	archive := releaseArchive(binary)
+	handlers := map[string]string{}
+	stubReleaseApis(t, handlers)
+	asset := fmt.Sprintf("bloodhound-cli-%s-%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	sum := sha256.Sum256(archive)
+	assets := map[string]string{
+		asset:             string(archive),
+		asset + ".sha256": hex.EncodeToString(sum[:]) + "  " + asset + "\n",
+	}
+	for name, content := range extra {
+		assets[name] = content
+	}
+	var list []string
+	for name, content := range assets {
+		handlers["/download/"+name] = content
+		list = append(list, fmt.Sprintf(`{"name": %q, "browser_download_url": %q}`, name, githubApiUrl+"/download/"+name))
+	}
+	handlers["/repos/SpecterOps/bloodhound-cli/releases/latest"] = fmt.Sprintf(
+		`{"tag_name": "v9.9.9", "html_url": "https://github.com/SpecterOps/bloodhound-cli/releases/tag/v9.9.9", "assets": [%s]}`,
+		strings.Join(list, ", "),
+	)
+
+	exe := filepath.Join(t.TempDir(), "bloodhound-cli")
+	assert.NoError(t, os.WriteFile(exe, []byte("old binary"), 0755))
+	previous := executablePath
+	executablePath = func() (string, error) { return exe, nil }
+	t.Cleanup(func() { executablePath = previous })
+	return exe, handlers
+}
+
+// releaseArchive returns a tar.gz release asset with the binary.
+func releaseArchive(binary string) []byte {
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	tw.WriteHeader(&tar.Header{Name: "bloodhound-cli", Mode: 0755, Size: int64(len(binary)), Typeflag: tar.TypeReg})
+	tw.Write([]byte(binary))
+	tw.Close()
+	gz.Close()
+	return archive.Bytes()
+}
+
+func TestRunSelfUpdate(t *testing.T) {
+	exe, _ := stubSelfUpdate(t, "new binary", nil)
+
+	update, err := RunSelfUpdate(false)
+	assert.NoError(t, err, "`RunSelfUpdate()` should install a release with a matching checksum")
+	assert.True(t, update.Updated)
+	assert.Equal(t, "v9.9.9", update.To)
+	assert.False(t, update.Signed)
+
+	content, _ := os.ReadFile(exe)
+	assert.Equal(t, "new binary", string(content), "The executable should be replaced with the binary from the archive")
+	content, _ = os.ReadFile(exe + ".previous")
+	assert.Equal(t, "old binary", string(content), "The previous binary should be kept")
+	info, err := os.Stat(exe)
+	assert.NoError(t, err)
+	assert.NotZero(t, info.Mode()&0100, "The new binary should be executable")
+
+	entries, _ := os.ReadDir(filepath.Dir(exe))
+	assert.Len(t, entries, 2, "No temporary files should be left next to the executable")
+}
+
+func TestRunSelfUpdateChecksumMismatch(t *testing.T) {
+	exe, handlers := stubSelfUpdate(t, "new binary", nil)
+	asset := fmt.Sprintf("bloodhound-cli-%s-%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	handlers["/download/"+asset+".sha256"] = "0000  " + asset + "\n"
+
+	_, err := RunSelfUpdate(false)
+	assert.ErrorIs(t, err, ErrVerificationFailed, "A checksum mismatch should fail verification")
+	content, _ := os.ReadFile(exe)
+	assert.Equal(t, "old binary", string(content), "The executable should not be replaced")
+
+	handlers["/download/"+asset+".sha256"] = ""
+	_, err = RunSelfUpdate(false)
+	assert.ErrorIs(t, err, ErrVerificationFailed, "A release without a checksum for the asset should fail verification")
+}
+
+func TestRunSelfUpdateSignature(t *testing.T) {
+	public, private, _ := ed25519.GenerateKey(nil)
+	previous := releaseSigningKey
+	releaseSigningKey = base64.StdEncoding.EncodeToString(public)
+	t.Cleanup(func() { releaseSigningKey = previous })
+
+	asset := fmt.Sprintf("bloodhound-cli-%s-%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	archive := releaseArchive("new binary")
+	stubWithSignature := func(signature []byte) string {
+		exe, _ := stubSelfUpdate(t, "new binary", map[string]string{
+			asset + ".sig": base64.StdEncoding.EncodeToString(signature),
+		})
+		return exe
+	}
+
+	exe := stubWithSignature(ed25519.Sign(private, []byte("something else")))
+	_, err := RunSelfUpdate(false)
+	assert.ErrorIs(t, err, ErrVerificationFailed, "A signature that does not match the asset should fail verification")
+	content, _ := os.ReadFile(exe)
+	assert.Equal(t, "old binary", string(content))
+
+	exe = stubWithSignature(ed25519.Sign(private, archive))
+	update, err := RunSelfUpdate(false)
+	assert.NoError(t, err, "A valid signature should be accepted")
+	assert.True(t, update.Signed)
+	content, _ = os.ReadFile(exe)
+	assert.Equal(t, "new binary", string(content))
+
+	exe, _ = stubSelfUpdate(t, "new binary", nil)
+	_, err = RunSelfUpdate(false)
+	assert.ErrorIs(t, err, ErrVerificationFailed, "A release without a signature should fail verification when the build has a signing key")
+	content, _ = os.ReadFile(exe)
+	assert.Equal(t, "old binary", string(content))
+}
+
+func TestRunSelfUpdateVersions(t *testing.T) {
+	previous := config.Version
+	t.Cleanup(func() { config.Version = previous })
+
+	config.Version = "v9.9.9"
+	exe, _ := stubSelfUpdate(t, "new binary", nil)
+	update, err := RunSelfUpdate(false)
+	assert.NoError(t, err)
+	assert.False(t, update.Updated, "A binary that is already the latest release should not be replaced")
+
+	config.Version = "v10.0.0"
+	update, err = RunSelfUpdate(false)
+	assert.Error(t, err, "An older release should not be installed without `force`")
+	assert.False(t, update.Updated)
+	content, _ := os.ReadFile(exe)
+	assert.Equal(t, "old binary", string(content))
+
+	update, err = RunSelfUpdate(true)
+	assert.NoError(t, err, "`force` should allow a downgrade")
+	assert.True(t, update.Updated)
+
+	config.Version = "dev"
+	exe, _ = stubSelfUpdate(t, "new binary", nil)
+	update, err = RunSelfUpdate(false)
+	assert.NoError(t, err, "A build without a release tag should be updated")
+	assert.True(t, update.Updated)
+}
+
+func TestRollbackSelfUpdate(t *testing.T) {
+	exe, _ := stubSelfUpdate(t, "new binary", nil)
+
+	_, err := RollbackSelfUpdate()
+	assert.Error(t, err, "Rolling back without a previous binary should fail")
+
+	_, err = RunSelfUpdate(false)
+	assert.NoError(t, err)
+
+	_, err = RollbackSelfUpdate()
+	assert.NoError(t, err, "`RollbackSelfUpdate()` should restore the previous binary")
+	content, _ := os.ReadFile(exe)
+	assert.Equal(t, "old binary", string(content))
+	content, _ = os.ReadFile(exe + ".previous")
+	assert.Equal(t, "new binary", string(content), "The replaced binary should be kept for switching back")
+}
+
+func TestParseChecksum(t *testing.T) {
+	sums := []byte("abc  other.tar.gz\ndef *bloodhound-cli-linux-amd64.tar.gz\n")
+	sum, ok := parseChecksum(sums, "bloodhound-cli-linux-amd64.tar.gz", false)
+	assert.True(t, ok)
+	assert.Equal(t, "def", sum, "Binary mode lines should be parsed")
+
+	_, ok = parseChecksum([]byte("abc\n"), "bloodhound-cli-linux-amd64.tar.gz", false)
+	assert.False(t, ok, "A checksum without a name only counts in the asset's own checksum file")
+	sum, ok = parseChecksum([]byte("abc\n"), "bloodhound-cli-linux-amd64.tar.gz", true)
+	assert.True(t, ok)
+	assert.Equal(t, "abc", sum)
+}
@@ -0,0 +1,245 @@
+package internal
+
+// Functions for upgrading the BloodHound image to a pinned tag and rolling back if the new version fails to come up
+// The image IDs of the running containers are recorded first, so a rollback restores the exact images even if their
+// tags (e.g., `latest`) moved to the new version
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Vars for upgrading BloodHound
+var (
+	// File in the config directory that records every upgrade
+	upgradeHistoryFile = "upgrades.json"
+	// Statuses of an upgrade in the history
+	upgradeSucceeded  = "succeeded"
+	upgradeRolledBack = "rolled back"
+	upgradeFailed     = "failed"
+	// Functions for the snapshot, restoring it, and the health check, which tests replace
+	upgradeSnapshot    = RunBackup
+	upgradeRestore     = restoreSnapshot
+	upgradeHealthCheck = WaitForStack
+)
+
+// UpgradeOptions controls RunUpgrade.
+type UpgradeOptions struct {
+	// Tag of the BloodHound image to upgrade to; empty or "latest" means the newest release, which is pinned by its
+	// release tag
+	Tag string
+	// Directory for the pre-upgrade snapshot
+	SnapshotDir string
+	// How long to wait for the services to become healthy; zero skips the health check, so only a failed pull or
+	// recreate is rolled back
+	Timeout time.Duration
+	// Keep the data written by the new version when rolling back instead of restoring the snapshot
+	SkipRestore bool
+}
+
+// UpgradeRecord describes an upgrade in the history file.
+type UpgradeRecord struct {
+	StartedAt string `json:"started_at"`
+	FromTag   string `json:"from_tag"`
+	ToTag     string `json:"to_tag"`
+	// Images of the BloodHound containers before the upgrade, keyed by the container's "name" label
+	Images   map[string]BackupImage `json:"images"`
+	Snapshot string                 `json:"snapshot"`
+	// Whether the rollback restored the snapshot
+	SnapshotRestored bool   `json:"snapshot_restored"`
+	Status           string `json:"status"`
+	Error            string `json:"error,omitempty"`
+}
+
+// RunUpgrade upgrades the BloodHound deployment described by the Docker Compose YAML file to the image tag in the
+// options, or to the newest BloodHound CE release if no tag is given. It records the current images, takes a snapshot
+// with RunBackup, pins the tag with the `bloodhound.tag` config value, pulls the images, and recreates the containers.
+// If the services do not become healthy, the recorded images and the previous tag are restored, and the containers are
+// recreated from the snapshot, since the new version may have migrated the data (unless SkipRestore is set). Every
+// upgrade is added to the "upgrades.json" file in the config directory. If the upgrade was rolled back, the error wraps
+// the reason (e.g., ErrUnhealthy if the services did not become healthy).
+func RunUpgrade(rt Runtime, yaml string, options UpgradeOptions) (UpgradeRecord, error) {
+	record := UpgradeRecord{StartedAt: time.Now().UTC().Format(time.RFC3339), ToTag: options.Tag, Status: upgradeFailed}
+	if err := CheckYamlExists(yaml); err != nil {
+		return record, err
+	}
+	// Pin the release that `latest` points to now, so the tag does not move to a release that was never upgraded to
+	if options.Tag == "" || options.Tag == "latest" {
+		latest, err := LatestBloodHoundRelease()
+		if err != nil {
+			return record, fmt.Errorf("%w; use `--to` to choose the release", err)
+		}
+		fmt.Printf("[+] The latest BloodHound CE release is %s\n", latest)
+		options.Tag = latest
+		record.ToTag = latest
+	}
+	entry, _ := LookupConfigKey("bloodhound.tag")
+	if _, err := entry.Parse(options.Tag); err != nil {
+		return record, err
+	}
+	// Compose reads the variable before the config value, so the pinned tag would be ignored
+	if value, ok := os.LookupEnv("BLOODHOUND_TAG"); ok {
+		return record, fmt.Errorf("%w: the BLOODHOUND_TAG environment variable (%s) overrides the tag that `upgrade` pins, so unset it first", ErrInvalidConfig, value)
+	}
+	previousTag := bhEnv.GetString("bloodhound.tag")
+	record.FromTag = composeValue("bloodhound.tag")
+
+	images, err := GetBloodHoundImages(rt)
+	if err != nil {
+		return record, err
+	}
+	if len(images) == 0 {
+		return record, errors.New("no BloodHound containers were found, so there is nothing to upgrade; run `bloodhound-cli install` instead")
+	}
+	record.Images = images
+	for _, name := range sortedImageNames(images) {
+		fmt.Printf("[+] Recorded the current image for %s: %s (%s)\n", name, images[name].Image, images[name].ImageID)
+	}
+
+	fmt.Println("[+] Taking a snapshot of the data before upgrading...")
+	snapshot, err := upgradeSnapshot(rt, yaml, options.SnapshotDir)
+	if err != nil {
+		return record, fmt.Errorf("error trying to take a snapshot before the upgrade: %w", err)
+	}
+	record.Snapshot = snapshot
+	fmt.Printf("[+] Snapshot saved to %s\n", snapshot)
+
+	upgradeErr := applyUpgrade(rt, yaml, options.Tag, options.Timeout)
+	if upgradeErr == nil {
+		record.Status = upgradeSucceeded
+		return record, saveUpgradeRecord(record)
+	}
+	record.Error = upgradeErr.Error()
+	fmt.Printf("[-] The upgrade to %s failed: %v\n", options.Tag, upgradeErr)
+
+	fmt.Printf("[!] Rolling back to the recorded images and the `%s` tag...\n", record.FromTag)
+	restore := snapshot
+	if options.SkipRestore {
+		restore = ""
+	}
+	if err := rollbackUpgrade(rt, yaml, images, previousTag, restore, options.Timeout); err != nil {
+		return record, errors.Join(
+			fmt.Errorf("the upgrade to %s failed, and so did the rollback; restore the snapshot with `bloodhound-cli restore %s`: %w", options.Tag, snapshot, err),
+			saveUpgradeRecord(record),
+		)
+	}
+	record.Status = upgradeRolledBack
+	record.SnapshotRestored = restore != ""
+	if err := saveUpgradeRecord(record); err != nil {
+		return record, err
+	}
+	if record.SnapshotRestored {
+		return record, fmt.Errorf("the upgrade to %s was rolled back and the data was restored from %s: %w", options.Tag, snapshot, upgradeErr)
+	}
+	return record, fmt.Errorf("the upgrade to %s was rolled back; if BloodHound does not work with the migrated data, restore the snapshot with `bloodhound-cli restore %s`: %w", options.Tag, snapshot, upgradeErr)
+}
+
+// applyUpgrade pins the tag in the configuration, pulls the images, recreates the containers, and waits up to
+// "timeout" for the services to become healthy.
+func applyUpgrade(rt Runtime, yaml string, tag string, timeout time.Duration) error {
+	if err := setBloodHoundTag(tag); err != nil {
+		return err
+	}
+	if err := RunDockerComposePull(rt, yaml); err != nil {
+		return err
+	}
+	if err := RunDockerComposeUp(rt, yaml); err != nil {
+		return err
+	}
+	return upgradeHealthCheck(rt, timeout)
+}
+
+// rollbackUpgrade points the image references back at the recorded images, restores the previous `bloodhound.tag`
+// config value, and recreates the containers without pulling. The data is restored from the "snapshot" archive unless
+// it is empty.
+func rollbackUpgrade(rt Runtime, yaml string, images map[string]BackupImage, previousTag string, snapshot string, timeout time.Duration) error {
+	for _, name := range sortedImageNames(images) {
+		image := images[name]
+		// A container created from an image that lost its tag reports the image ID instead of a reference
+		if image.ImageID == "" || image.Image == "" || strings.HasPrefix(image.Image, "sha256:") {
+			fmt.Printf("[!] Skipping %s because its image reference was not recorded\n", name)
+			continue
+		}
+		if err := rt.TagImage(context.Background(), image.ImageID, image.Image); err != nil {
+			return err
+		}
+	}
+	if err := setBloodHoundTag(previousTag); err != nil {
+		return err
+	}
+	if snapshot != "" {
+		fmt.Printf("[!] Restoring the data from the snapshot at %s...\n", snapshot)
+		if err := upgradeRestore(rt, yaml, snapshot); err != nil {
+			return fmt.Errorf("error trying to restore the snapshot: %w", err)
+		}
+	} else if err := RunDockerComposeUp(rt, yaml); err != nil {
+		return err
+	}
+	return upgradeHealthCheck(rt, timeout)
+}
+
+// restoreSnapshot restores the pre-upgrade snapshot without asking for confirmation. The containers still run the
+// images of the failed upgrade until the restore recreates them, so the image versions are not compared.
+func restoreSnapshot(rt Runtime, yaml string, snapshot string) error {
+	return restoreBackup(rt, yaml, snapshot, true, false)
+}
+
+// setBloodHoundTag saves the `bloodhound.tag` config value; an empty tag removes the pin.
+func setBloodHoundTag(tag string) error {
+	if tag == "" {
+		settings := currentSettings()
+		deleteNestedValue(settings, "bloodhound.tag")
+		return reloadConfig(settings)
+	}
+	bhEnv.Set("bloodhound.tag", tag)
+	return WriteBloodHoundEnvironmentVariables()
+}
+
+// sortedImageNames returns the container names in the recorded images, sorted.
+func sortedImageNames(images map[string]BackupImage) []string {
+	names := make([]string, 0, len(images))
+	for name := range images {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ReadUpgradeHistory returns the upgrades recorded in the config directory, oldest first.
+func ReadUpgradeHistory() ([]UpgradeRecord, error) {
+	var history []UpgradeRecord
+	content, err := os.ReadFile(filepath.Join(GetBloodHoundDir(), upgradeHistoryFile))
+	if os.IsNotExist(err) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the upgrade history: %w", err)
+	}
+	if err := json.Unmarshal(content, &history); err != nil {
+		return nil, fmt.Errorf("failed to parse the upgrade history: %w", err)
+	}
+	return history, nil
+}
+
+// saveUpgradeRecord adds the record to the upgrade history in the config directory.
+func saveUpgradeRecord(record UpgradeRecord) error {
+	history, err := ReadUpgradeHistory()
+	if err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(append(history, record), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal the upgrade history to JSON: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(GetBloodHoundDir(), upgradeHistoryFile), content, configFileMode()); err != nil {
+		return fmt.Errorf("failed to write the upgrade history: %w", err)
+	}
+	return nil
+}
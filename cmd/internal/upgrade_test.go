@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SpecterOps/BloodHound_CLI/cmd/internal/runtimetest"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubUpgrade replaces the snapshot with a fake archive path, the restore with one that records the archives it is
+// given, and the health check with one returning "healthErrs" in order (nil once they run out) until the test
+// finishes. Returns the recorded archives.
+func stubUpgrade(t *testing.T, healthErrs ...error) *[]string {
+	snapshot, restore, healthCheck := upgradeSnapshot, upgradeRestore, upgradeHealthCheck
+	var restored []string
+	upgradeSnapshot = func(rt Runtime, yaml string, dir string) (string, error) {
+		return filepath.Join(dir, "bloodhound-backup.tar.gz"), nil
+	}
+	upgradeRestore = func(rt Runtime, yaml string, archive string) error {
+		restored = append(restored, archive)
+		return nil
+	}
+	upgradeHealthCheck = func(rt Runtime, timeout time.Duration) error {
+		if len(healthErrs) == 0 {
+			return nil
+		}
+		err := healthErrs[0]
+		healthErrs = healthErrs[1:]
+		return err
+	}
+	t.Cleanup(func() { upgradeSnapshot, upgradeRestore, upgradeHealthCheck = snapshot, restore, healthCheck })
+	return &restored
+}
+
+func TestRunUpgrade(t *testing.T) {
+	dir := setTestConfigDirs(t)
+	setTestConfig(t, map[string]string{"bloodhound.tag": "v7.0.0"})
+	stubUpgrade(t)
+	rt := newFakeStack()
+
+	record, err := RunUpgrade(rt, writeTestYaml(t), UpgradeOptions{Tag: "v8.0.0", SnapshotDir: dir, Timeout: time.Minute})
+	assert.NoError(t, err, "`RunUpgrade()` should upgrade a healthy stack")
+	assert.Equal(t, "v7.0.0", record.FromTag)
+	assert.Equal(t, upgradeSucceeded, record.Status)
+	assert.Equal(t, "v8.0.0", bhEnv.GetString("bloodhound.tag"), "The new tag should be pinned")
+	assert.Equal(t, []string{"pull", "up -d"}, rt.Commands)
+	assert.Empty(t, rt.Tags, "Nothing should be rolled back")
+
+	history, err := ReadUpgradeHistory()
+	assert.NoError(t, err)
+	assert.Len(t, history, 1, "The upgrade should be recorded")
+	assert.Equal(t, "bhce_bloodhound:latest", history[0].Images["bhce_bloodhound"].Image)
+}
+
+func TestRunUpgradeToLatestRelease(t *testing.T) {
+	dir := setTestConfigDirs(t)
+	setTestConfig(t, map[string]string{"bloodhound.tag": "v7.0.0"})
+	stubUpgrade(t)
+	stubReleaseApis(t, map[string]string{"/v2/repositories/specterops/bloodhound/tags": registryTags})
+
+	record, err := RunUpgrade(newFakeStack(), writeTestYaml(t), UpgradeOptions{SnapshotDir: dir})
+	assert.NoError(t, err)
+	assert.Equal(t, "v8.1.0", record.ToTag, "The newest release should be resolved")
+	assert.Equal(t, "v8.1.0", bhEnv.GetString("bloodhound.tag"), "The release tag should be pinned instead of `latest`")
+
+	stubReleaseApis(t, map[string]string{})
+	rt := newFakeStack()
+	_, err = RunUpgrade(rt, writeTestYaml(t), UpgradeOptions{Tag: "latest", SnapshotDir: dir})
+	assert.ErrorContains(t, err, "--to", "Without the registry, the user should choose the release")
+	assert.Empty(t, rt.Commands)
+}
+
+func TestRunUpgradeRollsBack(t *testing.T) {
+	dir := setTestConfigDirs(t)
+	setTestConfig(t, map[string]string{"bloodhound.tag": "v7.0.0"})
+	restored := stubUpgrade(t, ErrUnhealthy, nil, ErrUnhealthy)
+	rt := newFakeStack()
+	for i := range rt.Containers {
+		rt.Containers[i].ImageID = "sha256:" + rt.Containers[i].ID
+	}
+
+	record, err := RunUpgrade(rt, writeTestYaml(t), UpgradeOptions{Tag: "v8.0.0", SnapshotDir: dir, Timeout: time.Minute})
+	assert.True(t, errors.Is(err, ErrUnhealthy), "`RunUpgrade()` should return the reason for the rollback")
+	assert.Equal(t, upgradeRolledBack, record.Status)
+	assert.True(t, record.SnapshotRestored)
+	assert.Equal(t, []string{record.Snapshot}, *restored, "The snapshot should be restored")
+	assert.Equal(t, "v7.0.0", bhEnv.GetString("bloodhound.tag"), "The previous tag should be pinned again")
+	assert.Equal(t, []string{"pull", "up -d"}, rt.Commands, "The restore should recreate the containers without pulling")
+	assert.Equal(t, "sha256:bhce_bloodhound-id", rt.Tags["bhce_bloodhound:latest"], "The image references should point to the recorded images")
+
+	*restored = nil
+	rt = newFakeStack()
+	record, err = RunUpgrade(rt, writeTestYaml(t), UpgradeOptions{Tag: "v8.0.0", SnapshotDir: dir, Timeout: time.Minute, SkipRestore: true})
+	assert.True(t, errors.Is(err, ErrUnhealthy))
+	assert.False(t, record.SnapshotRestored)
+	assert.Empty(t, *restored, "`SkipRestore` should keep the data")
+	assert.Equal(t, []string{"pull", "up -d", "up -d"}, rt.Commands, "The containers should be recreated without pulling")
+
+	rt = newFakeStack()
+	rt.Errors["Pull"] = errors.New("manifest unknown")
+	_, err = RunUpgrade(rt, writeTestYaml(t), UpgradeOptions{Tag: "v9.9.9", SnapshotDir: dir})
+	assert.Error(t, err, "A failed pull should be rolled back without a health check")
+	assert.Equal(t, "v7.0.0", bhEnv.GetString("bloodhound.tag"))
+	assert.Len(t, *restored, 1)
+
+	history, err := ReadUpgradeHistory()
+	assert.NoError(t, err)
+	assert.Len(t, history, 3)
+}
+
+func TestRunUpgradePreconditions(t *testing.T) {
+	dir := setTestConfigDirs(t)
+	stubUpgrade(t)
+	yaml := writeTestYaml(t)
+
+	_, err := RunUpgrade(runtimetest.NewFakeRuntime(), yaml, UpgradeOptions{Tag: "v8.0.0", SnapshotDir: dir})
+	assert.Error(t, err, "`RunUpgrade()` should need existing containers")
+
+	t.Setenv("BLOODHOUND_TAG", "latest")
+	rt := newFakeStack()
+	_, err = RunUpgrade(rt, yaml, UpgradeOptions{Tag: "v8.0.0", SnapshotDir: dir})
+	assert.True(t, errors.Is(err, ErrInvalidConfig), "A tag in the environment should be rejected")
+	assert.Empty(t, rt.Commands)
+}
@@ -41,12 +41,12 @@ func (c HealthIssues) Swap(i, j int) {
 }
 
 // GetCwdFromExe gets the current working directory based on "bloodhound-cli" location.
-func GetCwdFromExe() string {
+func GetCwdFromExe() (string, error) {
 	exe, err := os.Executable()
 	if err != nil {
-		log.Fatalf("Failed to get path to current executable.")
+		return "", fmt.Errorf("failed to get path to current executable: %w", err)
 	}
-	return filepath.Dir(exe)
+	return filepath.Dir(exe), nil
 }
 
 // FileExists determines if a given string is a valid filepath.
@@ -83,13 +83,13 @@ func GetBloodHoundDir() string {
 	return bhEnv.GetString("config_directory")
 }
 
-// MakeConfigDir ensures the configured BloodHound config directory exists, creating it if necessary.
-// Returns an error if directory creation fails.
+// MakeConfigDir ensures the configured BloodHound config directory exists, creating it if necessary with the mode for
+// the `permissions_mode` config value (0700 by default). Returns an error if directory creation fails.
 func MakeConfigDir() error {
 	configDir := GetBloodHoundDir()
 	if !DirExists(configDir) {
 		log.Printf("The BloodHound config directory you have set, %s, is missing, so attempting to create it.\n", configDir)
-		mkErr := os.MkdirAll(configDir, 0777)
+		mkErr := os.MkdirAll(configDir, configDirMode())
 		if mkErr != nil {
 			return mkErr
 		}
@@ -114,38 +114,36 @@ func CheckConfigDir(path string) (bool, error) {
 
 // GetYamlFilePath joins and returns the directory path of the BloodHound config directory with the Docker Compose YAML file.
 // If a user has provided the `-f` or `--file` flag with a string value, the function will return that filepath.
-func GetYamlFilePath(override string) string {
+// Returns an error wrapping ErrYamlMissing if the override path does not exist or is a directory.
+func GetYamlFilePath(override string) (string, error) {
 	if override != "" {
 		log.Printf("Using the override filepath: %s", override)
 		fileInfo, err := os.Stat(override)
 		if err != nil {
 			if os.IsNotExist(err) {
-				log.Fatalf("The override path '%s' does not exist.\n", override)
-			} else {
-				log.Fatalf("There was an error checking the override path '%s': %v\n", override, err)
+				return "", fmt.Errorf("%w: the override path '%s' does not exist", ErrYamlMissing, override)
 			}
+			return "", fmt.Errorf("there was an error checking the override path '%s': %w", override, err)
 		}
 		if fileInfo.IsDir() {
-			log.Fatalf("The provided override path '%s' is a directory instead of a YAML file.\n", override)
-		} else {
-			if !FileExists(override) {
-				log.Fatalf("Override filepaths does not exist: %s", override)
-			}
+			return "", fmt.Errorf("%w: the provided override path '%s' is a directory instead of a YAML file", ErrYamlMissing, override)
 		}
-		return override
+		return override, nil
 	}
-	return filepath.Join(GetBloodHoundDir(), "docker-compose.yml")
+	return filepath.Join(GetBloodHoundDir(), "docker-compose.yml"), nil
 }
 
 // CheckYamlExists verifies that a YAML file exists at the specified path.
-// If the file does not exist, it logs a fatal error with instructions for obtaining the required YAML file.
-func CheckYamlExists(path string) {
+// If the file does not exist, it returns an error wrapping ErrYamlMissing with instructions for obtaining the required
+// YAML file.
+func CheckYamlExists(path string) error {
 	if !FileExists(path) {
-		log.Fatalf(
-			"The YAML file %s does not exist! To continue, move your YAML file into the config directory or run "+
-				"`./bloodhound-cli check` to download the necessary YAML file.",
-			path)
+		return fmt.Errorf(
+			"%w: %s is missing! To continue, move your YAML file into the config directory or run "+
+				"`./bloodhound-cli check` to write the necessary YAML file",
+			ErrYamlMissing, path)
 	}
+	return nil
 }
 
 // CheckPath returns true if the specified command exists in the system's PATH.
@@ -157,37 +155,55 @@ func CheckPath(cmd string) bool {
 // RunBasicCmd executes a given command ("name") with a list of arguments ("args")
 // and returns a "string" with the output.
 func RunBasicCmd(name string, args []string) (string, error) {
-	out, err := exec.Command(name, args...).Output()
+	return RunBasicCmdWithEnv(name, args, nil)
+}
+
+// RunBasicCmdWithEnv is RunBasicCmd with extra "KEY=value" environment variables ("env") added to the command's
+// environment.
+func RunBasicCmdWithEnv(name string, args []string, env []string) (string, error) {
+	command := exec.Command(name, args...)
+	command.Env = commandEnv(env)
+	out, err := command.Output()
 	output := string(out[:])
 	return output, err
 }
 
+// commandEnv returns the current environment with the extra "KEY=value" entries added, replacing any existing values
+// for the same keys. Returns nil (inherit the environment) if there are no extra entries.
+func commandEnv(env []string) []string {
+	if len(env) == 0 {
+		return nil
+	}
+	return append(os.Environ(), env...)
+}
+
 // RunCmd executes a given command ("name") with a list of arguments ("args")
-// and return stdout and stderr buffers.
+// and prints its stdout and stderr.
 func RunCmd(name string, args []string) error {
-	// If the command is ``docker`` or ``podman``, prepend ``compose`` to the args
-	if name == "docker" || name == "podman" {
-		args = append([]string{"compose"}, args...)
-	}
+	return RunCmdWithEnv(name, args, nil)
+}
+
+// RunCmdWithEnv is RunCmd with extra "KEY=value" environment variables ("env") added to the command's environment.
+func RunCmdWithEnv(name string, args []string, env []string) error {
 	path, err := exec.LookPath(name)
 	if err != nil {
-		log.Fatalf("`%s` is not installed or not available in the current PATH variable", name)
+		return fmt.Errorf("`%s` is not installed or not available in the current PATH variable", name)
 	}
-	exe, err := os.Executable()
+	exePath, err := GetCwdFromExe()
 	if err != nil {
-		log.Fatalf("Failed to get path to current executable.")
+		return err
 	}
-	exePath := filepath.Dir(exe)
 	command := exec.Command(path, args...)
 	command.Dir = exePath
+	command.Env = commandEnv(env)
 
 	stdout, err := command.StdoutPipe()
 	if err != nil {
-		log.Fatalf("Failed to get stdout pipe for running `%s`", name)
+		return fmt.Errorf("failed to get stdout pipe for running `%s`: %w", name, err)
 	}
 	stderr, err := command.StderrPipe()
 	if err != nil {
-		log.Fatalf("Failed to get stderr pipe for running `%s`", name)
+		return fmt.Errorf("failed to get stderr pipe for running `%s`: %w", name, err)
 	}
 
 	stdoutScanner := bufio.NewScanner(stdout)
@@ -204,7 +220,7 @@ func RunCmd(name string, args []string) error {
 	}()
 	err = command.Start()
 	if err != nil {
-		log.Fatalf("Error trying to start `%s`: %v\n", name, err)
+		return fmt.Errorf("error trying to start `%s`: %w", name, err)
 	}
 	err = command.Wait()
 	if err != nil {
@@ -214,6 +230,28 @@ func RunCmd(name string, args []string) error {
 	return nil
 }
 
+// RunCmdWithIO executes a given command ("name") with a list of arguments ("args") and extra environment variables
+// ("env") like RunCmdWithEnv, but connects the command to the "stdin" reader and the "stdout" and "stderr" writers
+// instead of printing its output. Any of them may be nil to use the null device.
+func RunCmdWithIO(name string, args []string, env []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return fmt.Errorf("`%s` is not installed or not available in the current PATH variable", name)
+	}
+	exePath, err := GetCwdFromExe()
+	if err != nil {
+		return err
+	}
+	command := exec.Command(path, args...)
+	command.Dir = exePath
+	command.Env = commandEnv(env)
+	command.Stdin = stdin
+	command.Stdout = stdout
+	command.Stderr = stderr
+
+	return command.Run()
+}
+
 // Contains checks if a slice of strings ("slice" parameter) contains a given
 // string ("search" parameter).
 func Contains(slice []string, target string) bool {
@@ -246,7 +284,8 @@ func quietTests() func() {
 // AskForConfirmation asks the user for confirmation. A user must type in "yes" or "no" and
 // then press enter. It has fuzzy matching, so "y", "Y", "yes", "YES", and "Yes" all count as
 // confirmations. If the input is not recognized, it will ask again. The function does not return
-// until it gets a valid response from the user.
+// until it gets a valid response from the user. If the input cannot be read (e.g., stdin is closed), it is treated as
+// a "no" so nothing destructive happens without a confirmation.
 // Original source: https://gist.github.com/r0l1/3dcbb0c8f6cfe9c66ab8008f55f8f28b
 func AskForConfirmation(s string) bool {
 	reader := bufio.NewReader(os.Stdin)
@@ -256,7 +295,8 @@ func AskForConfirmation(s string) bool {
 
 		response, err := reader.ReadString('\n')
 		if err != nil {
-			log.Fatal(err)
+			fmt.Println()
+			return false
 		}
 
 		response = strings.ToLower(strings.TrimSpace(response))
@@ -269,33 +309,28 @@ func AskForConfirmation(s string) bool {
 	}
 }
 
-// DownloadFile downloads a file from the specified URL and saves it to the provided filepath.
+// DownloadFile downloads a file from the specified URL and saves it to the provided filepath. The file is only replaced
+// once the download succeeds.
 func DownloadFile(url string, filepath string) error {
-	// Create the file to stream the contents
-	out, err := os.Create(filepath)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
-	}
-	defer out.Close()
-
-	// Fetch the file to begin the download
-	resp, err := http.Get(url)
+	content, err := downloadBytes(url)
 	if err != nil {
 		return fmt.Errorf("failed to download file: %w", err)
 	}
-	defer resp.Body.Close()
+	return writeFileAtomic(filepath, content, 0644)
+}
 
-	// Check for HTTP errors
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download file: received status code %d", resp.StatusCode)
+// writeFileAtomic writes the content to a temporary file next to the path and renames it over the path, so an
+// interrupted write cannot leave a partial file behind.
+func writeFileAtomic(path string, content []byte, mode os.FileMode) error {
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err := os.WriteFile(tmp, content, mode); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write file: %w", err)
 	}
-
-	// Write the contents to the file created earlier
-	_, err = io.Copy(out, resp.Body)
-	if err != nil {
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
 		return fmt.Errorf("failed to write file: %w", err)
 	}
-
 	return nil
 }
 
@@ -303,9 +338,11 @@ func DownloadFile(url string, filepath string) error {
 func GetRemoteBloodHoundCliVersion() (string, string, error) {
 	var output string
 
-	baseUrl := "https://api.github.com/repos/SpecterOps/bloodhound-cli/releases/latest"
-	client := http.Client{Timeout: time.Second * 10}
-	resp, err := client.Get(baseUrl)
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/repos/%s/releases/latest", githubApiUrl, cliReleaseRepo), nil)
+	if err != nil {
+		return "", "", err
+	}
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return "", "", err
 	}
@@ -1,6 +1,9 @@
 package internal
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -8,10 +11,37 @@ import (
 )
 
 func TestGetCwdFromExe(t *testing.T) {
-	cwd := GetCwdFromExe()
+	cwd, err := GetCwdFromExe()
+	assert.NoError(t, err, "Expected `GetCwdFromExe()` to return no error")
 	assert.False(t, cwd == "", "Expected `GetCwdFromExe()` to return a non-empty string")
 }
 
+func TestGetYamlFilePath(t *testing.T) {
+	defer quietTests()()
+	dir := t.TempDir()
+	yaml := filepath.Join(dir, "docker-compose.yml")
+	assert.NoError(t, os.WriteFile(yaml, []byte("services: {}\n"), 0600))
+
+	path, err := GetYamlFilePath(yaml)
+	assert.NoError(t, err, "Expected `GetYamlFilePath()` to accept an existing file")
+	assert.Equal(t, yaml, path)
+
+	_, err = GetYamlFilePath(filepath.Join(dir, "missing.yml"))
+	assert.True(t, errors.Is(err, ErrYamlMissing), "Expected `GetYamlFilePath()` to return `ErrYamlMissing` for a missing file")
+
+	_, err = GetYamlFilePath(dir)
+	assert.True(t, errors.Is(err, ErrYamlMissing), "Expected `GetYamlFilePath()` to return `ErrYamlMissing` for a directory")
+}
+
+func TestCheckYamlExists(t *testing.T) {
+	dir := t.TempDir()
+	yaml := filepath.Join(dir, "docker-compose.yml")
+	assert.True(t, errors.Is(CheckYamlExists(yaml), ErrYamlMissing), "Expected `CheckYamlExists()` to return `ErrYamlMissing`")
+
+	assert.NoError(t, os.WriteFile(yaml, []byte("services: {}\n"), 0600))
+	assert.NoError(t, CheckYamlExists(yaml), "Expected `CheckYamlExists()` to return no error")
+}
+
 func TestCheckPath(t *testing.T) {
 	dockerFound := CheckPath("docker")
 	if !dockerFound {
@@ -22,13 +52,13 @@ func TestCheckPath(t *testing.T) {
 
 func TestRunBasicCmd(t *testing.T) {
 	defer quietTests()()
-	_, err := RunBasicCmd(dockerCmd, []string{"--version"})
+	_, err := RunBasicCmd("docker", []string{"--version"})
 	assert.Equal(t, nil, err, "Expected `RunBasicCmd()` to return no error")
 }
 
 func TestRunCmd(t *testing.T) {
 	defer quietTests()()
-	err := RunCmd(dockerCmd, []string{"--version"})
+	err := RunCmd("docker", []string{"--version"})
 	assert.Equal(t, nil, err, "Expected `RunCmd()` to return no error")
 }
 
@@ -1,38 +1,96 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
+
 	docker "github.com/SpecterOps/BloodHound_CLI/cmd/internal"
 	"github.com/spf13/cobra"
 )
 
 // logsCmd represents the logs command
 var logsCmd = &cobra.Command{
-	Use:   "logs <container>",
+	Use:   "logs [container]",
 	Short: "Fetch logs for BloodHound services",
-	Long: `Fetch logs for BloodHound services. Provide a container name.
+	Long: `Fetch logs for BloodHound services. Provide a container name or "all" (the default).
 
 Valid names are:
 
+* all
 * bloodhound
 * neo4j
-* postgres`,
-	Args: cobra.ExactArgs(1),
-	Run:  readLogs,
+* postgres
+
+Use "--follow" to keep streaming new log entries until you press Ctrl+C. Unlike "docker logs", it has no "-f"
+shorthand because "-f" is the global "--file" flag. Use "--since" and "--until" to limit the entries to a time range.
+Both accept timestamps (e.g., "2025-01-02T15:04:05Z") or relative times (e.g., "10m" or "2h").
+
+Use "--level" and "--grep" to parse each entry and only show entries at or above a level (trace, debug, info, warn,
+error, or fatal) or with a message matching a regular expression. Entries without a recognized level are hidden when
+filtering by level. Use "--json" to write the parsed entries as newline-delimited JSON for tools like jq.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: readLogs,
 }
 
 func init() {
 	rootCmd.AddCommand(logsCmd)
 
 	logsCmd.Flags().StringP("lines", "l", "500", "Number of lines to display")
+	logsCmd.Flags().Bool("follow", false, "Stream new log entries until interrupted (no -f shorthand; -f is --file)")
+	logsCmd.Flags().String("since", "", "Show logs since a timestamp or relative time (e.g., 10m)")
+	logsCmd.Flags().String("until", "", "Show logs before a timestamp or relative time (e.g., 10m)")
+	logsCmd.Flags().BoolP("timestamps", "t", false, "Show timestamps")
+	logsCmd.Flags().String("level", "", "Only show entries at or above this level (e.g., warn)")
+	logsCmd.Flags().String("grep", "", "Only show entries with a message matching this regular expression")
+	logsCmd.Flags().Bool("json", false, "Write the parsed entries as newline-delimited JSON")
 }
 
-func readLogs(cmd *cobra.Command, args []string) {
-	docker.EvaluateDockerComposeStatus()
-	lines := cmd.Flag("lines").Value.String()
-	fmt.Printf("[+] Fetching up to %s lines of logs for `%s`...\n", lines, args[0])
-	logs := docker.FetchLogs(args[0], lines)
-	for _, entry := range logs {
-		fmt.Print(entry)
+func readLogs(cmd *cobra.Command, args []string) error {
+	rt, err := newRuntime()
+	if err != nil {
+		return err
+	}
+	containerName := "all"
+	if len(args) > 0 {
+		containerName = args[0]
+	}
+
+	follow, _ := cmd.Flags().GetBool("follow")
+	timestamps, _ := cmd.Flags().GetBool("timestamps")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	filter, filterErr := docker.NewLogFilter(cmd.Flag("level").Value.String(), cmd.Flag("grep").Value.String())
+	if filterErr != nil {
+		return fmt.Errorf("error in the log filter: %w", filterErr)
+	}
+	options := docker.LogOptions{
+		Tail:       cmd.Flag("lines").Value.String(),
+		Since:      cmd.Flag("since").Value.String(),
+		Until:      cmd.Flag("until").Value.String(),
+		Timestamps: timestamps,
+		Follow:     follow,
+		Color:      docker.IsTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "",
+		Filter:     filter,
+		JSON:       jsonOutput,
+	}
+
+	// Keep stdout limited to the log entries when writing JSON so it can be piped into other tools
+	status := os.Stdout
+	if jsonOutput {
+		status = os.Stderr
+	}
+	if follow {
+		fmt.Fprintf(status, "[+] Following logs for `%s` (press Ctrl+C to stop)...\n", containerName)
+	} else {
+		fmt.Fprintf(status, "[+] Fetching up to %s lines of logs for `%s`...\n", options.Tail, containerName)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err = docker.FetchLogs(ctx, rt, containerName, options, os.Stdout)
+	if err != nil {
+		return fmt.Errorf("error fetching logs: %w", err)
 	}
+	return nil
 }
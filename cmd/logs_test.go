@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadLogs(t *testing.T) {
+	rt := newFakeStack()
+	rt.Logs["bhce_bloodhound-id"] = `{"level":"info","message":"server started"}` + "\n" + `{"level":"error","message":"query failed"}` + "\n"
+	rt.Logs["bhce_neo4j-id"] = "neo4j started\n"
+	useFakeRuntime(t, rt)
+
+	out, err := runCommand(t, "logs", "bloodhound")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "bhce_bloodhound | ")
+	assert.Contains(t, out, "server started")
+	assert.NotContains(t, out, "neo4j started", "Only the requested container's logs should be shown")
+
+	out, err = runCommand(t, "logs", "--level", "error")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "query failed")
+	assert.NotContains(t, out, "server started", "Entries below the level should be hidden")
+
+	_, err = runCommand(t, "logs", "--level", "loud")
+	assert.Error(t, err, "An unknown level should be refused")
+
+	_, err = runCommand(t, "logs", "nginx")
+	assert.ErrorContains(t, err, "no running BloodHound container")
+}
@@ -23,17 +23,26 @@ The command performs the following steps:
 
 **WARNING** : This action wipes all user data for the default admin user. This action cannot be undone.
 `,
-	Run: resetAdminPwd,
+	RunE: resetAdminPwd,
 }
 
 // init registers the resetpwd command with the root command for the CLI.
 func init() {
 	rootCmd.AddCommand(resetPwdCmd)
+
+	resetPwdCmd.Flags().DurationVar(&waitTimeout, "timeout", defaultWaitTimeout, waitTimeoutUsage)
 }
 
 // resetAdminPwd resets the default admin password by orchestrating Docker Compose operations and invoking the password reset process.
-func resetAdminPwd(cmd *cobra.Command, args []string) {
-	docker.EvaluateDockerComposeStatus()
+func resetAdminPwd(cmd *cobra.Command, args []string) error {
+	rt, err := newRuntime()
+	if err != nil {
+		return err
+	}
 	fmt.Println("[+] Resetting admin password")
-	docker.ResetAdminPassword(docker.GetYamlFilePath(fileOverride))
+	yaml, err := docker.GetYamlFilePath(fileOverride)
+	if err != nil {
+		return err
+	}
+	return docker.ResetAdminPassword(rt, yaml, waitTimeout)
 }
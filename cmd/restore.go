@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+
+	docker "github.com/SpecterOps/BloodHound_CLI/cmd/internal"
+	"github.com/spf13/cobra"
+)
+
+var forceRestore bool
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+	Use:   "restore <archive>",
+	Short: "Restore the BloodHound databases and configuration from a backup archive",
+	Long: `Restore the BloodHound databases and configuration from an archive created by the "backup" command.
+
+The command performs the following steps:
+
+* Verifies the checksums in the archive's manifest
+* Compares the image versions in the manifest against the images of the existing containers, or
+  pulls the images to compare them if there are no containers yet
+* Asks for confirmation before changing anything
+* Restores the YAML file from the archive if there is none, and restores the JSON config file
+* Deletes and recreates the BloodHound containers and volumes
+* Loads the Neo4j data and the Postgres dump
+* Brings the containers up
+
+The restore stops if the image versions do not match unless you provide the "--force" flag.
+
+**WARNING** : This action deletes all current BloodHound data. This action cannot be undone.`,
+	Args: cobra.ExactArgs(1),
+	RunE: restoreBloodHound,
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+
+	restoreCmd.Flags().BoolVar(&forceRestore, "force", false, "Restore even if the image versions in the backup do not match the local images")
+}
+
+// restoreBloodHound rebuilds the BloodHound deployment from the backup archive provided as the first argument.
+func restoreBloodHound(cmd *cobra.Command, args []string) error {
+	rt, err := newRuntime()
+	if err != nil {
+		return err
+	}
+	fmt.Println("[+] Starting BloodHound restore")
+	yaml, err := docker.GetYamlFilePath(fileOverride)
+	if err != nil {
+		return err
+	}
+	if err := docker.RunRestore(rt, yaml, args[0], forceRestore); err != nil {
+		return err
+	}
+	fmt.Println("[+] Restore complete! BloodHound is coming back up with the restored data.")
+	return nil
+}
@@ -1,13 +1,56 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
 	env "github.com/SpecterOps/BloodHound_CLI/cmd/internal"
 	"github.com/spf13/cobra"
+	"io"
 	"os"
+	"time"
 )
 
 // Vars for global flags
-var fileOverride string
+var (
+	fileOverride    string
+	outputFormat    string
+	hostOverride    string
+	contextOverride string
+)
+
+// Vars for flags shared by the commands that bring up the containers
+var (
+	waitTimeout        time.Duration
+	defaultWaitTimeout = 5 * time.Minute
+	waitTimeoutUsage   = "How long to wait for the BloodHound services to become healthy (0 skips the wait)"
+)
+
+// Exit codes returned by Execute so scripts can tell failures apart
+var (
+	exitOk                = 0
+	exitError             = 1
+	exitUsage             = 2
+	exitDockerNotFound    = 3
+	exitDaemonUnavailable = 4
+	exitComposeMissing    = 5
+	exitYamlMissing       = 6
+	exitConfigError       = 7
+	exitUnhealthy         = 8
+	exitVerification      = 9
+)
+
+// newRuntime resolves the container engine endpoint from the global flags and detects the container runtime used by
+// the commands. Tests can replace it to run the commands against a fake runtime instead of a container engine.
+var newRuntime = func() (env.Runtime, error) {
+	endpoint, err := env.ResolveEndpoint(hostOverride, contextOverride)
+	if err != nil {
+		return nil, err
+	}
+	return env.DetectRuntime(endpoint)
+}
+
+// Tracks whether a command got past argument and flag validation, so usage errors can be told apart from failures
+var commandStarted bool
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -15,20 +58,86 @@ var rootCmd = &cobra.Command{
 	Short: "A command line interface for managing BloodHound.",
 	Long: `BloodHound CLI is a command line interface for managing BloodHound and
 associated containers and services. Commands are grouped by their use.`,
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := env.ValidateOutputFormat(outputFormat); err != nil {
+			return err
+		}
+		// The arguments and flags are valid, so any error from here on is not a usage error
+		commandStarted = true
+		cmd.SilenceUsage = true
+		// Create or parse the Docker ``bloodhound.config.json`` file
+		if err := env.ParseBloodHoundEnvironmentVariables(); err != nil {
+			return err
+		}
+		// Commands that wait for the services use the configured timeout unless the flag is given
+		if flag := cmd.Flags().Lookup("timeout"); flag != nil && !flag.Changed {
+			waitTimeout = env.GetWaitTimeout()
+		}
+		return nil
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// Errors are printed to stderr and mapped to the documented exit codes by exitCode.
 func Execute() {
 	err := rootCmd.Execute()
-	if err != nil {
-		os.Exit(1)
+	if err != nil && !errors.Is(err, env.ErrCancelled) {
+		fmt.Fprintf(os.Stderr, "[-] Error: %v\n", err)
 	}
+	os.Exit(exitCode(err))
 }
 
-func init() {
-	// Create or parse the Docker ``bloodhound.config.json`` file
-	env.ParseBloodHoundEnvironmentVariables()
+// exitCode maps an error returned by a command to an exit code:
+//
+//	0: success, or the user declined a confirmation prompt
+//	1: general failure
+//	2: invalid arguments, flags, or command
+//	3: neither Docker nor Podman is installed
+//	4: the Docker or Podman daemon is not running or is inaccessible
+//	5: the Docker Compose v2 plugin is not installed
+//	6: the Docker YAML file is missing
+//	7: the JSON config file or a config value is invalid or missing
+//	8: one or more BloodHound services are unhealthy
+//	9: a download does not match its published checksum or signature
+func exitCode(err error) int {
+	switch {
+	case err == nil, errors.Is(err, env.ErrCancelled):
+		return exitOk
+	case errors.Is(err, env.ErrDockerNotFound):
+		return exitDockerNotFound
+	case errors.Is(err, env.ErrDaemonUnavailable):
+		return exitDaemonUnavailable
+	case errors.Is(err, env.ErrComposeMissing):
+		return exitComposeMissing
+	case errors.Is(err, env.ErrYamlMissing):
+		return exitYamlMissing
+	case errors.Is(err, env.ErrInvalidConfig), errors.Is(err, env.ErrConfigKeyNotFound):
+		return exitConfigError
+	case errors.Is(err, env.ErrUnhealthy):
+		return exitUnhealthy
+	case errors.Is(err, env.ErrVerificationFailed):
+		return exitVerification
+	case !commandStarted:
+		return exitUsage
+	}
+	return exitError
+}
 
+func init() {
 	rootCmd.PersistentFlags().StringVarP(&fileOverride, "file", "f", "", `Override the YAML file in the configured data directory and use a different YAML file for the container commands.`)
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", env.OutputTable, `Output format for commands that display information: table, json, or yaml.`)
+	rootCmd.PersistentFlags().StringVarP(&hostOverride, "host", "H", "", `Container engine to connect to (e.g., "unix:///var/run/docker.sock" or "ssh://user@host"). Overrides DOCKER_HOST, Docker contexts, and the docker_host config value.`)
+	rootCmd.PersistentFlags().StringVarP(&contextOverride, "context", "c", "", `Name of the Docker context to use. Overrides DOCKER_HOST, DOCKER_CONTEXT, and the docker_host config value.`)
+}
+
+// renderOutput writes "data" to stdout in the format selected with the global "--output" flag. The "table" function
+// writes the human-readable version.
+func renderOutput(data interface{}, table func(io.Writer)) error {
+	err := env.RenderOutput(os.Stdout, outputFormat, data, table)
+	if err != nil {
+		return fmt.Errorf("failed to render the output: %w", err)
+	}
+	return nil
 }
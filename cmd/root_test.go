@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	env "github.com/SpecterOps/BloodHound_CLI/cmd/internal"
+	"github.com/SpecterOps/BloodHound_CLI/cmd/internal/runtimetest"
+	"github.com/adrg/xdg"
+	"github.com/moby/moby/api/types/container"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMain points the config directory at a temporary directory, so the commands never read or write the real config.
+func TestMain(m *testing.M) {
+	home, err := os.MkdirTemp("", "bloodhound-cli-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Setenv("XDG_CONFIG_HOME", home)
+	xdg.Reload()
+	code := m.Run()
+	os.RemoveAll(home)
+	os.Exit(code)
+}
+
+// newFakeStack returns a fake runtime with a running container for each BloodHound service.
+func newFakeStack() *runtimetest.FakeRuntime {
+	rt := runtimetest.NewFakeRuntime()
+	for _, name := range []string{"bhce_bloodhound", "bhce_neo4j", "bhce_postgres"} {
+		rt.Containers = append(rt.Containers, container.Summary{
+			ID: name + "-id", Image: name + ":latest", State: container.StateRunning, Status: "Up 1 minute",
+			Labels: map[string]string{"name": name},
+		})
+	}
+	return rt
+}
+
+// useFakeRuntime makes the commands use "rt" instead of a container engine.
+func useFakeRuntime(t *testing.T, rt env.Runtime) {
+	previous := newRuntime
+	newRuntime = func() (env.Runtime, error) { return rt, nil }
+	t.Cleanup(func() { newRuntime = previous })
+}
+
+// writeTestYaml writes an empty Docker YAML file to a temporary directory and returns its path for the "--file" flag.
+func writeTestYaml(t *testing.T) string {
+	yaml := filepath.Join(t.TempDir(), "docker-compose.yml")
+	assert.NoError(t, os.WriteFile(yaml, []byte("services: {}\n"), 0600))
+	return yaml
+}
+
+// runCommand runs the CLI with the arguments and returns what the command wrote to stdout. Every flag is reset to its
+// default first, because cobra keeps the values from the previous run.
+func runCommand(t *testing.T, args ...string) (string, error) {
+	resetFlags(rootCmd)
+	commandStarted = false
+
+	stdout := os.Stdout
+	out, err := os.CreateTemp(t.TempDir(), "stdout")
+	assert.NoError(t, err)
+	os.Stdout = out
+	rootCmd.SetArgs(args)
+	runErr := rootCmd.Execute()
+	os.Stdout = stdout
+	out.Close()
+
+	content, err := os.ReadFile(out.Name())
+	assert.NoError(t, err)
+	return string(content), runErr
+}
+
+// resetFlags sets the flags of the command and its subcommands back to their defaults.
+func resetFlags(cmd *cobra.Command) {
+	reset := func(flag *pflag.Flag) {
+		flag.Value.Set(flag.DefValue)
+		flag.Changed = false
+	}
+	cmd.PersistentFlags().VisitAll(reset)
+	cmd.Flags().VisitAll(reset)
+	for _, child := range cmd.Commands() {
+		resetFlags(child)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	commandStarted = true
+	t.Cleanup(func() { commandStarted = false })
+
+	assert.Equal(t, exitOk, exitCode(nil))
+	assert.Equal(t, exitOk, exitCode(env.ErrCancelled), "Declining a prompt should not be a failure")
+	assert.Equal(t, exitDaemonUnavailable, exitCode(fmt.Errorf("wrapped: %w", env.ErrDaemonUnavailable)))
+	assert.Equal(t, exitConfigError, exitCode(env.ErrConfigKeyNotFound))
+	assert.Equal(t, exitUnhealthy, exitCode(env.ErrUnhealthy))
+	assert.Equal(t, exitVerification, exitCode(env.ErrVerificationFailed))
+	assert.Equal(t, exitError, exitCode(errors.New("something failed")))
+
+	commandStarted = false
+	assert.Equal(t, exitUsage, exitCode(errors.New("unknown flag")), "Errors before the command starts should be usage errors")
+}
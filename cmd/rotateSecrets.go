@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	docker "github.com/SpecterOps/BloodHound_CLI/cmd/internal"
+	"github.com/spf13/cobra"
+)
+
+// rotateSecretsCmd represents the rotate-secrets command
+var rotateSecretsCmd = &cobra.Command{
+	Use:   "rotate-secrets",
+	Short: "Change the Postgres and Neo4j passwords of a running deployment",
+	Long: `Change the Postgres and Neo4j passwords of a running deployment.
+
+The command performs the following steps:
+
+* Generates new random passwords for Postgres and Neo4j
+* Changes the passwords inside the running database containers
+* Saves the new passwords to the "database.postgres_password" and "neo4j.secret" config values
+* Recreates the BloodHound service so it connects with the new passwords
+* Waits for the services to become healthy and the UI to respond (see "--timeout")
+
+Use this command to move an existing deployment off the well-known default passwords. The
+databases must be running, and the POSTGRES_PASSWORD and NEO4J_SECRET environment variables must
+not be set. The current Neo4j password is read from the "neo4j.secret" config value, so if you set it
+in a ".env" file instead, copy it to the config with "config set" first.`,
+	RunE: rotateSecrets,
+}
+
+// init registers the rotate-secrets command with the root command.
+func init() {
+	rootCmd.AddCommand(rotateSecretsCmd)
+
+	rotateSecretsCmd.Flags().DurationVar(&waitTimeout, "timeout", defaultWaitTimeout, waitTimeoutUsage)
+}
+
+// rotateSecrets changes the database passwords of the running deployment.
+func rotateSecrets(cmd *cobra.Command, args []string) error {
+	rt, err := newRuntime()
+	if err != nil {
+		return err
+	}
+	fmt.Println("[+] Rotating the database passwords")
+	yaml, err := docker.GetYamlFilePath(fileOverride)
+	if err != nil {
+		return err
+	}
+	return docker.RotateSecrets(rt, yaml, waitTimeout)
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"text/tabwriter"
@@ -18,27 +19,43 @@ var runningCmd = &cobra.Command{
 
 If containers are found, the results will include information similar
 the information provided by the "docker containers ls" command.`,
-	Run: displayRunning,
+	RunE: displayRunning,
 }
 
 func init() {
 	rootCmd.AddCommand(runningCmd)
 }
 
-func displayRunning(cmd *cobra.Command, args []string) {
-	docker.EvaluateDockerComposeStatus()
+func displayRunning(cmd *cobra.Command, args []string) error {
+	rt, err := newRuntime()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "[+] Collecting list of running BloodHound containers...")
+
+	containers, err := docker.GetRunning(rt)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "[+] Found %d running BloodHound containers\n", len(containers))
+
+	if containers == nil {
+		containers = docker.Containers{}
+	}
+	return renderOutput(containers, func(out io.Writer) {
+		printRunningTable(out, containers)
+	})
+}
+
+// printRunningTable writes the containers as a table similar to the output of "docker containers ls".
+func printRunningTable(out io.Writer, containers docker.Containers) {
 	// initialize tabwriter
 	writer := new(tabwriter.Writer)
 	// Set minwidth, tabwidth, padding, padchar, and flags
-	writer.Init(os.Stdout, 8, 8, 1, ' ', 0)
+	writer.Init(out, 8, 8, 1, ' ', 0)
 
 	defer writer.Flush()
 
-	fmt.Println("[+] Collecting list of running BloodHound containers...")
-
-	containers := docker.GetRunning()
-	fmt.Printf("[+] Found %d running BloodHound containers\n", len(containers))
-
 	if len(containers) > 0 {
 		fmt.Fprintf(writer, "\n %s\t%s\t%s\t%s\t%s", "Name", "Container ID", "Image", "Status", "Ports")
 		fmt.Fprintf(writer, "\n %s\t%s\t%s\t%s\t%s", "––––––––––––", "––––––––––––", "––––––––––––", "––––––––––––", "––––––––––––")
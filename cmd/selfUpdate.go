@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+
+	docker "github.com/SpecterOps/BloodHound_CLI/cmd/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	selfUpdateRollback bool
+	selfUpdateForce    bool
+)
+
+// selfUpdateCmd represents the self-update command
+var selfUpdateCmd = &cobra.Command{
+	Use:   "self-update",
+	Short: "Replace BloodHound CLI with the latest release",
+	Long: `Replace the BloodHound CLI binary with the latest release from GitHub.
+
+The command downloads the release asset for the current OS and architecture and verifies it
+against the SHA-256 checksum published with the release. If the binary was built with a
+release signing key, the asset's signature must also be published with the release and is
+verified with that key. An asset that cannot be verified is not installed.
+
+The running binary is replaced atomically, and the previous binary is kept next to it with a
+".previous" suffix. Run "self-update --rollback" to switch back to it.
+
+Nothing is replaced if the binary is already the latest release, and a latest release that is
+older than the binary is refused, unless "--force" is set.`,
+	Args: cobra.NoArgs,
+	RunE: selfUpdate,
+}
+
+func init() {
+	rootCmd.AddCommand(selfUpdateCmd)
+
+	selfUpdateCmd.Flags().BoolVar(&selfUpdateRollback, "rollback", false, "Switch back to the binary that was replaced by the last update")
+	selfUpdateCmd.Flags().BoolVar(&selfUpdateForce, "force", false, "Reinstall or downgrade to the latest release even if this binary is the same or newer")
+}
+
+// selfUpdate replaces the running BloodHound CLI binary with the latest release or, with "--rollback", the previous
+// binary.
+func selfUpdate(cmd *cobra.Command, args []string) error {
+	if selfUpdateRollback {
+		update, err := docker.RollbackSelfUpdate()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("[+] Rolled back %s to the previous binary; the replaced binary was kept at %s\n", update.Path, update.Previous)
+		return nil
+	}
+	fmt.Println("[+] Checking for BloodHound CLI updates...")
+	update, err := docker.RunSelfUpdate(selfUpdateForce)
+	if err != nil {
+		return err
+	}
+	if !update.Updated {
+		fmt.Printf("[+] BloodHound CLI is already the latest release (%s)\n", update.To)
+		return nil
+	}
+	fmt.Printf("[+] BloodHound CLI was updated from %s to %s\n", update.From, update.To)
+	fmt.Printf("[+] The previous binary was kept at %s; run `bloodhound-cli self-update --rollback` to switch back to it\n", update.Previous)
+	return nil
+}
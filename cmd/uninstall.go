@@ -21,7 +21,7 @@ The command performs the following steps:
 
 This command is irreversible and should only be run if you are looking to remove BloodHound from the system or wanting
 a fresh start.`,
-	Run: uninstallBloodHound,
+	RunE: uninstallBloodHound,
 }
 
 func init() {
@@ -30,8 +30,15 @@ func init() {
 
 // uninstallBloodHound removes all BloodHound Docker containers, images, and volumes when the uninstall command is invoked.
 // It first checks the Docker Compose environment status and proceeds with the uninstallation if no errors are detected.
-func uninstallBloodHound(cmd *cobra.Command, args []string) {
-	docker.EvaluateDockerComposeStatus()
+func uninstallBloodHound(cmd *cobra.Command, args []string) error {
+	rt, err := newRuntime()
+	if err != nil {
+		return err
+	}
 	fmt.Println("[+] Starting BloodHound environment removal")
-	docker.RunDockerComposeUninstall(docker.GetYamlFilePath(fileOverride))
+	yaml, err := docker.GetYamlFilePath(fileOverride)
+	if err != nil {
+		return err
+	}
+	return docker.RunDockerComposeUninstall(rt, yaml)
 }
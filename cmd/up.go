@@ -8,11 +8,13 @@ import (
 var upCmd = &cobra.Command{
 	Use:   "up",
 	Short: "Shortcut for `containers up`",
-	Run: func(cmd *cobra.Command, args []string) {
-		containersUpCmd.Run(cmd, args)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return containersUpCmd.RunE(cmd, args)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(upCmd)
+
+	upCmd.Flags().DurationVar(&waitTimeout, "timeout", defaultWaitTimeout, waitTimeoutUsage)
 }
@@ -11,7 +11,7 @@ var updateCmd = &cobra.Command{
 	Use:   "update",
 	Short: "Update the BloodHound container images if any updates are available",
 	Long:  `Updates the BloodHound container images if any updates are available.`,
-	Run:   updateBloodHound,
+	RunE:  updateBloodHound,
 }
 
 func init() {
@@ -19,8 +19,15 @@ func init() {
 }
 
 // updateBloodHound checks the status of Docker Compose and pulls the latest BloodHound container images if available.
-func updateBloodHound(cmd *cobra.Command, args []string) {
-	docker.EvaluateDockerComposeStatus()
+func updateBloodHound(cmd *cobra.Command, args []string) error {
+	rt, err := newRuntime()
+	if err != nil {
+		return err
+	}
 	fmt.Println("[+] Checking for BloodHound image updates...")
-	docker.RunDockerComposePull(docker.GetYamlFilePath(fileOverride))
+	yaml, err := docker.GetYamlFilePath(fileOverride)
+	if err != nil {
+		return err
+	}
+	return docker.RunDockerComposePull(rt, yaml)
 }
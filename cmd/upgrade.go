@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	docker "github.com/SpecterOps/BloodHound_CLI/cmd/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	upgradeTag         string
+	upgradeSnapshotDir string
+	upgradeSkipRestore bool
+)
+
+// upgradeCmd represents the upgrade command
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Upgrade BloodHound to a pinned image tag and roll back if it fails",
+	Long: `Upgrade the BloodHound image to a pinned tag and roll back if the new version fails to come up.
+
+The command performs the following steps:
+
+* Records the image of every BloodHound container
+* Takes a snapshot of the data with the "backup" command
+* Pins the new tag with the "bloodhound.tag" config value
+* Pulls the images and recreates the containers
+* Waits for the services to become healthy (see "--timeout")
+
+If the pull, the recreate, or the health check fails, the command points the image tags back at
+the recorded images, restores the previous "bloodhound.tag" value, and restores the snapshot,
+because the new version may have already migrated the data. Add "--skip-restore" to keep the
+current data and only recreate the containers. The snapshot is kept either way, so you can also
+restore it later with the "restore" command. Every upgrade is recorded in the "upgrades.json"
+file in the config directory.
+
+Without "--to", the command looks up the newest BloodHound CE release and pins its release tag
+(e.g., "v8.0.0") instead of "latest", so the deployment does not move to a newer release later.
+By default, snapshots are written to the "backups" directory inside the config directory.
+
+For example: bloodhound-cli upgrade --to v8.0.0`,
+	Args: cobra.NoArgs,
+	RunE: upgradeBloodHound,
+}
+
+func init() {
+	rootCmd.AddCommand(upgradeCmd)
+
+	upgradeCmd.Flags().StringVar(&upgradeTag, "to", "", "Tag of the BloodHound image to upgrade to (e.g., v8.0.0); defaults to the newest release")
+	upgradeCmd.Flags().StringVarP(&upgradeSnapshotDir, "dir", "d", "", "Directory where the pre-upgrade snapshot will be written")
+	upgradeCmd.Flags().BoolVar(&upgradeSkipRestore, "skip-restore", false, "Keep the current data instead of restoring the snapshot if the upgrade is rolled back")
+	upgradeCmd.Flags().DurationVar(&waitTimeout, "timeout", defaultWaitTimeout, waitTimeoutUsage)
+}
+
+// upgradeBloodHound upgrades the BloodHound deployment described by the configured YAML file to the requested tag.
+func upgradeBloodHound(cmd *cobra.Command, args []string) error {
+	rt, err := newRuntime()
+	if err != nil {
+		return err
+	}
+	snapshotDir := upgradeSnapshotDir
+	if snapshotDir == "" {
+		snapshotDir = filepath.Join(docker.GetBloodHoundDir(), "backups")
+	}
+	yaml, err := docker.GetYamlFilePath(fileOverride)
+	if err != nil {
+		return err
+	}
+	if upgradeTag == "" {
+		fmt.Println("[+] Upgrading BloodHound to the latest release")
+	} else {
+		fmt.Printf("[+] Upgrading BloodHound to %s\n", upgradeTag)
+	}
+	record, err := docker.RunUpgrade(rt, yaml, docker.UpgradeOptions{
+		Tag:         upgradeTag,
+		SnapshotDir: snapshotDir,
+		Timeout:     waitTimeout,
+		SkipRestore: upgradeSkipRestore,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[+] BloodHound was upgraded from %s to %s\n", record.FromTag, record.ToTag)
+	return nil
+}
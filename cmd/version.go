@@ -5,6 +5,7 @@ import (
 	"github.com/SpecterOps/BloodHound_CLI/cmd/config"
 	utils "github.com/SpecterOps/BloodHound_CLI/cmd/internal"
 	"github.com/spf13/cobra"
+	"io"
 	"os"
 	"text/tabwriter"
 )
@@ -14,40 +15,130 @@ var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Displays BloodHound CLI's version information",
 	Long: `Displays BloodHound CLI's version information. The local version information comes from the current binary.
-The latest release information is pulled from GitHub's API`,
+The latest release information is pulled from GitHub's API
+
+Add "--server" to show the images of the BloodHound containers instead, including the tag and registry digest
+of the BloodHound image and the Postgres and Neo4j versions. The command compares the BloodHound image against
+the latest BloodHound CE release in the registry, flags a deployment that is behind, and summarizes the
+release notes.`,
 	RunE: compareCliVersions,
 }
 
+var serverVersion bool
+
 // init registers the version command with the root command, enabling the "version" CLI command.
 func init() {
 	rootCmd.AddCommand(versionCmd)
+
+	versionCmd.Flags().BoolVar(&serverVersion, "server", false, "Show the BloodHound image versions and the latest BloodHound CE release")
+}
+
+// versionInfo holds the local and latest release version information for the BloodHound CLI.
+type versionInfo struct {
+	LocalVersion  string `json:"local_version"`
+	BuildDate     string `json:"build_date,omitempty"`
+	LatestRelease string `json:"latest_release"`
+	LatestUrl     string `json:"latest_url"`
 }
 
 // compareCliVersions collects BloodHound CLI's local and latest stable release version numbers and build dates and then
-// prints them to standard output.
+// prints them to standard output in the selected output format.
 func compareCliVersions(cmd *cobra.Command, args []string) error {
+	if serverVersion {
+		return compareServerVersions()
+	}
+	fmt.Fprintln(os.Stderr, "[+] Fetching latest version information:")
+
+	remoteVersion, htmlUrl, remoteErr := utils.GetRemoteBloodHoundCliVersion()
+	if remoteErr != nil {
+		return remoteErr
+	}
+
+	info := versionInfo{
+		LocalVersion:  config.Version,
+		BuildDate:     config.BuildDate,
+		LatestRelease: remoteVersion,
+		LatestUrl:     htmlUrl,
+	}
+	return renderOutput(info, func(out io.Writer) {
+		printVersionTable(out, info)
+	})
+}
+
+// printVersionTable writes the version information as a two-column table.
+func printVersionTable(out io.Writer, info versionInfo) {
 	// initialize tabwriter
 	writer := new(tabwriter.Writer)
 	// Set minwidth, tabwidth, padding, padchar, and flags
-	writer.Init(os.Stdout, 8, 8, 1, '\t', 0)
+	writer.Init(out, 8, 8, 1, '\t', 0)
 
 	defer writer.Flush()
 
-	fmt.Println("[+] Fetching latest version information:")
-
-	if len(config.BuildDate) == 0 {
-		fmt.Fprintf(writer, "\nLocal Version\tBloodHound CLI %s", config.Version)
+	if len(info.BuildDate) == 0 {
+		fmt.Fprintf(writer, "\nLocal Version\tBloodHound CLI %s", info.LocalVersion)
 	} else {
-		fmt.Fprintf(writer, "\nLocal Version\tBloodHound CLI %s (%s)", config.Version, config.BuildDate)
+		fmt.Fprintf(writer, "\nLocal Version\tBloodHound CLI %s (%s)", info.LocalVersion, info.BuildDate)
 	}
 
-	remoteVersion, htmlUrl, remoteErr := utils.GetRemoteBloodHoundCliVersion()
-	if remoteErr != nil {
-		return remoteErr
+	fmt.Fprintf(writer, "\nLatest Release\t%s\n", info.LatestRelease)
+	fmt.Fprintf(writer, "Latest Download URL\t%s\n", info.LatestUrl)
+}
+
+// compareServerVersions collects the versions of the BloodHound images and the latest BloodHound CE release and then
+// prints them to standard output in the selected output format.
+func compareServerVersions() error {
+	rt, err := newRuntime()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "[+] Fetching the BloodHound image versions and the latest release:")
+	info, err := utils.GetServerVersion(rt)
+	if err != nil {
+		return err
 	}
+	if len(info.Images) == 0 {
+		fmt.Fprintln(os.Stderr, "[-] No BloodHound containers were found; run `bloodhound-cli up` to create them")
+	}
+	return renderOutput(info, func(out io.Writer) {
+		printServerVersionTable(out, info)
+	})
+}
 
-	fmt.Fprintf(writer, "\nLatest Release\t%s\n", remoteVersion)
-	fmt.Fprintf(writer, "Latest Download URL\t%s\n", htmlUrl)
+// printServerVersionTable writes the image versions as a table followed by the latest release information.
+func printServerVersionTable(out io.Writer, info utils.ServerVersion) {
+	writer := new(tabwriter.Writer)
+	writer.Init(out, 8, 8, 1, '\t', 0)
+
+	fmt.Fprintf(writer, "\n %s\t%s\t%s\t%s", "Container", "Image", "Digest", "State")
+	fmt.Fprintf(writer, "\n %s\t%s\t%s\t%s", "–––––––––", "–––––", "––––––", "–––––")
+	for _, image := range info.Images {
+		digest := image.Digest
+		if digest == "" {
+			digest = image.ImageID
+		}
+		fmt.Fprintf(writer, "\n %s\t%s\t%s\t%s", image.Name, image.Image, digest, image.State)
+	}
+	fmt.Fprintln(writer, "")
+	writer.Flush()
 
-	return nil
+	running := info.RunningRelease
+	if running == "" {
+		running = "unknown"
+	}
+	fmt.Fprintf(writer, "\nRunning Release\t%s", running)
+	fmt.Fprintf(writer, "\nLatest Release\t%s", info.LatestRelease)
+	fmt.Fprintf(writer, "\nRelease Notes URL\t%s\n", info.LatestUrl)
+	writer.Flush()
+
+	switch {
+	case info.Behind:
+		fmt.Fprintf(out, "\n[!] This deployment is behind; run `bloodhound-cli upgrade --to %s` to upgrade\n", info.LatestRelease)
+	case info.RunningRelease == "":
+		fmt.Fprintln(out, "\n[*] The running release could not be matched to a release tag, so compare it with the latest release yourself")
+	default:
+		fmt.Fprintln(out, "\n[+] This deployment is running the latest release")
+	}
+	if info.ReleaseNotes != "" {
+		fmt.Fprintf(out, "\nRelease notes for %s:\n%s\n", info.LatestRelease, info.ReleaseNotes)
+	}
 }
@@ -1,9 +1,18 @@
 package main
 
 import (
+	"embed"
+
 	"github.com/SpecterOps/BloodHound_CLI/cmd"
+	"github.com/SpecterOps/BloodHound_CLI/cmd/config"
 )
 
+// The Docker YAML files for this release, written to the config directory when they are missing
+//
+//go:embed docker-compose.yml docker-compose.dev.yml
+var composeFiles embed.FS
+
 func main() {
+	config.ComposeFiles = composeFiles
 	cmd.Execute()
 }